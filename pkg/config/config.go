@@ -17,8 +17,14 @@ package config
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
 	"path"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/ossf/allstar/pkg/config/operator"
 
@@ -31,6 +37,93 @@ import (
 type OrgConfig struct {
 	// OptConfig contains the opt in/out configuration.
 	OptConfig OrgOptConfig `yaml:"optConfig"`
+
+	// IssueDigest : set to true to combine the issues of all failing policies
+	// on a repo into a single digest issue, rather than one issue per policy.
+	IssueDigest bool `yaml:"issueDigest"`
+
+	// QuietHours is a daily window during which issue creation and pings are
+	// deferred until the window ends. Policies are still checked and logged.
+	// Defaults to the operator configured quiet hours, if any.
+	QuietHours *QuietHours `yaml:"quietHours"`
+
+	// Dispatch configures the repository_dispatch event sent by the dispatch
+	// action.
+	Dispatch Dispatch `yaml:"dispatch"`
+
+	// AppealLabel is an issue label that, when added to an Allstar issue by a
+	// repo admin, pauses reopening and pinging that issue until the label is
+	// removed. An appeal is approved by adding an exemption, or denied by
+	// removing the label. Default empty, disabled.
+	AppealLabel string `yaml:"appealLabel"`
+
+	// NotifyFallback is the order of notification surfaces tried by the issue
+	// action: "issues" for the repo's issues, "discussions" for the repo's
+	// discussions, "repo:<name>" for issues in a central repo of the org, and
+	// "log" to only log. Surfaces the repo has disabled are skipped. Default
+	// empty, always use the repo's issues.
+	NotifyFallback []string `yaml:"notifyFallback"`
+
+	// ScanInterval is how often the reconciliation job scans the org's repos,
+	// as a duration such as "1h" or "24h". Intervals shorter than the job's
+	// period have no effect. The operator may set the interval instead, see
+	// operator.ScanIntervals. Default empty, scan on every run of the job.
+	ScanInterval string `yaml:"scanInterval"`
+}
+
+// Dispatch configures the repository_dispatch event sent to a repo when a
+// policy with the dispatch action fails, so that the repo's own workflow can
+// remediate.
+type Dispatch struct {
+	// EventType is the event_type of the repository_dispatch event. Defaults to
+	// the event type of an operator mapped dispatch action, otherwise
+	// operator.DispatchEventType.
+	EventType string `yaml:"eventType"`
+
+	// Payload is additional client_payload sent with the event, alongside
+	// the policy name, notify text, and reason codes.
+	Payload map[string]string `yaml:"payload"`
+}
+
+// QuietHours is a daily time window, which may span midnight.
+type QuietHours struct {
+	// Start is the start of the window, "HH:MM" 24 hour.
+	Start string `yaml:"start"`
+
+	// End is the end of the window, "HH:MM" 24 hour.
+	End string `yaml:"end"`
+
+	// TimeZone is the IANA time zone name of Start and End, such as
+	// "America/New_York". Default UTC.
+	TimeZone string `yaml:"timeZone"`
+}
+
+// Active returns true if t is within the quiet hours window.
+func (q QuietHours) Active(t time.Time) (bool, error) {
+	loc := time.UTC
+	if q.TimeZone != "" {
+		var err error
+		loc, err = time.LoadLocation(q.TimeZone)
+		if err != nil {
+			return false, fmt.Errorf("invalid quiet hours time zone: %w", err)
+		}
+	}
+	start, err := time.Parse("15:04", q.Start)
+	if err != nil {
+		return false, fmt.Errorf("invalid quiet hours start: %w", err)
+	}
+	end, err := time.Parse("15:04", q.End)
+	if err != nil {
+		return false, fmt.Errorf("invalid quiet hours end: %w", err)
+	}
+	lt := t.In(loc)
+	now := lt.Hour()*60 + lt.Minute()
+	s := start.Hour()*60 + start.Minute()
+	e := end.Hour()*60 + end.Minute()
+	if s <= e {
+		return now >= s && now < e, nil
+	}
+	return now >= s || now < e, nil
 }
 
 // OrgOptConfig is used in Allstar and policy-secific org-level config to
@@ -45,11 +138,76 @@ type OrgOptConfig struct {
 	// OptOutRepos is the list of repos to opt-out when in opt-out strategy.
 	OptOutRepos []string `yaml:"optOutRepos"`
 
+	// Exemptions is a list of repos to disable, with a recorded justification,
+	// in either strategy. Exemptions missing a reason or approver, or past their
+	// expiry, are rejected and have no effect.
+	Exemptions []Exemption `yaml:"exemptions"`
+
 	// DisableRepoOverride : set to true to disallow repos from opt-in/out in
 	// their config.
 	DisableRepoOverride bool `yaml:"disableRepoOverride"`
 }
 
+// Exemption is an audited exemption of a repo from enforcement.
+type Exemption struct {
+	// Repo is the name of the exempt repo, or a glob such as "template-*".
+	Repo string `yaml:"repo"`
+
+	// Reason is the justification for the exemption, required.
+	Reason string `yaml:"reason"`
+
+	// ApprovedBy is who approved the exemption, required.
+	ApprovedBy string `yaml:"approvedBy"`
+
+	// Until is the last date (YYYY-MM-DD) the exemption is in effect. Optional,
+	// if not set the exemption does not expire.
+	Until string `yaml:"until"`
+}
+
+// Validate returns an error if the exemption is missing required fields, or an
+// invalid or past Until date.
+func (e Exemption) Validate() error {
+	if e.Reason == "" {
+		return fmt.Errorf("exemption for repo %q is missing a reason", e.Repo)
+	}
+	if e.ApprovedBy == "" {
+		return fmt.Errorf("exemption for repo %q is missing an approver", e.Repo)
+	}
+	if e.Until != "" {
+		u, err := time.Parse("2006-01-02", e.Until)
+		if err != nil {
+			return fmt.Errorf("exemption for repo %q has invalid until date: %w", e.Repo, err)
+		}
+		if !timeNow().Before(u.AddDate(0, 0, 1)) {
+			return fmt.Errorf("exemption for repo %q expired on %v", e.Repo, e.Until)
+		}
+	}
+	return nil
+}
+
+// FindExemption returns the first valid exemption in o matching the provided
+// repo, or nil if none match. Matching exemptions that are not valid are
+// skipped, so that a broken glob does not hide a later exemption, and the first
+// validation error is returned to allow the caller to log it. Invalid globs
+// never match.
+func FindExemption(o OrgOptConfig, repo string) (*Exemption, error) {
+	var invalid error
+	for i := range o.Exemptions {
+		e := &o.Exemptions[i]
+		if ok, err := path.Match(e.Repo, repo); err != nil || !ok {
+			continue
+		}
+		if err := e.Validate(); err != nil {
+			if invalid == nil {
+				invalid = err
+			}
+			continue
+		}
+		return e, invalid
+	}
+	return nil, invalid
+}
+
 // RepoConfig is the repo-level config definition for Allstar
 type RepoConfig struct {
 	// OptConfig contains the opt in/out configuration.
@@ -66,7 +224,40 @@ type RepoOptConfig struct {
 	OptOut bool `yaml:"optOut"`
 }
 
-// FetchConfig grabs a yaml config file from github and writes it to out.
+// OwnerTypeUser is the GitHub account type of a user, as opposed to an
+// organization.
+const OwnerTypeUser = "User"
+
+type ownerTypeKey struct{}
+
+// WithOwnerType returns a copy of ctx noting the GitHub account type of the
+// owner of the repos being enforced, such as the installation account type.
+// Owner-level config of OwnerTypeUser owners is read from
+// operator.UserConfigRepo.
+func WithOwnerType(ctx context.Context, t string) context.Context {
+	return context.WithValue(ctx, ownerTypeKey{}, t)
+}
+
+// ConfigRepo returns the repo that owner-level config is read from:
+// operator.UserConfigRepo if ctx notes a user owner, see WithOwnerType,
+// otherwise operator.OrgConfigRepo.
+func ConfigRepo(ctx context.Context) string {
+	if t, _ := ctx.Value(ownerTypeKey{}).(string); t == OwnerTypeUser {
+		return operator.UserConfigRepo
+	}
+	return operator.OrgConfigRepo
+}
+
+// ErrOrgConfigRepoNotFound is returned when fetching org-level config with
+// operator.OrgConfigFailClosed set, and operator.OrgConfigRepo does not exist
+// or is not accessible to the App.
+var ErrOrgConfigRepoNotFound = errors.New("org config repo not found")
+
+// FetchConfig grabs a yaml config file from github and writes it to out. Org
+// level config has environment variable references interpolated, see
+// operator.ConfigEnvPrefix. A missing file is not an error, unless it is
+// org-level config, operator.OrgConfigFailClosed is set, and the whole repo is
+// missing.
 func FetchConfig(ctx context.Context, c *github.Client, owner, repo, path string, out interface{}) error {
 	return fetchConfig(ctx, c.Repositories, owner, repo, path, out)
 }
@@ -75,6 +266,9 @@ func fetchConfig(ctx context.Context, r repositories, owner, repo, path string,
 	cf, _, rsp, err := r.GetContents(ctx, owner, repo, path, nil)
 	if err != nil {
 		if rsp != nil && rsp.StatusCode == http.StatusNotFound {
+			if repo == ConfigRepo(ctx) && operator.OrgConfigFailClosed {
+				return checkOrgConfigRepo(ctx, r, owner)
+			}
 			return nil
 		}
 		return err
@@ -83,6 +277,12 @@ func fetchConfig(ctx context.Context, r repositories, owner, repo, path string,
 	if err != nil {
 		return err
 	}
+	if repo == ConfigRepo(ctx) {
+		con, err = interpolateEnv(con)
+		if err != nil {
+			return err
+		}
+	}
 	if err := yaml.UnmarshalStrict([]byte(con), out); err != nil {
 		log.Warn().
 			Str("org", owner).
@@ -96,6 +296,61 @@ func fetchConfig(ctx context.Context, r repositories, owner, repo, path string,
 	return nil
 }
 
+// checkOrgConfigRepo returns ErrOrgConfigRepoNotFound if the org config repo
+// does not exist, so that a deleted repo or lost access is not mistaken for a
+// missing config file.
+func checkOrgConfigRepo(ctx context.Context, r repositories, owner string) error {
+	_, _, rsp, err := r.GetContents(ctx, owner, ConfigRepo(ctx), "", nil)
+	if err != nil {
+		if rsp != nil && rsp.StatusCode == http.StatusNotFound {
+			return ErrOrgConfigRepoNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+var envRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+var osLookupEnv func(string) (string, bool)
+var timeNow func() time.Time
+
+func init() {
+	osLookupEnv = os.LookupEnv
+	timeNow = time.Now
+}
+
+// interpolateEnv replaces ${VAR} and ${VAR:-default} references in org-level
+// config with values from the environment. Only variables with the
+// operator.ConfigEnvPrefix prefix may be referenced, so that org config cannot
+// be used to read arbitrary environment from the Allstar process. An undefined
+// variable without a default is an error.
+func interpolateEnv(s string) (string, error) {
+	var rerr error
+	out := envRegex.ReplaceAllStringFunc(s, func(m string) string {
+		sm := envRegex.FindStringSubmatch(m)
+		name := sm[1]
+		if !strings.HasPrefix(name, operator.ConfigEnvPrefix) {
+			if rerr == nil {
+				rerr = fmt.Errorf("environment variable %q not allowed in config, must have prefix %q",
+					name, operator.ConfigEnvPrefix)
+			}
+			return m
+		}
+		if v, ok := osLookupEnv(name); ok {
+			return v
+		}
+		if sm[2] != "" {
+			return sm[3]
+		}
+		if rerr == nil {
+			rerr = fmt.Errorf("undefined environment variable %q in config", name)
+		}
+		return m
+	})
+	return out, rerr
+}
+
 type repositories interface {
 	GetContents(context.Context, string, string, string,
 		*github.RepositoryContentGetOptions) (*github.RepositoryContent,
@@ -103,8 +358,16 @@ type repositories interface {
 }
 
 // IsEnabled determines if a repo is enabled by interpreting the provided
-// org-level and repo-level OptConfigs.
+// org-level and repo-level OptConfigs. A valid exemption always disables the
+// repo. Conflicting config, see OptConflict, is resolved by disabling the repo,
+// as an explicit opt-out is never overridden.
 func IsEnabled(o OrgOptConfig, r RepoOptConfig, repo string) bool {
+	if e, _ := FindExemption(o, repo); e != nil {
+		return false
+	}
+	if OptConflict(o, r, repo) != "" {
+		return false
+	}
 	var enabled bool
 	if o.OptOutStrategy {
 		enabled = true
@@ -126,7 +389,23 @@ func IsEnabled(o OrgOptConfig, r RepoOptConfig, repo string) bool {
 	return enabled
 }
 
+// OptConflict returns a description of conflicting opt in/out config for the
+// repo, or an empty string if there is none. A repo conflicts if it is listed
+// in both OptInRepos and OptOutRepos, or if repo override is allowed and its
+// repo-level config sets both OptIn and OptOut.
+func OptConflict(o OrgOptConfig, r RepoOptConfig, repo string) string {
+	if contains(o.OptInRepos, repo) && contains(o.OptOutRepos, repo) {
+		return "listed in both optInRepos and optOutRepos"
+	}
+	if !o.DisableRepoOverride && r.OptIn && r.OptOut {
+		return "repo config sets both optIn and optOut"
+	}
+	return ""
+}
+
 // IsBotEnabled determines if allstar is enabled overall on the provided repo.
+// If the org-level config can't be read and operator.OrgConfigFailClosed is
+// set, the repo is disabled.
 func IsBotEnabled(ctx context.Context, c *github.Client, owner, repo string) bool {
 	return isBotEnabled(ctx, c.Repositories, owner, repo)
 }
@@ -134,10 +413,20 @@ func IsBotEnabled(ctx context.Context, c *github.Client, owner, repo string) boo
 func isBotEnabled(ctx context.Context, r repositories, owner, repo string) bool {
 	// drop errors, if cfg file is not there, go with defaults
 	oc := &OrgConfig{}
-	if err := fetchConfig(ctx, r, owner, operator.OrgConfigRepo, operator.AppConfigFile, oc); err != nil {
+	if err := fetchConfig(ctx, r, owner, ConfigRepo(ctx), operator.AppConfigFile, oc); err != nil {
+		if operator.OrgConfigFailClosed {
+			log.Error().
+				Str("org", owner).
+				Str("repo", ConfigRepo(ctx)).
+				Str("area", "bot").
+				Str("file", operator.AppConfigFile).
+				Err(err).
+				Msg("Org config unreadable, disabling repo.")
+			return false
+		}
 		log.Error().
 			Str("org", owner).
-			Str("repo", operator.OrgConfigRepo).
+			Str("repo", ConfigRepo(ctx)).
 			Str("area", "bot").
 			Str("file", operator.AppConfigFile).
 			Err(err).
@@ -154,6 +443,14 @@ func isBotEnabled(ctx context.Context, r repositories, owner, repo string) bool
 			Msg("Unexpected config error, using defaults.")
 	}
 
+	if c := OptConflict(oc.OptConfig, rc.OptConfig, repo); c != "" {
+		log.Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", "bot").
+			Str("conflict", c).
+			Msg("Conflicting opt in/out config, repo is opted out.")
+	}
 	enabled := IsEnabled(oc.OptConfig, rc.OptConfig, repo)
 	log.Info().
 		Str("org", owner).
@@ -164,6 +461,96 @@ func isBotEnabled(ctx context.Context, r repositories, owner, repo string) bool
 	return enabled
 }
 
+// GetOrgConfig returns the org's config from operator.AppConfigFile, or the
+// defaults if it can't be read. Callers reading several org settings, such as
+// IssueDigest, AppealLabel, Dispatch, and NotifyFallback, should get it once and
+// read the fields.
+func GetOrgConfig(ctx context.Context, c *github.Client, owner string) *OrgConfig {
+	return getOrgConfig(ctx, c.Repositories, owner)
+}
+
+func getOrgConfig(ctx context.Context, r repositories, owner string) *OrgConfig {
+	oc := &OrgConfig{}
+	if err := fetchConfig(ctx, r, owner, ConfigRepo(ctx), operator.AppConfigFile, oc); err != nil {
+		log.Error().
+			Str("org", owner).
+			Str("repo", ConfigRepo(ctx)).
+			Str("area", "bot").
+			Str("file", operator.AppConfigFile).
+			Err(err).
+			Msg("Unexpected config error, using defaults.")
+	}
+	return oc
+}
+
+// InQuietHours determines if the org with config oc is currently in its quiet
+// hours, see OrgConfig.QuietHours.
+func InQuietHours(ctx context.Context, oc *OrgConfig, owner string) bool {
+	q := oc.QuietHours
+	if q == nil {
+		if operator.QuietHoursStart == "" || operator.QuietHoursEnd == "" {
+			return false
+		}
+		q = &QuietHours{
+			Start:    operator.QuietHoursStart,
+			End:      operator.QuietHoursEnd,
+			TimeZone: operator.QuietHoursTimeZone,
+		}
+	}
+	active, err := q.Active(timeNow())
+	if err != nil {
+		log.Warn().
+			Str("org", owner).
+			Str("repo", ConfigRepo(ctx)).
+			Str("area", "bot").
+			Err(err).
+			Msg("Invalid quiet hours, ignoring.")
+		return false
+	}
+	return active
+}
+
+// GetScanInterval returns how often the org should be scanned, from
+// operator.ScanIntervals or the org's OrgConfig.ScanInterval, or zero to scan
+// on every run.
+func GetScanInterval(ctx context.Context, c *github.Client, owner string) time.Duration {
+	return getScanInterval(ctx, c.Repositories, owner)
+}
+
+func getScanInterval(ctx context.Context, r repositories, owner string) time.Duration {
+	for o, d := range operator.ScanIntervals {
+		if strings.EqualFold(o, owner) {
+			return d
+		}
+	}
+	oc := getOrgConfig(ctx, r, owner)
+	if oc.ScanInterval == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(oc.ScanInterval)
+	if err != nil {
+		log.Warn().
+			Str("org", owner).
+			Str("repo", ConfigRepo(ctx)).
+			Str("area", "bot").
+			Err(err).
+			Msg("Invalid scan interval, ignoring.")
+		return 0
+	}
+	return d
+}
+
+// IsReadOnlyOrg returns true if the org is listed in operator.ReadOnlyOrgs, and
+// Allstar must not take write actions on it.
+func IsReadOnlyOrg(owner string) bool {
+	for _, o := range operator.ReadOnlyOrgs {
+		if strings.EqualFold(o, owner) {
+			return true
+		}
+	}
+	return false
+}
+
 func contains(s []string, e string) bool {
 	for _, v := range s {
 		if v == e {
@@ -17,7 +17,11 @@ package config
 import (
 	"context"
 	"encoding/base64"
+	"net/http"
 	"testing"
+	"time"
+
+	"github.com/ossf/allstar/pkg/config/operator"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-github/v39/github"
@@ -132,6 +136,77 @@ optConfig:
 	}
 }
 
+func TestInterpolateEnv(t *testing.T) {
+	env := map[string]string{
+		"ALLSTAR_CONFIG_URL": "https://internal.example.com",
+	}
+	oldLookupEnv := osLookupEnv
+	defer func() { osLookupEnv = oldLookupEnv }()
+	osLookupEnv = func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+	tests := []struct {
+		Name   string
+		Input  string
+		Expect string
+		Err    bool
+	}{
+		{
+			Name:   "NoVars",
+			Input:  "action: issue",
+			Expect: "action: issue",
+		},
+		{
+			Name:   "Defined",
+			Input:  "url: ${ALLSTAR_CONFIG_URL}/security",
+			Expect: "url: https://internal.example.com/security",
+		},
+		{
+			Name:   "DefinedIgnoresDefault",
+			Input:  "url: ${ALLSTAR_CONFIG_URL:-https://other}",
+			Expect: "url: https://internal.example.com",
+		},
+		{
+			Name:   "Default",
+			Input:  "url: ${ALLSTAR_CONFIG_MISSING:-https://default}",
+			Expect: "url: https://default",
+		},
+		{
+			Name:   "EmptyDefault",
+			Input:  "url: \"${ALLSTAR_CONFIG_MISSING:-}\"",
+			Expect: "url: \"\"",
+		},
+		{
+			Name:  "Undefined",
+			Input: "url: ${ALLSTAR_CONFIG_MISSING}",
+			Err:   true,
+		},
+		{
+			Name:  "NotAllowed",
+			Input: "url: ${HOME}",
+			Err:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			got, err := interpolateEnv(test.Input)
+			if test.Err {
+				if err == nil {
+					t.Errorf("Expected error, got: %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != test.Expect {
+				t.Errorf("Unexpected results. Expected: %v Got: %v", test.Expect, got)
+			}
+		})
+	}
+}
+
 func TestIsEnabled(t *testing.T) {
 	tests := []struct {
 		Name   string
@@ -203,9 +278,138 @@ func TestIsEnabled(t *testing.T) {
 			},
 			Expect: true,
 		},
+		{
+			Name: "Exempt",
+			Org: OrgOptConfig{
+				OptOutStrategy: true,
+				Exemptions: []Exemption{
+					{
+						Repo:       "thisrepo",
+						Reason:     "Generated mirror",
+						ApprovedBy: "secteam",
+						Until:      "2021-09-10",
+					},
+				},
+			},
+			Repo:   RepoOptConfig{},
+			Expect: false,
+		},
+		{
+			Name: "ExemptOptIn",
+			Org: OrgOptConfig{
+				OptInRepos: []string{"thisrepo"},
+				Exemptions: []Exemption{
+					{
+						Repo:       "thisrepo",
+						Reason:     "Generated mirror",
+						ApprovedBy: "secteam",
+					},
+				},
+			},
+			Repo:   RepoOptConfig{},
+			Expect: false,
+		},
+		{
+			Name: "ExemptGlob",
+			Org: OrgOptConfig{
+				OptOutStrategy: true,
+				Exemptions: []Exemption{
+					{
+						Repo:       "this*",
+						Reason:     "Generated mirror",
+						ApprovedBy: "secteam",
+					},
+				},
+			},
+			Repo:   RepoOptConfig{},
+			Expect: false,
+		},
+		{
+			Name: "ExemptNoReason",
+			Org: OrgOptConfig{
+				OptOutStrategy: true,
+				Exemptions: []Exemption{
+					{
+						Repo:       "thisrepo",
+						ApprovedBy: "secteam",
+					},
+				},
+			},
+			Repo:   RepoOptConfig{},
+			Expect: true,
+		},
+		{
+			Name: "ExemptInvalidGlob",
+			Org: OrgOptConfig{
+				OptOutStrategy: true,
+				Exemptions: []Exemption{
+					{
+						Repo:       "*",
+						ApprovedBy: "secteam",
+					},
+					{
+						Repo:       "thisrepo",
+						Reason:     "Generated mirror",
+						ApprovedBy: "secteam",
+					},
+				},
+			},
+			Repo:   RepoOptConfig{},
+			Expect: false,
+		},
+		{
+			Name: "ExemptExpired",
+			Org: OrgOptConfig{
+				OptOutStrategy: true,
+				Exemptions: []Exemption{
+					{
+						Repo:       "thisrepo",
+						Reason:     "Generated mirror",
+						ApprovedBy: "secteam",
+						Until:      "2021-09-09",
+					},
+				},
+			},
+			Repo:   RepoOptConfig{},
+			Expect: true,
+		},
+		{
+			Name: "ConflictingLists",
+			Org: OrgOptConfig{
+				OptInRepos:  []string{"thisrepo"},
+				OptOutRepos: []string{"thisrepo"},
+			},
+			Repo:   RepoOptConfig{},
+			Expect: false,
+		},
+		{
+			Name: "ConflictingRepo",
+			Org:  OrgOptConfig{},
+			Repo: RepoOptConfig{
+				OptIn:  true,
+				OptOut: true,
+			},
+			Expect: false,
+		},
+		{
+			Name: "ConflictingRepoNoOverride",
+			Org: OrgOptConfig{
+				OptOutStrategy:      true,
+				DisableRepoOverride: true,
+			},
+			Repo: RepoOptConfig{
+				OptIn:  true,
+				OptOut: true,
+			},
+			Expect: true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
+			timeNow = func() time.Time {
+				return time.Date(2021, 9, 10, 12, 0, 0, 0, time.UTC)
+			}
+			defer func() { timeNow = time.Now }()
 			if IsEnabled(test.Org, test.Repo, "thisrepo") != test.Expect {
 				t.Errorf("Unexpected results. Expected: %v", test.Expect)
 			}
@@ -213,6 +417,24 @@ func TestIsEnabled(t *testing.T) {
 	}
 }
 
+func TestOptConflict(t *testing.T) {
+	o := OrgOptConfig{
+		OptInRepos:  []string{"both", "in"},
+		OptOutRepos: []string{"both", "out"},
+	}
+	for _, repo := range []string{"in", "out", "neither"} {
+		if c := OptConflict(o, RepoOptConfig{}, repo); c != "" {
+			t.Errorf("Unexpected conflict for %v: %v", repo, c)
+		}
+	}
+	if OptConflict(o, RepoOptConfig{}, "both") == "" {
+		t.Error("Expected conflict for repo in both lists")
+	}
+	if OptConflict(OrgOptConfig{}, RepoOptConfig{OptIn: true, OptOut: true}, "in") == "" {
+		t.Error("Expected conflict for repo config")
+	}
+}
+
 func TestIsBotEnabled(t *testing.T) {
 	// FetchConfig and IsEnabled are both tested, just do one test case here
 	orgIn := `
@@ -247,3 +469,186 @@ optConfig:
 		t.Error("Expected repo to be enabled")
 	}
 }
+
+func TestIsBotEnabledFailClosed(t *testing.T) {
+	repoIn := `
+optConfig:
+  optIn: true
+`
+	repoExists := true
+	getContents = func(ctx context.Context, owner, repo, path string,
+		opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+		[]*github.RepositoryContent, *github.Response, error) {
+		if repo == operator.OrgConfigRepo {
+			if path == "" && repoExists {
+				return nil, []*github.RepositoryContent{}, nil, nil
+			}
+			return nil, nil, &github.Response{
+				Response: &http.Response{StatusCode: http.StatusNotFound},
+			}, &github.ErrorResponse{}
+		}
+		e := "base64"
+		c := base64.StdEncoding.EncodeToString([]byte(repoIn))
+		return &github.RepositoryContent{
+			Encoding: &e,
+			Content:  &c,
+		}, nil, nil, nil
+	}
+	defer func() { operator.OrgConfigFailClosed = false }()
+
+	if !isBotEnabled(context.Background(), mockRepos{}, "", "thisrepo") {
+		t.Error("Expected repo to be enabled with fail open")
+	}
+	operator.OrgConfigFailClosed = true
+	if !isBotEnabled(context.Background(), mockRepos{}, "", "thisrepo") {
+		t.Error("Expected repo to be enabled when only the config file is missing")
+	}
+	repoExists = false
+	if isBotEnabled(context.Background(), mockRepos{}, "", "thisrepo") {
+		t.Error("Expected repo to be disabled when the org config repo is missing")
+	}
+	err := fetchConfig(context.Background(), mockRepos{}, "", operator.OrgConfigRepo, operator.AppConfigFile, &OrgConfig{})
+	if err != ErrOrgConfigRepoNotFound {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestQuietHoursActive(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Q      QuietHours
+		Time   time.Time
+		Expect bool
+		Err    bool
+	}{
+		{
+			Name:   "InWindow",
+			Q:      QuietHours{Start: "09:00", End: "17:00"},
+			Time:   time.Date(2021, 9, 10, 12, 0, 0, 0, time.UTC),
+			Expect: true,
+		},
+		{
+			Name:   "AtEnd",
+			Q:      QuietHours{Start: "09:00", End: "17:00"},
+			Time:   time.Date(2021, 9, 10, 17, 0, 0, 0, time.UTC),
+			Expect: false,
+		},
+		{
+			Name:   "SpansMidnight",
+			Q:      QuietHours{Start: "22:00", End: "07:00"},
+			Time:   time.Date(2021, 9, 10, 3, 0, 0, 0, time.UTC),
+			Expect: true,
+		},
+		{
+			Name:   "SpansMidnightOutside",
+			Q:      QuietHours{Start: "22:00", End: "07:00"},
+			Time:   time.Date(2021, 9, 10, 12, 0, 0, 0, time.UTC),
+			Expect: false,
+		},
+		{
+			Name:   "TimeZone",
+			Q:      QuietHours{Start: "22:00", End: "07:00", TimeZone: "America/New_York"},
+			Time:   time.Date(2021, 9, 10, 6, 0, 0, 0, time.UTC),
+			Expect: true,
+		},
+		{
+			Name: "BadZone",
+			Q:    QuietHours{Start: "22:00", End: "07:00", TimeZone: "Nowhere/Here"},
+			Err:  true,
+		},
+		{
+			Name: "BadStart",
+			Q:    QuietHours{Start: "10pm", End: "07:00"},
+			Err:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			got, err := test.Q.Active(test.Time)
+			if test.Err {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != test.Expect {
+				t.Errorf("Unexpected results. Expected: %v Got: %v", test.Expect, got)
+			}
+		})
+	}
+}
+
+func TestIsReadOnlyOrg(t *testing.T) {
+	operator.ReadOnlyOrgs = []string{"Locked"}
+	defer func() { operator.ReadOnlyOrgs = nil }()
+	if !IsReadOnlyOrg("locked") {
+		t.Errorf("Expected org to be read-only")
+	}
+	if IsReadOnlyOrg("other") {
+		t.Errorf("Expected org not to be read-only")
+	}
+}
+
+func TestConfigRepo(t *testing.T) {
+	ctx := context.Background()
+	if got := ConfigRepo(ctx); got != operator.OrgConfigRepo {
+		t.Errorf("Unexpected config repo. Expected: %v Got: %v", operator.OrgConfigRepo, got)
+	}
+	if got := ConfigRepo(WithOwnerType(ctx, OwnerTypeUser)); got != operator.UserConfigRepo {
+		t.Errorf("Unexpected config repo. Expected: %v Got: %v", operator.UserConfigRepo, got)
+	}
+}
+
+func TestGetOrgConfig(t *testing.T) {
+	orgIn := "issueDigest: true\nappealLabel: appeal\nnotifyFallback: [log]\ndispatch:\n  eventType: fix\n"
+	fetched := 0
+	getContents = func(ctx context.Context, owner, repo, path string,
+		opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+		[]*github.RepositoryContent, *github.Response, error) {
+		fetched++
+		e := "base64"
+		c := base64.StdEncoding.EncodeToString([]byte(orgIn))
+		return &github.RepositoryContent{
+			Encoding: &e,
+			Content:  &c,
+		}, nil, nil, nil
+	}
+	oc := getOrgConfig(context.Background(), mockRepos{}, "thisorg")
+	if fetched != 1 {
+		t.Errorf("Expected one fetch, got: %v", fetched)
+	}
+	if !oc.IssueDigest || oc.AppealLabel != "appeal" || oc.Dispatch.EventType != "fix" ||
+		len(oc.NotifyFallback) != 1 || oc.NotifyFallback[0] != "log" {
+		t.Errorf("Unexpected org config: %+v", oc)
+	}
+}
+
+func TestGetScanInterval(t *testing.T) {
+	orgIn := "scanInterval: 6h\n"
+	getContents = func(ctx context.Context, owner, repo, path string,
+		opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+		[]*github.RepositoryContent, *github.Response, error) {
+		e := "base64"
+		c := base64.StdEncoding.EncodeToString([]byte(orgIn))
+		return &github.RepositoryContent{
+			Encoding: &e,
+			Content:  &c,
+		}, nil, nil, nil
+	}
+	ctx := context.Background()
+	if got := getScanInterval(ctx, mockRepos{}, "thisorg"); got != 6*time.Hour {
+		t.Errorf("Unexpected interval. Expected: %v Got: %v", 6*time.Hour, got)
+	}
+	orgIn = "scanInterval: often\n"
+	if got := getScanInterval(ctx, mockRepos{}, "thisorg"); got != 0 {
+		t.Errorf("Expected invalid interval to be ignored, got: %v", got)
+	}
+	operator.ScanIntervals = map[string]time.Duration{"ThisOrg": time.Hour}
+	defer func() { operator.ScanIntervals = nil }()
+	if got := getScanInterval(ctx, mockRepos{}, "thisorg"); got != time.Hour {
+		t.Errorf("Expected operator interval. Expected: %v Got: %v", time.Hour, got)
+	}
+}
@@ -30,10 +30,19 @@ const KeySecret = "gcpsecretmanager://projects/allstar-ossf/secrets/allstar-priv
 // OrgConfigRepo is the name of the expected org-level repo to contain config.
 const OrgConfigRepo = ".allstar"
 
+// UserConfigRepo is the name of the expected repo to contain owner-level config
+// for repos owned by a user account rather than an org.
+const UserConfigRepo = ".allstar"
+
 // RepoConfigDir is the name of the expected directory in each repo to contain
 // repo-level config.
 const RepoConfigDir = ".allstar"
 
+// ConfigEnvPrefix is the required prefix for environment variables referenced
+// in org-level config with ${VAR} or ${VAR:-default}. Other variables are
+// rejected.
+const ConfigEnvPrefix = "ALLSTAR_CONFIG_"
+
 // AppConfigFile is the name of the expected file in org or repo level config.
 const AppConfigFile = "allstar.yaml"
 
@@ -46,6 +55,167 @@ const GitHubIssueFooter = `This issue will auto resolve when the policy is in co
 
 Issue created by Allstar. See https://github.com/ossf/allstar/ for more information. For questions specific to the repository, please contact the owner or maintainer.`
 
+// CheckTimeout bounds the total time a single policy check on a repo may take,
+// including all GitHub API calls and any retries, so that one slow repo can't
+// stall enforcement of the others.
+const CheckTimeout = (2 * time.Minute)
+
 // NoticePingDuration is the duration to wait between pinging notice actions,
 // such as updating a GitHub issue.
 const NoticePingDuration = (24 * time.Hour)
+
+// DispatchEventType is the default event_type of the repository_dispatch event
+// sent by the dispatch action. Orgs may override this in allstar.yaml.
+const DispatchEventType = "allstar-policy-failure"
+
+// DiscussionCategory is the discussion category used when notifying through
+// repo discussions. If the repo has no category with this name, the first
+// category is used.
+const DiscussionCategory = "General"
+
+// QuietHoursStart and QuietHoursEnd, if both set, define the default daily
+// window ("HH:MM", 24 hour) in QuietHoursTimeZone during which the issue action
+// is deferred. Orgs may override this with quietHours in allstar.yaml.
+const QuietHoursStart = ""
+const QuietHoursEnd = ""
+
+// QuietHoursTimeZone is the IANA time zone of the default quiet hours.
+const QuietHoursTimeZone = "UTC"
+
+// ConfigCacheDuration is how long policy config fetched from org and repo
+// config files is cached. Cached config for an org can be cleared early, such as
+// on a config repo push webhook. Zero disables caching.
+const ConfigCacheDuration = 0
+
+// SafeModeMaxPercent is the maximum percentage of an installation's repos that a
+// single enforcement run may take write actions on, such as creating issues or
+// fixing. If a run would write to more, no write actions are taken for it and an
+// alert is logged, to protect against config mistakes. Only applies to
+// installations with at least SafeModeMinRepos repos. Zero disables, the
+// default.
+const SafeModeMaxPercent = 0
+
+// SafeModeMinRepos is the minimum number of repos for SafeModeMaxPercent to
+// apply.
+const SafeModeMinRepos = 10
+
+// SafeModeMaxCount is the maximum number of repos a single enforcement run may
+// take write actions on per installation, as for SafeModeMaxPercent. Zero
+// disables.
+const SafeModeMaxCount = 0
+
+// CheckErrorBackoffThreshold is the number of consecutive errors checking the
+// SECURITY.md policy on a repo after which the repo is skipped for a backoff
+// interval, so that a few repos that always error don't waste API budget or
+// flood logs. Zero disables.
+const CheckErrorBackoffThreshold = 5
+
+// CheckErrorBackoffBase is the first backoff interval once
+// CheckErrorBackoffThreshold is reached. It doubles with each further error, up
+// to CheckErrorBackoffMax.
+const CheckErrorBackoffBase = time.Hour
+
+// CheckErrorBackoffMax is the longest backoff interval.
+const CheckErrorBackoffMax = (24 * time.Hour)
+
+// CheckRetryAttempts is the number of attempts made for a GitHub API request
+// of the SECURITY.md policy check, the GraphQL queries and config fetches,
+// that fails with a transient 5xx or secondary rate limit error. Other errors
+// are not retried. One disables retries. It may be set at startup to tune
+// retries for an installation.
+var CheckRetryAttempts = 3
+
+// CheckRetryBaseDelay is the delay before the first retry of a failed check
+// request. It doubles with each further retry. A Retry-After response header
+// takes precedence, unless it is longer than RateLimitMaxWait, in which case the
+// request is not retried.
+var CheckRetryBaseDelay = time.Second
+
+// MeasureDetectionLag : set to true to log, when a SECURITY.md file is found
+// that GitHub does not yet report as the security policy, how long ago the file
+// was last committed. The samples show how long GitHub takes to detect a new
+// policy. This makes an extra request per lagging repo.
+const MeasureDetectionLag = false
+
+// DebugAPIResponses : set to true to log the raw GitHub API responses used by
+// policy checks at debug level, to diagnose unexpected results. Tokens in
+// response URLs are redacted, but responses may include repo contents, so
+// only enable this when needed.
+const DebugAPIResponses = false
+
+// RateLimitMinRemaining is the number of remaining GitHub API requests below
+// which paging through issue lists waits for the rate limit to reset before
+// fetching the next page.
+const RateLimitMinRemaining = 10
+
+// RateLimitMaxWait is the longest a list operation will wait for the GitHub API
+// rate limit to reset. If the reset is further away, the operation fails
+// instead.
+const RateLimitMaxWait = (5 * time.Minute)
+
+// BatchConcurrency is the maximum number of repos processed at once by batch
+// operations, such as fixing all repos in an org.
+const BatchConcurrency = 5
+
+// UserAgent is sent as the User-Agent header on all GitHub API requests so
+// that requests from this instance can be identified. Operators should include
+// their instance name and version.
+const UserAgent = "allstar-ossf"
+
+// RequestTagHeader is the name of an optional extra header added to all GitHub
+// API requests, such as for internal tracing. Not sent if empty.
+const RequestTagHeader = ""
+
+// RequestTagValue is the value sent in RequestTagHeader.
+const RequestTagValue = ""
+
+// RESTEndpoint is the REST API endpoint of a GitHub Enterprise Server install,
+// such as "https://github.example.com/api/v3/". Empty uses github.com.
+var RESTEndpoint = ""
+
+// V4Endpoint is the GraphQL (v4) API endpoint of a GitHub Enterprise Server
+// install, such as "https://github.example.com/api/graphql". If empty, it is
+// derived from the REST API endpoint, which is github.com by default.
+var V4Endpoint = ""
+
+// ActionMap maps action names that may be set in policy config to the action
+// Allstar takes, so that a deployment can define what an action means without
+// each org restating it. A value may include a policy specific option after a
+// colon, such as "fix:commit" to have the SECURITY.md policy fix by committing
+// directly rather than opening a pull request, or "dispatch:my-event" to send
+// a specific event type. Options set in org config, such as fixMode, take
+// precedence. Names not in the map are used as is.
+var ActionMap = map[string]string{
+	"pr":     "fix:pr",
+	"commit": "fix:commit",
+}
+
+// SecurityAllowedOptions, if not nil, is the list of SECURITY.md policy config
+// options (by yaml name) that orgs and repos are permitted to set. Other
+// options are reset to their defaults and a warning is logged. The optConfig
+// and action options are always permitted. If nil, all options are permitted.
+var SecurityAllowedOptions []string
+
+// ReadOnlyOrgs are orgs on which Allstar never takes write actions. Policies
+// are still checked, but any configured action, such as issue or fix, is
+// capped at log, regardless of org and repo config. Unlike pausing, the lock
+// is permanent until the org is removed from the list.
+var ReadOnlyOrgs []string
+
+// RedactPatterns are regular expressions masked in security policy content
+// before it is quoted in issues or logged, such as internal email addresses or
+// domains. Orgs may add their own patterns with redactPatterns.
+var RedactPatterns []string
+
+// ScanIntervals sets how often the reconciliation job scans each org, keyed by
+// org name, such as more often for high-risk orgs. It takes precedence over an
+// org's scanInterval. Orgs without an interval are scanned on every run of the
+// job.
+var ScanIntervals map[string]time.Duration
+
+// OrgConfigFailClosed : set to true to disable Allstar on all repos of an org
+// whose org-level config can't be read, such as when OrgConfigRepo has been
+// deleted or the App has lost access to it, and log an error. This requires
+// every org to have an OrgConfigRepo. Default false, an unreadable org config
+// is logged and defaults are used.
+var OrgConfigFailClosed = false
@@ -0,0 +1,63 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforce
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// dispatch sends a repository_dispatch event for the failing result r of
+// policy, with the event type of the org's dispatch config d. If the org has
+// not configured one, eventType is used, otherwise operator.DispatchEventType.
+// The client_payload contains the org configured payload, plus the policy name,
+// notify text, and reason codes.
+func dispatch(ctx context.Context, c *github.Client, d config.Dispatch, owner, repo, policy, eventType string, r *policydef.Result) error {
+	if d.EventType == "" {
+		d.EventType = eventType
+	}
+	if d.EventType == "" {
+		d.EventType = operator.DispatchEventType
+	}
+	payload := make(map[string]interface{})
+	for k, v := range d.Payload {
+		payload[k] = v
+	}
+	payload["policy"] = policy
+	payload["notifyText"] = r.NotifyText
+	payload["reasons"] = r.Reasons
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(b)
+	runid.Logger(ctx).Info().
+		Str("org", owner).
+		Str("repo", repo).
+		Str("area", policy).
+		Str("eventType", d.EventType).
+		Msg("Sending repository dispatch.")
+	return sendDispatch(ctx, c, owner, repo, github.DispatchRequestOptions{
+		EventType:     d.EventType,
+		ClientPayload: &raw,
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforce
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func TestRunPoliciesDispatch(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			pol{},
+		}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{
+			Dispatch: config.Dispatch{
+				EventType: "fix-security-policy",
+				Payload:   map[string]string{"team": "security", "policy": "overridden"},
+			},
+		}
+	}
+	var sent []github.DispatchRequestOptions
+	sendDispatch = func(ctx context.Context, c *github.Client, owner, repo string, opts github.DispatchRequestOptions) error {
+		sent = append(sent, opts)
+		return nil
+	}
+	action = "dispatch"
+	result = policydef.Result{Enabled: true, Pass: false, NotifyText: "missing", Reasons: []string{"a"}}
+	for i := 0; i < 2; i++ {
+		if err := RunPolicies(context.Background(), nil, "org", "dispatch", true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if len(sent) != 1 {
+		t.Fatalf("Expected 1 dispatch for unchanged result, got: %v", len(sent))
+	}
+	if sent[0].EventType != "fix-security-policy" {
+		t.Errorf("Unexpected event type: %v", sent[0].EventType)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(*sent[0].ClientPayload, &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]interface{}{
+		"team":       "security",
+		"policy":     "Test policy",
+		"notifyText": "missing",
+		"reasons":    []interface{}{"a"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected payload. (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforce
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ossf/allstar/pkg/issue"
+	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// dryRunner is implemented by policies that can be configured to only log the
+// issues they would create.
+type dryRunner interface {
+	DryRun(ctx context.Context, c *github.Client, owner, repo string) bool
+}
+
+// isDryRun returns whether the policy is in dry run mode on the repo.
+func isDryRun(ctx context.Context, c *github.Client, p policydef.Policy, owner, repo string) bool {
+	dr, ok := p.(dryRunner)
+	return ok && dr.DryRun(ctx, c, owner, repo)
+}
+
+// logDryRunIssue logs the issue notifyEnsure would create, and where, without
+// creating it.
+func logDryRunIssue(ctx context.Context, c *github.Client, fallback []string, owner, repo, policy, text string) error {
+	surface, target, err := notifyTarget(ctx, c, fallback, owner, repo)
+	if err != nil {
+		return err
+	}
+	title := policy
+	if target != "" && target != repo {
+		title = centralPolicy(policy, repo)
+		text = fmt.Sprintf("Repository: https://github.com/%v/%v\n%v", owner, repo, text)
+	}
+	p := issue.Render(title, text)
+	runid.Logger(ctx).Info().
+		Str("org", owner).
+		Str("repo", repo).
+		Str("area", policy).
+		Str("surface", surface).
+		Str("target", target).
+		Str("title", p.Title).
+		Str("body", p.Body).
+		Strs("labels", p.Labels).
+		Msg("Dry run, not creating issue.")
+	return nil
+}
@@ -18,13 +18,18 @@ package enforce
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/config/operator"
 	"github.com/ossf/allstar/pkg/ghclients"
 	"github.com/ossf/allstar/pkg/issue"
 	"github.com/ossf/allstar/pkg/policies"
 	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/results"
+	"github.com/ossf/allstar/pkg/runid"
 
 	"github.com/google/go-github/v39/github"
 	"github.com/rs/zerolog/log"
@@ -33,20 +38,94 @@ import (
 var policiesGetPolicies func() []policydef.Policy
 var issueEnsure func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error
 var issueClose func(ctx context.Context, c *github.Client, owner, repo, policy string) error
+var issueEnsureDigest func(ctx context.Context, c *github.Client, owner, repo string, sections []issue.DigestSection) error
+var issueCloseDigest func(ctx context.Context, c *github.Client, owner, repo string) error
+var issuePending func(ctx context.Context, c *github.Client, owner, repo, policy string) (bool, error)
+var issueDigestPending func(ctx context.Context, c *github.Client, owner, repo string, sections []issue.DigestSection) (bool, error)
+var configGetOrgConfig func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig
+var configInQuietHours func(ctx context.Context, oc *config.OrgConfig, owner string) bool
+var resultsWrite func(ctx context.Context, owner, repo, policy string, r *policydef.Result) error
+var configGetScanInterval func(ctx context.Context, c *github.Client, owner string) time.Duration
+var issueEnsureDiscussion func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error
+var getNotifySurfaces func(ctx context.Context, c *github.Client, owner, repo string) (*notifySurfaces, error)
+var sendDispatch func(ctx context.Context, c *github.Client, owner, repo string, opts github.DispatchRequestOptions) error
+
+var timeNow func() time.Time
 
 func init() {
 	policiesGetPolicies = policies.GetPolicies
 	issueEnsure = issue.Ensure
 	issueClose = issue.Close
+	issueEnsureDigest = issue.EnsureDigest
+	issueCloseDigest = issue.CloseDigest
+	issuePending = issue.Pending
+	issueDigestPending = issue.DigestPending
+	configGetOrgConfig = config.GetOrgConfig
+	configInQuietHours = config.InQuietHours
+	configGetScanInterval = config.GetScanInterval
+	issueEnsureDiscussion = issue.EnsureDiscussion
+	getNotifySurfaces = queryNotifySurfaces
+	resultsWrite = results.Write
+	sendDispatch = func(ctx context.Context, c *github.Client, owner, repo string, opts github.DispatchRequestOptions) error {
+		_, _, err := c.Repositories.Dispatch(ctx, owner, repo, opts)
+		return err
+	}
+	timeNow = time.Now
+}
+
+type notification struct {
+	result *policydef.Result
+	at     time.Time
+}
+
+// notified stores the last result an issue action was taken on, per
+// org/repo/policy, so that unchanged results don't cause repeated issue API
+// calls within the notice ping duration.
+var notified = struct {
+	sync.Mutex
+	m map[string]notification
+}{m: make(map[string]notification)}
+
+// shouldNotify returns true if an issue action should be taken for r, either
+// because it has changed since the last action or the ping duration has
+// passed.
+func shouldNotify(key string, r *policydef.Result, ping time.Duration) bool {
+	notified.Lock()
+	defer notified.Unlock()
+	n, ok := notified.m[key]
+	if !ok || policydef.ResultChanged(n.result, r) {
+		return true
+	}
+	return !r.Pass && n.at.Before(timeNow().Add(-1*ping))
+}
+
+func recordNotify(key string, r *policydef.Result) {
+	// Copy, as policies may reuse the result.
+	rc := *r
+	rc.Reasons = append([]string(nil), r.Reasons...)
+	notified.Lock()
+	defer notified.Unlock()
+	notified.m[key] = notification{
+		result: &rc,
+		at:     timeNow(),
+	}
 }
 
 // EnforceAll iterates through all available installations and repos Allstar
 // has access to and runs policies on those repos. It is meant to be a
 // reconcilation job to check repos which a webhook event may have been lost.
+// Installations scanned within their org's scan interval are skipped, see
+// config.GetScanInterval. All repos of an installation are checked before any
+// write action is taken, so that the run can be paused if it would write to more
+// repos than the safe mode limit, see applyWrites. A new run ID is added to the
+// context for log correlation, unless one is already present.
 //
 // TBD: determine if this should remain exported, or if it will only be called
 // from EnforceJob.
 func EnforceAll(ctx context.Context, ghc *ghclients.GHClients) error {
+	if runid.FromContext(ctx) == "" {
+		ctx = runid.WithRunID(ctx, runid.New())
+	}
 	ac, err := ghc.Get(0)
 	if err != nil {
 		return err
@@ -75,6 +154,10 @@ func EnforceAll(ctx context.Context, ghc *ghclients.GHClients) error {
 				Msg("Unexpected error getting installation client.")
 			continue
 		}
+		octx := config.WithOwnerType(ctx, i.GetAccount().GetType())
+		if !scanDue(octx, ic, i.GetAccount().GetLogin()) {
+			continue
+		}
 		var repos []*github.Repository
 		opt := &github.ListOptions{
 			PerPage: 100,
@@ -99,20 +182,22 @@ func EnforceAll(ctx context.Context, ghc *ghclients.GHClients) error {
 				Msg("Unexpected error listing installation repos.")
 			continue
 		}
-		err = nil
+		ictx := withPermissions(octx, i.GetPermissions())
+		planned := make([]repoWrites, 0, len(repos))
 		for _, r := range repos {
-			enabled := config.IsBotEnabled(ctx, ic, *r.Owner.Login, *r.Name)
-			err = RunPolicies(ctx, ic, *r.Owner.Login, *r.Name, enabled)
+			enabled := config.IsBotEnabled(ictx, ic, *r.Owner.Login, *r.Name)
+			w, err := planPolicies(ictx, ic, *r.Owner.Login, *r.Name, enabled)
 			if err != nil {
-				break
+				log.Error().
+					Str("org", *r.Owner.Login).
+					Str("repo", *r.Name).
+					Err(err).
+					Msg("Unexpected error running policies, continuing with other repos.")
+				continue
 			}
+			planned = append(planned, repoWrites{repo: *r.Name, writes: w})
 		}
-		if err != nil {
-			log.Error().
-				Err(err).
-				Msg("Unexpected error running policies.")
-			continue
-		}
+		applyWrites(ictx, i.GetAccount().GetLogin(), safeModeLimit(len(repos)), planned)
 	}
 	return nil
 }
@@ -136,16 +221,57 @@ func EnforceJob(ctx context.Context, ghc *ghclients.GHClients, d time.Duration)
 }
 
 // RunPolicies enforces policies on the provided repo. It is meant to be called
-// from either jobs, webhooks, or delayed checks. TODO: implement concurrency
-// check to only run a single instance per repo at a time.
+// from either jobs, webhooks, or delayed checks. Actions are resolved through
+// operator.ActionMap and results are recorded, see results.SetSink. Issue and
+// dispatch actions are only taken when the result changes or a ping is due, see
+// renotifier, and are logged only in dry run, see dryRunner. The safe mode
+// limit is applied by EnforceAll, see applyWrites.
+// TODO: implement concurrency check to only run a single instance per repo at
+// a time.
 func RunPolicies(ctx context.Context, c *github.Client, owner, repo string, enabled bool) error {
+	w, err := planPolicies(ctx, c, owner, repo, enabled)
+	if err != nil {
+		return err
+	}
+	return w.apply()
+}
+
+// planPolicies checks the policies on the repo and takes the actions that are
+// not limited by safe mode, such as logging and closing issues. The write
+// actions, such as creating issues, dispatching, and fixing, are returned to be
+// taken with apply, so that a run knows how many repos it would write to before
+// writing to any, see applyWrites.
+func planPolicies(ctx context.Context, c *github.Client, owner, repo string, enabled bool) (writes, error) {
+	ctx = policydef.WithRunCache(ctx)
 	ps := policiesGetPolicies()
+	oc := &config.OrgConfig{}
+	if enabled {
+		oc = configGetOrgConfig(ctx, c, owner)
+	}
+	digest := oc.IssueDigest
+	quiet := enabled && configInQuietHours(ctx, oc, owner)
+	var w writes
+	var downgraded []string
+	defer func() {
+		if len(downgraded) > 0 {
+			runid.Logger(ctx).Warn().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", "bot").
+				Strs("downgraded", downgraded).
+				Msg("Installation is missing permissions for configured actions, using log action instead.")
+		}
+	}()
+	var sections []issue.DigestSection
+	// The digest issue is not closed if only policies in dry run use it.
+	var digestLive, digestDryRun bool
 	for _, p := range ps {
+		p := p
 		r, err := p.Check(ctx, c, owner, repo)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		log.Info().
+		runid.Logger(ctx).Info().
 			Str("org", owner).
 			Str("repo", repo).
 			Str("area", p.Name()).
@@ -154,29 +280,121 @@ func RunPolicies(ctx context.Context, c *github.Client, owner, repo string, enab
 			Str("notify", r.NotifyText).
 			Interface("details", r.Details).
 			Msg("Policy run result.")
+		rec := *r
+		rec.Enabled = enabled && r.Enabled
+		if err := resultsWrite(ctx, owner, repo, p.Name(), &rec); err != nil {
+			runid.Logger(ctx).Warn().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", p.Name()).
+				Err(err).
+				Msg("Unable to record policy result.")
+		}
 		if !enabled || !r.Enabled {
 			continue
 		}
-		a := p.GetAction(ctx, c, owner, repo)
+		a, opt := resolveAction(p.GetAction(policydef.WithReasons(ctx, r.Reasons), c, owner, repo))
+		if a != "log" && config.IsReadOnlyOrg(owner) {
+			runid.Logger(ctx).Info().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", p.Name()).
+				Str("action", a).
+				Msg("Org is read-only, capping action at log.")
+			a = "log"
+		}
+		if missing := missingPermissions(ctx, p, a); a != "log" && len(missing) > 0 {
+			downgraded = append(downgraded, p.Name()+" "+a+" ("+strings.Join(missing, ", ")+")")
+			a = "log"
+		}
+		key := owner + "/" + repo + "/" + p.Name()
+		dry := a != "log" && isDryRun(ctx, c, p, owner, repo)
+		if digest && a == "issue" {
+			digestLive = digestLive || !dry
+			digestDryRun = digestDryRun || dry
+		}
 		if !r.Pass {
 			switch a {
 			case "log":
 			case "issue":
-				err := issueEnsure(ctx, c, owner, repo, p.Name(), r.NotifyText)
+				if digest {
+					if dry {
+						runid.Logger(ctx).Info().
+							Str("org", owner).
+							Str("repo", repo).
+							Str("area", p.Name()).
+							Str("text", r.NotifyText).
+							Msg("Dry run, not adding to digest issue.")
+						break
+					}
+					sections = append(sections, issue.DigestSection{
+						Policy: p.Name(),
+						Text:   r.NotifyText,
+					})
+					break
+				}
+				ping := renotifyInterval(ctx, c, p, owner, repo)
+				if !shouldNotify(key, r, ping) {
+					break
+				}
+				if quiet {
+					logDeferred(ctx, owner, repo, p.Name())
+					break
+				}
+				if dry {
+					if err := logDryRunIssue(ctx, c, oc.NotifyFallback, owner, repo, p.Name(), r.NotifyText); err != nil {
+						return nil, err
+					}
+					recordNotify(key, r)
+					break
+				}
+				ictx := issue.WithAppealLabel(issue.WithPingInterval(ctx, ping), oc.AppealLabel)
+				changes, err := notifyPending(ictx, c, oc.NotifyFallback, owner, repo, p.Name())
 				if err != nil {
-					return err
+					return nil, err
 				}
+				w = append(w, write{func() error {
+					if err := notifyEnsure(ictx, c, oc.NotifyFallback, owner, repo, p.Name(), r.NotifyText); err != nil {
+						return err
+					}
+					recordNotify(key, r)
+					return nil
+				}, changes})
 			case "email":
 				log.Warn().
 					Str("org", owner).
 					Str("repo", repo).
 					Str("area", p.Name()).
 					Msg("Email action configured, but not implemented yet.")
+			case "dispatch":
+				if !shouldNotify(key, r, operator.NoticePingDuration) {
+					break
+				}
+				if dry {
+					runid.Logger(ctx).Info().
+						Str("org", owner).
+						Str("repo", repo).
+						Str("area", p.Name()).
+						Msg("Dry run, not dispatching.")
+					recordNotify(key, r)
+					break
+				}
+				w = append(w, write{func() error {
+					if err := dispatch(ctx, c, oc.Dispatch, owner, repo, p.Name(), opt, r); err != nil {
+						return err
+					}
+					recordNotify(key, r)
+					return nil
+				}, true})
 			case "fix":
-				err := p.Fix(ctx, c, owner, repo)
+				fctx := policydef.WithActionOption(policydef.WithReasons(ctx, r.Reasons), opt)
+				changes, err := fixPending(fctx, c, p, owner, repo)
 				if err != nil {
-					return err
+					return nil, err
 				}
+				w = append(w, write{func() error {
+					return p.Fix(fctx, c, owner, repo)
+				}, changes})
 			default:
 				log.Warn().
 					Str("org", owner).
@@ -186,12 +404,67 @@ func RunPolicies(ctx context.Context, c *github.Client, owner, repo string, enab
 					Msg("Unknown action configured.")
 			}
 		}
-		if r.Pass && a == "issue" {
-			err := issueClose(ctx, c, owner, repo, p.Name())
+		if r.Pass && a == "issue" && !digest && shouldNotify(key, r, operator.NoticePingDuration) {
+			if dry {
+				runid.Logger(ctx).Info().
+					Str("org", owner).
+					Str("repo", repo).
+					Str("area", p.Name()).
+					Msg("Dry run, not closing issue.")
+				recordNotify(key, r)
+				continue
+			}
+			err := notifyClose(ctx, c, oc.NotifyFallback, owner, repo, p.Name())
 			if err != nil {
-				return err
+				return nil, err
 			}
+			recordNotify(key, r)
 		}
 	}
-	return nil
+	if digest {
+		if len(sections) > 0 {
+			if quiet {
+				logDeferred(ctx, owner, repo, "digest")
+				return w, nil
+			}
+			changes, err := issueDigestPending(ctx, c, owner, repo, sections)
+			if err != nil {
+				return nil, err
+			}
+			w = append(w, write{func() error {
+				return issueEnsureDigest(ctx, c, owner, repo, sections)
+			}, changes})
+			return w, nil
+		}
+		if digestDryRun && !digestLive {
+			runid.Logger(ctx).Info().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", "digest").
+				Msg("Dry run, not closing digest issue.")
+			return w, nil
+		}
+		return w, issueCloseDigest(ctx, c, owner, repo)
+	}
+	return w, nil
+}
+
+// resolveAction maps a configured action name through operator.ActionMap,
+// returning the action and its policy specific option, if any.
+func resolveAction(a string) (string, string) {
+	if m, ok := operator.ActionMap[a]; ok {
+		a = m
+	}
+	if i := strings.Index(a, ":"); i >= 0 {
+		return a[:i], a[i+1:]
+	}
+	return a, ""
+}
+
+func logDeferred(ctx context.Context, owner, repo, area string) {
+	runid.Logger(ctx).Info().
+		Str("org", owner).
+		Str("repo", repo).
+		Str("area", area).
+		Msg("Quiet hours, deferring issue.")
 }
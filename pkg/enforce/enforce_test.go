@@ -16,15 +16,23 @@ package enforce
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/issue"
 	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/results"
 )
 
 var result policydef.Result
 var action string
 var fixCalled bool
+var fixOption string
 
 type pol struct{}
 
@@ -38,6 +46,7 @@ func (p pol) Check(ctx context.Context, c *github.Client, owner, repo string) (*
 
 func (p pol) Fix(ctx context.Context, c *github.Client, owner, repo string) error {
 	fixCalled = true
+	fixOption = policydef.ActionOption(ctx)
 	return nil
 }
 
@@ -45,12 +54,29 @@ func (p pol) GetAction(ctx context.Context, c *github.Client, owner, repo string
 	return action
 }
 
+func init() {
+	// With no existing issues, every issue and digest write is a change.
+	issuePending = func(ctx context.Context, c *github.Client, owner, repo, policy string) (bool, error) {
+		return true, nil
+	}
+	issueDigestPending = func(ctx context.Context, c *github.Client, owner, repo string,
+		sections []issue.DigestSection) (bool, error) {
+		return true, nil
+	}
+}
+
 func TestRunPolicies(t *testing.T) {
 	policiesGetPolicies = func() []policydef.Policy {
 		return []policydef.Policy{
 			pol{},
 		}
 	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
 	ensureCalled := false
 	issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
 		ensureCalled = true
@@ -146,6 +172,600 @@ func TestRunPolicies(t *testing.T) {
 	}
 }
 
+func TestRunPoliciesUnchanged(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			pol{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	ensureCalls := 0
+	issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+		ensureCalls++
+		return nil
+	}
+	closeCalls := 0
+	issueClose = func(ctx context.Context, c *github.Client, owner, repo, policy string) error {
+		closeCalls++
+		return nil
+	}
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	action = "issue"
+	run := func(r policydef.Result) {
+		result = r
+		if err := RunPolicies(context.Background(), nil, "org", "unchanged", true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	run(policydef.Result{Enabled: true, Pass: false, NotifyText: "one", Reasons: []string{"a"}})
+	run(policydef.Result{Enabled: true, Pass: false, NotifyText: "two", Reasons: []string{"a"}})
+	if ensureCalls != 1 {
+		t.Errorf("Expected 1 Ensure call for unchanged result, got: %v", ensureCalls)
+	}
+	run(policydef.Result{Enabled: true, Pass: false, Reasons: []string{"a", "b"}})
+	if ensureCalls != 2 {
+		t.Errorf("Expected Ensure call for new finding, got: %v", ensureCalls)
+	}
+	now = now.Add(25 * time.Hour)
+	run(policydef.Result{Enabled: true, Pass: false, Reasons: []string{"a", "b"}})
+	if ensureCalls != 3 {
+		t.Errorf("Expected Ensure call after ping duration, got: %v", ensureCalls)
+	}
+	run(policydef.Result{Enabled: true, Pass: true})
+	run(policydef.Result{Enabled: true, Pass: true})
+	if closeCalls != 1 {
+		t.Errorf("Expected 1 Close call, got: %v", closeCalls)
+	}
+}
+
+func TestRunPoliciesQuietHours(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			pol{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	quiet := true
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return quiet
+	}
+	defer func() {
+		configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+			return false
+		}
+	}()
+	ensureCalls := 0
+	issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+		ensureCalls++
+		return nil
+	}
+	action = "issue"
+	result = policydef.Result{Enabled: true, Pass: false, Reasons: []string{"a"}}
+	if err := RunPolicies(context.Background(), nil, "org", "quiet", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ensureCalls != 0 {
+		t.Errorf("Expected issue to be deferred during quiet hours")
+	}
+	quiet = false
+	if err := RunPolicies(context.Background(), nil, "org", "quiet", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ensureCalls != 1 {
+		t.Errorf("Expected deferred issue after quiet hours, got: %v calls", ensureCalls)
+	}
+}
+
+type pol2 struct {
+	pol
+}
+
+func (p pol2) Name() string {
+	return "Second policy"
+}
+
+func (p pol2) Check(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+	return &result2, nil
+}
+
+var result2 policydef.Result
+
+func TestRunPoliciesDigest(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			pol{},
+			pol2{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{IssueDigest: true}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	issueEnsure = nil
+	issueClose = nil
+	var sections []issue.DigestSection
+	issueEnsureDigest = func(ctx context.Context, c *github.Client, owner, repo string, s []issue.DigestSection) error {
+		sections = s
+		return nil
+	}
+	closeCalled := false
+	issueCloseDigest = func(ctx context.Context, c *github.Client, owner, repo string) error {
+		closeCalled = true
+		return nil
+	}
+	action = "issue"
+
+	result = policydef.Result{Enabled: true, Pass: false, NotifyText: "one"}
+	result2 = policydef.Result{Enabled: true, Pass: false, NotifyText: "two"}
+	if err := RunPolicies(context.Background(), nil, "org", "digest", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []issue.DigestSection{
+		{Policy: "Test policy", Text: "one"},
+		{Policy: "Second policy", Text: "two"},
+	}
+	if diff := cmp.Diff(want, sections); diff != "" {
+		t.Errorf("Unexpected sections. (-want +got):\n%s", diff)
+	}
+
+	sections = nil
+	result = policydef.Result{Enabled: true, Pass: true}
+	if err := RunPolicies(context.Background(), nil, "org", "digest", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want = []issue.DigestSection{
+		{Policy: "Second policy", Text: "two"},
+	}
+	if diff := cmp.Diff(want, sections); diff != "" {
+		t.Errorf("Unexpected sections. (-want +got):\n%s", diff)
+	}
+
+	result2 = policydef.Result{Enabled: true, Pass: true}
+	if err := RunPolicies(context.Background(), nil, "org", "digest", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !closeCalled {
+		t.Error("Expected digest issue to be closed")
+	}
+}
+
 func TestEnforceAll(t *testing.T) {
 	t.Skip("Testing EnforceAll looks tricky, TODO")
 }
+
+func TestRunPoliciesSafeMode(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			pol{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	var ensured []string
+	issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+		ensured = append(ensured, repo)
+		if repo == "safe2" {
+			return errors.New("write failed")
+		}
+		return nil
+	}
+	action = "issue"
+	result = policydef.Result{Enabled: true, Pass: false}
+	var planned []repoWrites
+	for _, repo := range []string{"safe1", "safe2", "safe3"} {
+		w, err := planPolicies(context.Background(), nil, "org", repo, true)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		planned = append(planned, repoWrites{repo: repo, writes: w})
+	}
+	planned = append(planned, repoWrites{repo: "none"})
+	if len(ensured) != 0 {
+		t.Fatalf("Expected no writes while planning, got: %v", ensured)
+	}
+	applyWrites(context.Background(), "org", 2, planned)
+	if len(ensured) != 0 {
+		t.Errorf("Expected run over the limit to be paused, got: %v", ensured)
+	}
+	// A failed write on safe2 does not stop the writes on safe3.
+	applyWrites(context.Background(), "org", 3, planned)
+	if diff := cmp.Diff([]string{"safe1", "safe2", "safe3"}, ensured); diff != "" {
+		t.Errorf("Unexpected ensured repos. (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunPoliciesSafeModeRestart(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			pol{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	var ensured []string
+	issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+		ensured = append(ensured, repo)
+		return nil
+	}
+	// After a restart nothing is remembered, but the issues from before are
+	// still open and not due for a ping.
+	notified.Lock()
+	notified.m = make(map[string]notification)
+	notified.Unlock()
+	issuePending = func(ctx context.Context, c *github.Client, owner, repo, policy string) (bool, error) {
+		return repo == "restart3", nil
+	}
+	defer func() {
+		issuePending = func(ctx context.Context, c *github.Client, owner, repo, policy string) (bool, error) {
+			return true, nil
+		}
+	}()
+	action = "issue"
+	result = policydef.Result{Enabled: true, Pass: false}
+	var planned []repoWrites
+	for _, repo := range []string{"restart1", "restart2", "restart3"} {
+		w, err := planPolicies(context.Background(), nil, "org", repo, true)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		planned = append(planned, repoWrites{repo: repo, writes: w})
+	}
+	applyWrites(context.Background(), "org", 1, planned)
+	if diff := cmp.Diff([]string{"restart1", "restart2", "restart3"}, ensured); diff != "" {
+		t.Errorf("Expected run with one changed repo not to be paused. (-want +got):\n%s", diff)
+	}
+}
+
+func TestSafeModeLimit(t *testing.T) {
+	if l := safeModeLimit(operator.SafeModeMinRepos - 1); l != 0 {
+		t.Errorf("Expected no limit for small installations, got: %v", l)
+	}
+	if l := safeModeLimit(100); l != 100*operator.SafeModeMaxPercent/100 {
+		t.Errorf("Unexpected limit: %v", l)
+	}
+}
+
+type permPol struct {
+	pol
+}
+
+func (p permPol) MissingPermissions(action string, granted *github.InstallationPermissions) []string {
+	if action == "issue" && granted.GetIssues() != "write" {
+		return []string{"issues:write"}
+	}
+	return nil
+}
+
+func TestRunPoliciesMissingPermissions(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			permPol{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	ensureCalls := 0
+	issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+		ensureCalls++
+		return nil
+	}
+	action = "issue"
+	result = policydef.Result{Enabled: true, Pass: false}
+	read := withPermissions(context.Background(), &github.InstallationPermissions{Issues: github.String("read")})
+	if err := RunPolicies(read, nil, "org", "readonly", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ensureCalls != 0 {
+		t.Errorf("Expected issue action to be downgraded to log")
+	}
+	write := withPermissions(context.Background(), &github.InstallationPermissions{Issues: github.String("write")})
+	if err := RunPolicies(write, nil, "org", "writable", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ensureCalls != 1 {
+		t.Errorf("Expected issue with granted permissions, got: %v calls", ensureCalls)
+	}
+	if err := RunPolicies(context.Background(), nil, "org", "unknown", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ensureCalls != 2 {
+		t.Errorf("Expected issue when permissions are unknown, got: %v calls", ensureCalls)
+	}
+}
+
+type renotifyPol struct {
+	pol
+}
+
+func (p renotifyPol) RenotifyInterval(ctx context.Context, c *github.Client, owner, repo string) time.Duration {
+	return 7 * 24 * time.Hour
+}
+
+func TestRunPoliciesRenotifyInterval(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			renotifyPol{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	ensureCalls := 0
+	issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+		ensureCalls++
+		return nil
+	}
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	action = "issue"
+	result = policydef.Result{Enabled: true, Pass: false, Reasons: []string{"a"}}
+	run := func() {
+		if err := RunPolicies(context.Background(), nil, "org", "renotify", true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	run()
+	now = now.Add(25 * time.Hour)
+	run()
+	if ensureCalls != 1 {
+		t.Errorf("Expected no Ensure call within renotify interval, got: %v calls", ensureCalls)
+	}
+	now = now.Add(7 * 24 * time.Hour)
+	run()
+	if ensureCalls != 2 {
+		t.Errorf("Expected Ensure call after renotify interval, got: %v calls", ensureCalls)
+	}
+}
+
+func TestRunPoliciesRecordsResults(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			pol{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	var recorded []policydef.Result
+	resultsWrite = func(ctx context.Context, owner, repo, policy string, r *policydef.Result) error {
+		recorded = append(recorded, *r)
+		return nil
+	}
+	defer func() { resultsWrite = results.Write }()
+	action = "log"
+	result = policydef.Result{Enabled: true, Pass: false, Reasons: []string{"a"}}
+	for _, enabled := range []bool{true, false} {
+		if err := RunPolicies(context.Background(), nil, "org", "recorded", enabled); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	want := []policydef.Result{
+		{Enabled: true, Pass: false, Reasons: []string{"a"}},
+		{Enabled: false, Pass: false, Reasons: []string{"a"}},
+	}
+	if diff := cmp.Diff(want, recorded); diff != "" {
+		t.Errorf("Unexpected recorded results. (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunPoliciesActionMap(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			pol{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	old := operator.ActionMap
+	operator.ActionMap = map[string]string{"fix": "fix:commit"}
+	defer func() { operator.ActionMap = old }()
+	fixCalled = false
+	fixOption = ""
+	action = "fix"
+	result = policydef.Result{Enabled: true, Pass: false}
+	if err := RunPolicies(context.Background(), nil, "org", "mapped", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !fixCalled || fixOption != "commit" {
+		t.Errorf("Expected fix with commit option, got called: %v option: %q", fixCalled, fixOption)
+	}
+}
+
+func TestRunPoliciesReadOnlyOrg(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			pol{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	ensureCalled := false
+	issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+		ensureCalled = true
+		return nil
+	}
+	operator.ReadOnlyOrgs = []string{"Locked"}
+	defer func() { operator.ReadOnlyOrgs = nil }()
+	fixCalled = false
+	for _, a := range []string{"fix", "issue"} {
+		action = a
+		result = policydef.Result{Enabled: true, Pass: false, Reasons: []string{a}}
+		if err := RunPolicies(context.Background(), nil, "locked", "readonly", true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if fixCalled || ensureCalled {
+		t.Errorf("Expected no write actions on read-only org, got fix: %v issue: %v", fixCalled, ensureCalled)
+	}
+}
+
+func TestRunPoliciesOrgConfigOnce(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			pol{},
+			pol{},
+		}
+	}
+	fetched := 0
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		fetched++
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+		return nil
+	}
+	action = "issue"
+	result = policydef.Result{Enabled: true, Pass: false, Reasons: []string{"once"}}
+	if err := RunPolicies(context.Background(), nil, "org", "once", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fetched != 1 {
+		t.Errorf("Expected org config to be fetched once, got: %v", fetched)
+	}
+	fetched = 0
+	if err := RunPolicies(context.Background(), nil, "org", "disabled", false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fetched != 0 {
+		t.Errorf("Expected org config not to be fetched for a disabled repo, got: %v", fetched)
+	}
+}
+
+func TestResolveAction(t *testing.T) {
+	tests := []struct {
+		Action string
+		Exp    string
+		Opt    string
+	}{
+		{Action: "issue", Exp: "issue"},
+		{Action: "pr", Exp: "fix", Opt: "pr"},
+		{Action: "commit", Exp: "fix", Opt: "commit"},
+		{Action: "dispatch:my-event", Exp: "dispatch", Opt: "my-event"},
+	}
+	for _, test := range tests {
+		a, opt := resolveAction(test.Action)
+		if a != test.Exp || opt != test.Opt {
+			t.Errorf("Unexpected resolution of %v: %v %v", test.Action, a, opt)
+		}
+	}
+}
+
+type dryRunPol struct {
+	pol
+}
+
+func (p dryRunPol) DryRun(ctx context.Context, c *github.Client, owner, repo string) bool {
+	return true
+}
+
+func TestRunPoliciesDryRun(t *testing.T) {
+	policiesGetPolicies = func() []policydef.Policy {
+		return []policydef.Policy{
+			dryRunPol{},
+		}
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	configInQuietHours = func(ctx context.Context, oc *config.OrgConfig, owner string) bool {
+		return false
+	}
+	calls := 0
+	issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+		calls++
+		return nil
+	}
+	issueClose = func(ctx context.Context, c *github.Client, owner, repo, policy string) error {
+		calls++
+		return nil
+	}
+	action = "issue"
+	result = policydef.Result{Enabled: true, Pass: false, NotifyText: "one", Reasons: []string{"a"}}
+	if err := RunPolicies(context.Background(), nil, "org", "dryrun", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result = policydef.Result{Enabled: true, Pass: true}
+	if err := RunPolicies(context.Background(), nil, "org", "dryrun", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no issue changes in dry run, got: %v calls", calls)
+	}
+
+	issueEnsureDigest = func(ctx context.Context, c *github.Client, owner, repo string, sections []issue.DigestSection) error {
+		calls++
+		return nil
+	}
+	issueCloseDigest = func(ctx context.Context, c *github.Client, owner, repo string) error {
+		calls++
+		return nil
+	}
+	sendDispatch = func(ctx context.Context, c *github.Client, owner, repo string, opts github.DispatchRequestOptions) error {
+		calls++
+		return nil
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{IssueDigest: true}
+	}
+	result = policydef.Result{Enabled: true, Pass: false, NotifyText: "one", Reasons: []string{"a"}}
+	if err := RunPolicies(context.Background(), nil, "org", "dryrundigest", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result = policydef.Result{Enabled: true, Pass: true}
+	if err := RunPolicies(context.Background(), nil, "org", "dryrundigest", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	configGetOrgConfig = func(ctx context.Context, c *github.Client, owner string) *config.OrgConfig {
+		return &config.OrgConfig{}
+	}
+	action = "dispatch"
+	result = policydef.Result{Enabled: true, Pass: false, NotifyText: "one", Reasons: []string{"a"}}
+	if err := RunPolicies(context.Background(), nil, "org", "dryrundispatch", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no digest changes or dispatches in dry run, got: %v calls", calls)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforce
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ossf/allstar/pkg/ghclients"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+	"github.com/shurcooL/githubv4"
+)
+
+// Notification surfaces of the issue action, see
+// config.OrgConfig.NotifyFallback.
+const (
+	surfaceIssues      = "issues"
+	surfaceDiscussions = "discussions"
+	surfaceLog         = "log"
+	surfaceRepoPrefix  = "repo:"
+)
+
+// notifySurfaces records which notification surfaces a repo has enabled.
+type notifySurfaces struct {
+	Issues      bool
+	Discussions bool
+}
+
+func queryNotifySurfaces(ctx context.Context, c *github.Client, owner, repo string) (*notifySurfaces, error) {
+	var q struct {
+		Repository struct {
+			HasIssuesEnabled      bool
+			HasDiscussionsEnabled bool
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	vars := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+	}
+	if err := ghclients.NewV4Client(c, c.Client()).Query(ctx, &q, vars); err != nil {
+		return nil, err
+	}
+	return &notifySurfaces{
+		Issues:      q.Repository.HasIssuesEnabled,
+		Discussions: q.Repository.HasDiscussionsEnabled,
+	}, nil
+}
+
+// notifyTarget returns the first surface in the org's fallback order that is
+// available for the repo, and the repo to notify on. If the org has not
+// configured an order, the repo's issues are used without checking. If no
+// surface is available, the log surface is returned.
+func notifyTarget(ctx context.Context, c *github.Client, order []string, owner, repo string) (string, string, error) {
+	if len(order) == 0 {
+		return surfaceIssues, repo, nil
+	}
+	var s *notifySurfaces
+	for _, o := range order {
+		switch {
+		case o == surfaceIssues || o == surfaceDiscussions:
+			if s == nil {
+				var err error
+				if s, err = getNotifySurfaces(ctx, c, owner, repo); err != nil {
+					return "", "", err
+				}
+			}
+			if o == surfaceIssues && s.Issues {
+				return surfaceIssues, repo, nil
+			}
+			if o == surfaceDiscussions && s.Discussions {
+				return surfaceDiscussions, repo, nil
+			}
+		case strings.HasPrefix(o, surfaceRepoPrefix):
+			return surfaceIssues, strings.TrimPrefix(o, surfaceRepoPrefix), nil
+		case o == surfaceLog:
+			return surfaceLog, "", nil
+		default:
+			runid.Logger(ctx).Warn().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", "bot").
+				Str("surface", o).
+				Msg("Unknown notification surface configured.")
+		}
+	}
+	return surfaceLog, "", nil
+}
+
+// notifyEnsure is the issue action. It ensures an issue or discussion exists
+// for the failing policy on the surface chosen by notifyTarget. Issues in a
+// central repo are titled with the failing repo's name.
+func notifyEnsure(ctx context.Context, c *github.Client, fallback []string, owner, repo, policy, text string) error {
+	surface, target, err := notifyTarget(ctx, c, fallback, owner, repo)
+	if err != nil {
+		return err
+	}
+	switch {
+	case surface == surfaceLog:
+		runid.Logger(ctx).Info().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", policy).
+			Msg("No notification surface available, not creating issue.")
+		return nil
+	case surface == surfaceDiscussions:
+		return issueEnsureDiscussion(ctx, c, owner, repo, policy, text)
+	case target != repo:
+		return issueEnsure(ctx, c, owner, target, centralPolicy(policy, repo),
+			fmt.Sprintf("Repository: https://github.com/%v/%v\n%v", owner, repo, text))
+	}
+	return issueEnsure(ctx, c, owner, repo, policy, text)
+}
+
+// notifyPending returns whether notifyEnsure is expected to create, reopen, or
+// ping an issue. Discussions are always counted.
+func notifyPending(ctx context.Context, c *github.Client, fallback []string, owner, repo, policy string) (bool, error) {
+	surface, target, err := notifyTarget(ctx, c, fallback, owner, repo)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case surface == surfaceLog:
+		return false, nil
+	case surface == surfaceDiscussions:
+		return true, nil
+	case target != repo:
+		return issuePending(ctx, c, owner, target, centralPolicy(policy, repo))
+	}
+	return issuePending(ctx, c, owner, repo, policy)
+}
+
+// notifyClose closes the issue for the now passing policy on the surface
+// chosen by notifyTarget. Discussions are left as is.
+func notifyClose(ctx context.Context, c *github.Client, fallback []string, owner, repo, policy string) error {
+	surface, target, err := notifyTarget(ctx, c, fallback, owner, repo)
+	if err != nil {
+		return err
+	}
+	if surface != surfaceIssues {
+		return nil
+	}
+	if target != repo {
+		return issueClose(ctx, c, owner, target, centralPolicy(policy, repo))
+	}
+	return issueClose(ctx, c, owner, repo, policy)
+}
+
+// centralPolicy is the policy name used for issues in a central repo, so that
+// each failing repo has its own issue.
+func centralPolicy(policy, repo string) string {
+	return fmt.Sprintf("%v for %v", policy, repo)
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforce
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestNotifyEnsure(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Fallback    []string
+		Surfaces    notifySurfaces
+		ExpIssue    string
+		ExpPolicy   string
+		ExpDiscuss  bool
+		ExpSurfaces bool
+	}{
+		{
+			Name:      "Default",
+			ExpIssue:  "thisrepo",
+			ExpPolicy: "SECURITY.md",
+		},
+		{
+			Name:        "IssuesEnabled",
+			Fallback:    []string{"issues", "discussions"},
+			Surfaces:    notifySurfaces{Issues: true, Discussions: true},
+			ExpIssue:    "thisrepo",
+			ExpPolicy:   "SECURITY.md",
+			ExpSurfaces: true,
+		},
+		{
+			Name:        "Discussions",
+			Fallback:    []string{"issues", "discussions"},
+			Surfaces:    notifySurfaces{Discussions: true},
+			ExpDiscuss:  true,
+			ExpSurfaces: true,
+		},
+		{
+			Name:        "CentralRepo",
+			Fallback:    []string{"issues", "repo:security"},
+			ExpIssue:    "security",
+			ExpPolicy:   "SECURITY.md for thisrepo",
+			ExpSurfaces: true,
+		},
+		{
+			Name:        "NothingAvailable",
+			Fallback:    []string{"issues", "discussions"},
+			ExpSurfaces: true,
+		},
+		{
+			Name:     "Log",
+			Fallback: []string{"log", "issues"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			surfacesCalled := false
+			getNotifySurfaces = func(ctx context.Context, c *github.Client, owner, repo string) (*notifySurfaces, error) {
+				surfacesCalled = true
+				s := test.Surfaces
+				return &s, nil
+			}
+			var gotRepo, gotPolicy, gotText string
+			issueEnsure = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+				gotRepo, gotPolicy, gotText = repo, policy, text
+				return nil
+			}
+			discussed := false
+			issueEnsureDiscussion = func(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+				discussed = true
+				return nil
+			}
+			if err := notifyEnsure(context.Background(), nil, test.Fallback, "thisorg", "thisrepo", "SECURITY.md", "text"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if gotRepo != test.ExpIssue || gotPolicy != test.ExpPolicy {
+				t.Errorf("Unexpected issue. Expected: %q %q Got: %q %q", test.ExpIssue, test.ExpPolicy, gotRepo, gotPolicy)
+			}
+			if gotRepo == "security" && !strings.Contains(gotText, "https://github.com/thisorg/thisrepo") {
+				t.Errorf("Central issue does not link the repo: %q", gotText)
+			}
+			if discussed != test.ExpDiscuss {
+				t.Errorf("Unexpected discussion. Expected: %v Got: %v", test.ExpDiscuss, discussed)
+			}
+			if surfacesCalled != test.ExpSurfaces {
+				t.Errorf("Unexpected surfaces query. Expected: %v Got: %v", test.ExpSurfaces, surfacesCalled)
+			}
+		})
+	}
+}
+
+func TestNotifyClose(t *testing.T) {
+	var gotRepo, gotPolicy string
+	issueClose = func(ctx context.Context, c *github.Client, owner, repo, policy string) error {
+		gotRepo, gotPolicy = repo, policy
+		return nil
+	}
+	if err := notifyClose(context.Background(), nil, []string{"repo:security"}, "thisorg", "thisrepo", "SECURITY.md"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gotRepo != "security" || gotPolicy != "SECURITY.md for thisrepo" {
+		t.Errorf("Unexpected close. Got: %q %q", gotRepo, gotPolicy)
+	}
+}
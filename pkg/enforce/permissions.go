@@ -0,0 +1,53 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforce
+
+import (
+	"context"
+
+	"github.com/ossf/allstar/pkg/policydef"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// permissionChecker is implemented by policies that can report which App
+// permissions an action needs beyond those granted.
+type permissionChecker interface {
+	MissingPermissions(action string, granted *github.InstallationPermissions) []string
+}
+
+type permissionsKey struct{}
+
+// withPermissions records the permissions granted to the installation being
+// enforced.
+func withPermissions(ctx context.Context, p *github.InstallationPermissions) context.Context {
+	return context.WithValue(ctx, permissionsKey{}, p)
+}
+
+// missingPermissions returns the permissions the action on policy p needs that
+// the installation was not granted. If the granted permissions are unknown,
+// such as when not run from EnforceAll, or the policy can't report them,
+// nothing is missing.
+func missingPermissions(ctx context.Context, p policydef.Policy, action string) []string {
+	granted, ok := ctx.Value(permissionsKey{}).(*github.InstallationPermissions)
+	if !ok || granted == nil {
+		return nil
+	}
+	pc, ok := p.(permissionChecker)
+	if !ok {
+		return nil
+	}
+	return pc.MissingPermissions(action, granted)
+}
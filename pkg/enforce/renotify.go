@@ -0,0 +1,43 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforce
+
+import (
+	"context"
+	"time"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// renotifier is implemented by policies that configure how often a failing
+// issue is re-pinged.
+type renotifier interface {
+	RenotifyInterval(ctx context.Context, c *github.Client, owner, repo string) time.Duration
+}
+
+// renotifyInterval returns the minimum duration between issue pings for the
+// policy on the repo. If the policy doesn't configure one,
+// operator.NoticePingDuration is used.
+func renotifyInterval(ctx context.Context, c *github.Client, p policydef.Policy, owner, repo string) time.Duration {
+	if rn, ok := p.(renotifier); ok {
+		if d := rn.RenotifyInterval(ctx, c, owner, repo); d > 0 {
+			return d
+		}
+	}
+	return operator.NoticePingDuration
+}
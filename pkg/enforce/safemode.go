@@ -0,0 +1,126 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforce
+
+import (
+	"context"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// write is a write action planned by planPolicies. changes is false if the
+// action is expected to leave the repo as is, such as ensuring an issue that is
+// already open and not due for a ping, so that safe mode does not count it.
+type write struct {
+	apply   func() error
+	changes bool
+}
+
+// writes are the write actions planned for a repo by planPolicies.
+type writes []write
+
+// apply takes the write actions in order, stopping at the first error.
+func (w writes) apply() error {
+	for _, a := range w {
+		if err := a.apply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changes returns whether any of the write actions is expected to change the
+// repo.
+func (w writes) changes() bool {
+	for _, a := range w {
+		if a.changes {
+			return true
+		}
+	}
+	return false
+}
+
+// fixPender is implemented by policies that can tell whether Fix would change
+// the repo, such as not if a fix pull request is already open. Fixes of other
+// policies are always counted by safe mode.
+type fixPender interface {
+	FixPending(ctx context.Context, c *github.Client, owner, repo string) (bool, error)
+}
+
+// fixPending returns whether p.Fix is expected to change the repo.
+func fixPending(ctx context.Context, c *github.Client, p policydef.Policy, owner, repo string) (bool, error) {
+	fp, ok := p.(fixPender)
+	if !ok {
+		return true, nil
+	}
+	return fp.FixPending(ctx, c, owner, repo)
+}
+
+// safeModeLimit returns how many repos a run over total repos of an
+// installation may take write actions on, see operator.SafeModeMaxPercent and
+// operator.SafeModeMaxCount. Zero means no limit.
+func safeModeLimit(total int) int {
+	limit := 0
+	if operator.SafeModeMaxPercent > 0 && total >= operator.SafeModeMinRepos {
+		limit = total * operator.SafeModeMaxPercent / 100
+	}
+	if operator.SafeModeMaxCount > 0 && (limit == 0 || operator.SafeModeMaxCount < limit) {
+		limit = operator.SafeModeMaxCount
+	}
+	return limit
+}
+
+// repoWrites are the write actions planned for one repo of a run.
+type repoWrites struct {
+	repo   string
+	writes writes
+}
+
+// applyWrites takes the planned write actions of each repo of an
+// installation's run. If more than limit repos would be changed, an alert is
+// logged and no write actions are taken for the run, so that a config mistake
+// does not reach any repo. A zero limit means no limit. A failed write is
+// logged, and the other repos are still written to.
+func applyWrites(ctx context.Context, owner string, limit int, planned []repoWrites) {
+	acting := 0
+	for _, p := range planned {
+		if p.writes.changes() {
+			acting++
+		}
+	}
+	if limit > 0 && acting > limit {
+		runid.Logger(ctx).Error().
+			Str("org", owner).
+			Str("area", "bot").
+			Int("limit", limit).
+			Int("repos", acting).
+			Msg("Safe mode: too many repos would be affected by this run, pausing write actions. Check for a config mistake.")
+		return
+	}
+	for _, p := range planned {
+		if err := p.writes.apply(); err != nil {
+			runid.Logger(ctx).Error().
+				Str("org", owner).
+				Str("repo", p.repo).
+				Str("area", "bot").
+				Err(err).
+				Msg("Unexpected error taking write actions, continuing with other repos.")
+		}
+	}
+}
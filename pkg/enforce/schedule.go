@@ -0,0 +1,51 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforce
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// lastScan stores when each org was last scanned by EnforceAll.
+var lastScan = struct {
+	sync.Mutex
+	m map[string]time.Time
+}{m: make(map[string]time.Time)}
+
+// scanDue returns true if owner has not been scanned within its scan interval,
+// and records the scan if so.
+func scanDue(ctx context.Context, c *github.Client, owner string) bool {
+	d := configGetScanInterval(ctx, c, owner)
+	key := strings.ToLower(owner)
+	lastScan.Lock()
+	defer lastScan.Unlock()
+	if t, ok := lastScan.m[key]; ok && d > 0 && t.After(timeNow().Add(-d)) {
+		runid.Logger(ctx).Debug().
+			Str("org", owner).
+			Str("area", "bot").
+			Dur("interval", d).
+			Msg("Org scanned within its scan interval, skipping.")
+		return false
+	}
+	lastScan.m[key] = timeNow()
+	return true
+}
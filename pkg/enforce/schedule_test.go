@@ -0,0 +1,48 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enforce
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestScanDue(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	intervals := map[string]time.Duration{"hourly": time.Hour}
+	configGetScanInterval = func(ctx context.Context, c *github.Client, owner string) time.Duration {
+		return intervals[owner]
+	}
+	ctx := context.Background()
+	if !scanDue(ctx, nil, "hourly") || !scanDue(ctx, nil, "always") {
+		t.Fatal("Expected first scans to be due.")
+	}
+	now = now.Add(30 * time.Minute)
+	if scanDue(ctx, nil, "hourly") {
+		t.Error("Expected hourly org not to be due after 30m.")
+	}
+	if !scanDue(ctx, nil, "always") {
+		t.Error("Expected org without interval to be due.")
+	}
+	now = now.Add(30 * time.Minute)
+	if !scanDue(ctx, nil, "hourly") {
+		t.Error("Expected hourly org to be due after 1h.")
+	}
+}
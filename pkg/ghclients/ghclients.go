@@ -19,11 +19,13 @@ package ghclients
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"github.com/bradleyfalzon/ghinstallation"
 	"github.com/google/go-github/v39/github"
 	"github.com/gregjones/httpcache"
 	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/shurcooL/githubv4"
 	"gocloud.dev/runtimevar"
 	_ "gocloud.dev/runtimevar/gcpsecretmanager"
 )
@@ -48,7 +50,8 @@ type GHClients struct {
 }
 
 // NewGHClients returns a new GHClients. The provided RoundTripper will be
-// stored and used when creating new clients.
+// stored and used when creating new clients. Requests are tagged with the
+// operator configured User-Agent and tag header.
 func NewGHClients(ctx context.Context, t http.RoundTripper) (*GHClients, error) {
 	key, err := getKey(ctx)
 	if err != nil {
@@ -56,11 +59,36 @@ func NewGHClients(ctx context.Context, t http.RoundTripper) (*GHClients, error)
 	}
 	return &GHClients{
 		clients: make(map[int64]*github.Client),
-		tr:      t,
-		key:     key,
+		tr: &tagTransport{
+			base:      t,
+			userAgent: operator.UserAgent,
+			header:    operator.RequestTagHeader,
+			value:     operator.RequestTagValue,
+		},
+		key: key,
 	}, nil
 }
 
+// tagTransport sets the User-Agent and optional tag header on every request,
+// including those made by both the REST and GraphQL clients.
+type tagTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	header    string
+	value     string
+}
+
+func (t *tagTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r2 := r.Clone(r.Context())
+	if t.userAgent != "" {
+		r2.Header.Set("User-Agent", t.userAgent)
+	}
+	if t.header != "" {
+		r2.Header.Set(t.header, t.value)
+	}
+	return t.base.RoundTrip(r2)
+}
+
 // Get gets the client for installation id i, If i is 0 it gets the client for
 // the app-level api. If a stored client is not available, it creates a new
 // client with auth and caching built in.
@@ -68,23 +96,64 @@ func (g *GHClients) Get(i int64) (*github.Client, error) {
 	if c, ok := g.clients[i]; ok {
 		return c, nil
 	}
+	// ghinstallation expects the base URL without a trailing slash.
+	base := strings.TrimSuffix(operator.RESTEndpoint, "/")
 	var tr http.RoundTripper
-	var err error
 	if i == 0 {
-		tr, err = ghinstallationNewAppsTransport(g.tr, operator.AppID, g.key)
+		atr, err := ghinstallationNewAppsTransport(g.tr, operator.AppID, g.key)
+		if err != nil {
+			return nil, err
+		}
+		if base != "" {
+			atr.BaseURL = base
+		}
+		tr = atr
 	} else {
-		tr, err = ghinstallationNew(g.tr, operator.AppID, i, g.key)
-	}
-	if err != nil {
-		return nil, err
+		itr, err := ghinstallationNew(g.tr, operator.AppID, i, g.key)
+		if err != nil {
+			return nil, err
+		}
+		if base != "" {
+			itr.BaseURL = base
+		}
+		tr = itr
 	}
 	ctr := &httpcache.Transport{
 		Transport:           tr,
 		Cache:               httpcache.NewMemoryCache(),
 		MarkCachedResponses: true,
 	}
-	g.clients[i] = github.NewClient(&http.Client{Transport: ctr})
-	return g.clients[i], nil
+	hc := &http.Client{Transport: ctr}
+	if operator.RESTEndpoint == "" {
+		g.clients[i] = github.NewClient(hc)
+		return g.clients[i], nil
+	}
+	c, err := github.NewEnterpriseClient(operator.RESTEndpoint, operator.RESTEndpoint, hc)
+	if err != nil {
+		return nil, err
+	}
+	g.clients[i] = c
+	return c, nil
+}
+
+// NewV4Client returns a GraphQL client for the server c is configured for,
+// sending requests with hc. Requests go to operator.V4Endpoint if set, or else
+// the GraphQL endpoint derived from the base URL of c, so that GitHub
+// Enterprise and test servers are supported.
+func NewV4Client(c *github.Client, hc *http.Client) *githubv4.Client {
+	if operator.V4Endpoint != "" {
+		return githubv4.NewEnterpriseClient(operator.V4Endpoint, hc)
+	}
+	if c.BaseURL.String() == "https://api.github.com/" {
+		return githubv4.NewClient(hc)
+	}
+	return githubv4.NewEnterpriseClient(graphQLURL(c.BaseURL.String()), hc)
+}
+
+// graphQLURL returns the GraphQL endpoint for a REST API base URL, such as
+// https://example.com/api/graphql for https://example.com/api/v3/.
+func graphQLURL(base string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(base, "/"), "/v3") + "/graphql"
 }
 
 func getKeyReal(ctx context.Context) ([]byte, error) {
@@ -18,10 +18,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 
 	"github.com/bradleyfalzon/ghinstallation"
+	"github.com/gregjones/httpcache"
+	"github.com/ossf/allstar/pkg/config/operator"
 )
 
 func TestGet(t *testing.T) {
@@ -75,3 +78,90 @@ func TestGet(t *testing.T) {
 		t.Errorf("Got wrong client")
 	}
 }
+
+func TestGetEnterprise(t *testing.T) {
+	ghinstallationNewAppsTransport = func(http.RoundTripper, int64,
+		[]byte) (*ghinstallation.AppsTransport, error) {
+		return &ghinstallation.AppsTransport{}, nil
+	}
+	ghinstallationNew = func(r http.RoundTripper, a int64, i int64,
+		f []byte) (*ghinstallation.Transport, error) {
+		return &ghinstallation.Transport{}, nil
+	}
+	getKey = func(ctx context.Context) ([]byte, error) {
+		return nil, nil
+	}
+	old := operator.RESTEndpoint
+	operator.RESTEndpoint = "https://github.example.com/api/v3/"
+	defer func() { operator.RESTEndpoint = old }()
+	ghc, err := NewGHClients(context.Background(), http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, i := range []int64{0, 123} {
+		c, err := ghc.Get(i)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := c.BaseURL.String(); got != operator.RESTEndpoint {
+			t.Errorf("Unexpected base URL: %v", got)
+		}
+		var base string
+		switch tr := c.Client().Transport.(*httpcache.Transport).Transport.(type) {
+		case *ghinstallation.AppsTransport:
+			base = tr.BaseURL
+		case *ghinstallation.Transport:
+			base = tr.BaseURL
+		}
+		if base != "https://github.example.com/api/v3" {
+			t.Errorf("Unexpected installation base URL: %v", base)
+		}
+	}
+}
+
+func TestTagTransport(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer srv.Close()
+	c := &http.Client{
+		Transport: &tagTransport{
+			base:      http.DefaultTransport,
+			userAgent: "allstar-test/1.0",
+			header:    "X-Trace",
+			value:     "abc",
+		},
+	}
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	req.Header.Set("User-Agent", "go-github")
+	rsp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rsp.Body.Close()
+	if ua := got.Get("User-Agent"); ua != "allstar-test/1.0" {
+		t.Errorf("Unexpected User-Agent: %v", ua)
+	}
+	if v := got.Get("X-Trace"); v != "abc" {
+		t.Errorf("Unexpected tag header: %v", v)
+	}
+	if req.Header.Get("User-Agent") != "go-github" {
+		t.Errorf("Original request was modified")
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/api/v3/": "https://example.com/api/graphql",
+		"http://127.0.0.1:8080/":      "http://127.0.0.1:8080/graphql",
+	}
+	for base, exp := range tests {
+		if got := graphQLURL(base); got != exp {
+			t.Errorf("Unexpected GraphQL URL for %v. Expected: %v Got: %v", base, exp, got)
+		}
+	}
+}
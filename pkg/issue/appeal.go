@@ -0,0 +1,80 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+
+	"github.com/google/go-github/v39/github"
+)
+
+type appealLabelKey struct{}
+
+// WithAppealLabel returns a copy of ctx that makes Ensure treat issues with
+// label, added by a repo admin, as under appeal.
+func WithAppealLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, appealLabelKey{}, label)
+}
+
+func appealLabel(ctx context.Context) string {
+	l, _ := ctx.Value(appealLabelKey{}).(string)
+	return l
+}
+
+// checkAppeal returns true if the issue has the appeal label and the most
+// recent user to add it is a repo admin. Labels added by other users are
+// ignored.
+func checkAppeal(ctx context.Context, issues issues, perms repoPermissions, owner, repo string, issue *github.Issue) (bool, error) {
+	label := appealLabel(ctx)
+	if label == "" || !hasLabel(issue, label) {
+		return false, nil
+	}
+	opt := &github.ListOptions{
+		PerPage: 100,
+	}
+	var by string
+	for {
+		es, resp, err := issues.ListIssueEvents(ctx, owner, repo, issue.GetNumber(), opt)
+		if err != nil {
+			return false, err
+		}
+		for _, e := range es {
+			if e.GetEvent() == "labeled" && e.GetLabel().GetName() == label {
+				by = e.GetActor().GetLogin()
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	if by == "" {
+		return false, nil
+	}
+	p, _, err := perms.GetPermissionLevel(ctx, owner, repo, by)
+	if err != nil {
+		return false, err
+	}
+	return p.GetPermission() == "admin", nil
+}
+
+func hasLabel(issue *github.Issue, label string) bool {
+	for _, l := range issue.Labels {
+		if l.GetName() == label {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestEnsureAppeal(t *testing.T) {
+	getPermissionLevel = func(ctx context.Context, owner, repo, user string) (
+		*github.RepositoryPermissionLevel, *github.Response, error) {
+		p := "write"
+		if user == "admin" {
+			p = "admin"
+		}
+		return &github.RepositoryPermissionLevel{Permission: &p}, nil, nil
+	}
+	defer func() { listIssueEvents = nil }()
+	labeled := func(user string) *github.IssueEvent {
+		return &github.IssueEvent{
+			Event: github.String("labeled"),
+			Actor: &github.User{Login: github.String(user)},
+			Label: &github.Label{Name: github.String("allstar-appeal")},
+		}
+	}
+	tests := []struct {
+		Name   string
+		Label  string
+		Labels []string
+		Events []*github.IssueEvent
+		Reopen bool
+	}{
+		{
+			Name:   "AdminAppeal",
+			Label:  "allstar-appeal",
+			Labels: []string{"allstar", "allstar-appeal"},
+			Events: []*github.IssueEvent{labeled("admin")},
+		},
+		{
+			Name:   "NonAdmin",
+			Label:  "allstar-appeal",
+			Labels: []string{"allstar", "allstar-appeal"},
+			Events: []*github.IssueEvent{labeled("dev")},
+			Reopen: true,
+		},
+		{
+			Name:   "ReaddedByNonAdmin",
+			Label:  "allstar-appeal",
+			Labels: []string{"allstar", "allstar-appeal"},
+			Events: []*github.IssueEvent{labeled("admin"), labeled("dev")},
+			Reopen: true,
+		},
+		{
+			Name:   "LabelRemoved",
+			Label:  "allstar-appeal",
+			Labels: []string{"allstar"},
+			Events: []*github.IssueEvent{labeled("admin")},
+			Reopen: true,
+		},
+		{
+			Name:   "NotConfigured",
+			Labels: []string{"allstar", "allstar-appeal"},
+			Events: []*github.IssueEvent{labeled("admin")},
+			Reopen: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			closed := "closed"
+			var labels []*github.Label
+			for _, l := range test.Labels {
+				labels = append(labels, &github.Label{Name: github.String(l)})
+			}
+			listByRepo = func(ctx context.Context, owner string, repo string,
+				opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+				return []*github.Issue{{Number: github.Int(1), State: &closed, Labels: labels,
+					Body: github.String("<!-- allstar-policy: thispolicy -->")}}, &github.Response{}, nil
+			}
+			listIssueEvents = func(ctx context.Context, owner, repo string, number int,
+				opts *github.ListOptions) ([]*github.IssueEvent, *github.Response, error) {
+				return test.Events, &github.Response{}, nil
+			}
+			reopened := false
+			edit = func(ctx context.Context, owner string, repo string, number int,
+				issue *github.IssueRequest) (*github.Issue, *github.Response, error) {
+				reopened = true
+				return nil, nil, nil
+			}
+			createComment = func(ctx context.Context, owner string, repo string, number int,
+				c *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+				return nil, nil, nil
+			}
+			ctx := WithAppealLabel(context.Background(), test.Label)
+			err := ensure(ctx, mockIssues{}, mockPerms{}, markerFinder{mockIssues{}},
+				"", "", "thispolicy", "Status text")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if reopened != test.Reopen {
+				t.Errorf("Unexpected reopen. Expected: %v Got: %v", test.Reopen, reopened)
+			}
+		})
+	}
+}
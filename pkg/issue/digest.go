@@ -0,0 +1,154 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config/operator"
+)
+
+const digestTitle = "Security Policy violations"
+
+// digestMarker is a hidden fingerprint added to the body of the digest issue,
+// like markerFormat for policy issues.
+const digestMarker = "<!-- allstar-digest -->"
+
+// DigestSection is the status of a single failing policy in a digest issue.
+type DigestSection struct {
+	Policy string
+	Text   string
+}
+
+// EnsureDigest ensures a single digest issue exists and is open for the
+// provided repo, listing every failing policy section. The issue body is
+// updated when the set of sections changes.
+func EnsureDigest(ctx context.Context, c *github.Client, owner, repo string, sections []DigestSection) error {
+	return ensureDigest(ctx, c.Issues, owner, repo, sections)
+}
+
+func digestBody(sections []DigestSection) string {
+	ss := append([]DigestSection(nil), sections...)
+	sort.Slice(ss, func(i, j int) bool { return ss[i].Policy < ss[j].Policy })
+	var b strings.Builder
+	b.WriteString("Allstar has detected that this repository is out of compliance with the following security policies.\n\n")
+	for _, s := range ss {
+		fmt.Fprintf(&b, "## %v\n\n%v\n\n", s.Policy, s.Text)
+	}
+	b.WriteString(operator.GitHubIssueFooter)
+	b.WriteString("\n\n" + digestMarker)
+	return b.String()
+}
+
+func digestPolicies(sections []DigestSection) string {
+	var ps []string
+	for _, s := range sections {
+		ps = append(ps, s.Policy)
+	}
+	sort.Strings(ps)
+	return strings.Join(ps, ", ")
+}
+
+func ensureDigest(ctx context.Context, issues issues, owner, repo string, sections []DigestSection) error {
+	issue, err := findIssue(ctx, issues, owner, repo, digestMarker, digestTitle)
+	if err != nil {
+		return err
+	}
+	body := digestBody(sections)
+	if issue == nil {
+		t := digestTitle
+		new := &github.IssueRequest{
+			Title:  &t,
+			Body:   &body,
+			Labels: &[]string{operator.GitHubIssueLabel},
+		}
+		_, _, err := issues.Create(ctx, owner, repo, new)
+		return err
+	}
+	var comment string
+	update := &github.IssueRequest{}
+	changed := false
+	if issue.GetState() == "closed" {
+		state := "open"
+		update.State = &state
+		changed = true
+		comment = "Reopening issue. Failing policies: " + digestPolicies(sections)
+	}
+	if issue.GetBody() != body {
+		update.Body = &body
+		changed = true
+		// A body that only lacks the marker is from before markers were
+		// added, the policies have not changed.
+		if old, _ := withMarker(issue, digestMarker); comment == "" && old != body {
+			comment = "Updating issue, failing policies changed: " + digestPolicies(sections)
+		}
+	}
+	if changed {
+		if _, _, err := issues.Edit(ctx, owner, repo, issue.GetNumber(), update); err != nil {
+			return err
+		}
+	}
+	if comment == "" && issue.GetUpdatedAt().Before(time.Now().Add(-1*operator.NoticePingDuration)) {
+		comment = "Updating issue after ping interval. Failing policies: " + digestPolicies(sections)
+	}
+	if comment == "" {
+		return nil
+	}
+	_, _, err = issues.CreateComment(ctx, owner, repo, issue.GetNumber(), &github.IssueComment{
+		Body: &comment,
+	})
+	return err
+}
+
+// DigestPending returns whether EnsureDigest would create, reopen, update, or
+// ping the digest issue for the provided repo and sections.
+func DigestPending(ctx context.Context, c *github.Client, owner, repo string, sections []DigestSection) (bool, error) {
+	return digestPending(ctx, c.Issues, owner, repo, sections)
+}
+
+func digestPending(ctx context.Context, issues issues, owner, repo string, sections []DigestSection) (bool, error) {
+	issue, err := findIssue(ctx, issues, owner, repo, digestMarker, digestTitle)
+	if err != nil {
+		return false, err
+	}
+	if issue == nil || issue.GetState() == "closed" {
+		return true, nil
+	}
+	// A body that only lacks the marker is updated, but is not a change of the
+	// failing policies.
+	if old, _ := withMarker(issue, digestMarker); old != digestBody(sections) {
+		return true, nil
+	}
+	return issue.GetUpdatedAt().Before(time.Now().Add(-1 * operator.NoticePingDuration)), nil
+}
+
+// CloseDigest ensures that there is not a digest issue open for the provided
+// repo. If open it closes it with a message.
+func CloseDigest(ctx context.Context, c *github.Client, owner, repo string) error {
+	return closeDigest(ctx, c.Issues, owner, repo)
+}
+
+func closeDigest(ctx context.Context, issues issues, owner, repo string) error {
+	issue, err := findIssue(ctx, issues, owner, repo, digestMarker, digestTitle)
+	if err != nil {
+		return err
+	}
+	return closeFound(ctx, issues, owner, repo, issue, digestMarker)
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestEnsureDigest(t *testing.T) {
+	title := digestTitle
+	open := "open"
+	closed := "closed"
+	now := time.Now()
+	sections := []DigestSection{
+		{Policy: "SECURITY.md", Text: "Security policy not enabled."},
+		{Policy: "Branch Protection", Text: "No protection found for branch main"},
+	}
+	current := digestBody(sections)
+	unmarked := strings.TrimSuffix(current, "\n\n"+digestMarker)
+	otherTitle := "Renamed by a maintainer"
+	old := "old body"
+	tests := []struct {
+		Name          string
+		Existing      []*github.Issue
+		ExpectCreate  bool
+		ExpectEdit    bool
+		ExpectComment string
+	}{
+		{
+			Name:         "NoIssue",
+			ExpectCreate: true,
+		},
+		{
+			Name: "Unchanged",
+			Existing: []*github.Issue{
+				{Title: &title, State: &open, Body: &current, UpdatedAt: &now},
+			},
+		},
+		{
+			Name: "RenamedWithMarker",
+			Existing: []*github.Issue{
+				{Title: &otherTitle, State: &open, Body: &current, UpdatedAt: &now},
+			},
+		},
+		{
+			Name: "UnmarkedByTitle",
+			Existing: []*github.Issue{
+				{Title: &title, State: &open, Body: &unmarked, UpdatedAt: &now},
+			},
+			ExpectEdit: true,
+		},
+		{
+			Name: "Changed",
+			Existing: []*github.Issue{
+				{Title: &title, State: &open, Body: &old, UpdatedAt: &now},
+			},
+			ExpectEdit:    true,
+			ExpectComment: "Updating issue, failing policies changed: Branch Protection, SECURITY.md",
+		},
+		{
+			Name: "Closed",
+			Existing: []*github.Issue{
+				{Title: &title, State: &closed, Body: &current, UpdatedAt: &now},
+			},
+			ExpectEdit:    true,
+			ExpectComment: "Reopening issue.",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			listByRepo = func(ctx context.Context, owner string, repo string,
+				opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+				return test.Existing, &github.Response{NextPage: 0}, nil
+			}
+			createCalled := false
+			create = func(ctx context.Context, owner string, repo string,
+				issue *github.IssueRequest) (*github.Issue, *github.Response, error) {
+				if !strings.Contains(issue.GetBody(), "## Branch Protection") ||
+					!strings.Contains(issue.GetBody(), "## SECURITY.md") ||
+					!strings.Contains(issue.GetBody(), digestMarker) {
+					t.Errorf("Unexpected body: %v", issue.GetBody())
+				}
+				createCalled = true
+				return nil, nil, nil
+			}
+			editCalled := false
+			edit = func(ctx context.Context, owner string, repo string, number int,
+				issue *github.IssueRequest) (*github.Issue, *github.Response, error) {
+				editCalled = true
+				return nil, nil, nil
+			}
+			comment := ""
+			createComment = func(ctx context.Context, owner string, repo string,
+				number int, c *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+				comment = c.GetBody()
+				return nil, nil, nil
+			}
+			if err := ensureDigest(context.Background(), mockIssues{}, "", "", sections); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if createCalled != test.ExpectCreate {
+				t.Errorf("Unexpected create: %v", createCalled)
+			}
+			if editCalled != test.ExpectEdit {
+				t.Errorf("Unexpected edit: %v", editCalled)
+			}
+			if !strings.HasPrefix(comment, test.ExpectComment) || (test.ExpectComment == "" && comment != "") {
+				t.Errorf("Unexpected comment: %v", comment)
+			}
+		})
+	}
+}
+
+func TestDigestPending(t *testing.T) {
+	title := digestTitle
+	open := "open"
+	now := time.Now()
+	sections := []DigestSection{{Policy: "SECURITY.md", Text: "Security policy not enabled."}}
+	current := digestBody(sections)
+	unmarked := strings.TrimSuffix(current, "\n\n"+digestMarker)
+	old := "old body"
+	tests := []struct {
+		Name     string
+		Existing []*github.Issue
+		Expect   bool
+	}{
+		{Name: "NoIssue", Expect: true},
+		{Name: "Unchanged", Existing: []*github.Issue{{Title: &title, State: &open, Body: &current, UpdatedAt: &now}}, Expect: false},
+		{Name: "Unmarked", Existing: []*github.Issue{{Title: &title, State: &open, Body: &unmarked, UpdatedAt: &now}}, Expect: false},
+		{Name: "Changed", Existing: []*github.Issue{{Title: &title, State: &open, Body: &old, UpdatedAt: &now}}, Expect: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			listByRepo = func(ctx context.Context, owner string, repo string,
+				opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+				return test.Existing, &github.Response{NextPage: 0}, nil
+			}
+			got, err := digestPending(context.Background(), mockIssues{}, "", "", sections)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != test.Expect {
+				t.Errorf("Unexpected pending. Expected: %v Got: %v", test.Expect, got)
+			}
+		})
+	}
+}
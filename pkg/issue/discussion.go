@@ -0,0 +1,110 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/ghclients"
+	"github.com/shurcooL/githubv4"
+)
+
+type v4client interface {
+	Query(context.Context, interface{}, map[string]interface{}) error
+	Mutate(context.Context, interface{}, githubv4.Input, map[string]interface{}) error
+}
+
+var newV4Client func(*github.Client) v4client
+
+func init() {
+	newV4Client = func(c *github.Client) v4client {
+		return ghclients.NewV4Client(c, c.Client())
+	}
+}
+
+var errNoDiscussionCategory = errors.New("repository has no discussion categories")
+
+// EnsureDiscussion ensures a discussion exists for the provided repo and
+// policy, for repos that use discussions instead of issues. A new discussion
+// includes the provided text, and is created in the operator.DiscussionCategory
+// category, or the first category if that does not exist. Existing discussions
+// are not updated.
+func EnsureDiscussion(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
+	return ensureDiscussion(ctx, newV4Client(c), owner, repo, policy, text)
+}
+
+func ensureDiscussion(ctx context.Context, v4 v4client, owner, repo, policy, text string) error {
+	var q struct {
+		Repository struct {
+			ID          githubv4.ID
+			Discussions struct {
+				Nodes []struct {
+					Title string
+					Body  string
+				}
+			} `graphql:"discussions(first: 100, orderBy: {field: UPDATED_AT, direction: DESC})"`
+			DiscussionCategories struct {
+				Nodes []struct {
+					ID   githubv4.ID
+					Name string
+				}
+			} `graphql:"discussionCategories(first: 25)"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	vars := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+	}
+	if err := v4.Query(ctx, &q, vars); err != nil {
+		return err
+	}
+	p := Render(policy, text)
+	marker := fmt.Sprintf(markerFormat, policy)
+	for _, d := range q.Repository.Discussions.Nodes {
+		if d.Title == p.Title || strings.Contains(d.Body, marker) {
+			return nil
+		}
+	}
+	cats := q.Repository.DiscussionCategories.Nodes
+	if len(cats) == 0 {
+		return errNoDiscussionCategory
+	}
+	cat := cats[0].ID
+	for _, c := range cats {
+		if strings.EqualFold(c.Name, operator.DiscussionCategory) {
+			cat = c.ID
+			break
+		}
+	}
+	var m struct {
+		CreateDiscussion struct {
+			Discussion struct {
+				ID githubv4.ID
+			}
+		} `graphql:"createDiscussion(input: $input)"`
+	}
+	input := githubv4.CreateDiscussionInput{
+		RepositoryID: q.Repository.ID,
+		Title:        githubv4.String(p.Title),
+		Body:         githubv4.String(p.Body),
+		CategoryID:   cat,
+	}
+	return v4.Mutate(ctx, &m, input, nil)
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/shurcooL/githubv4"
+)
+
+type mockV4 struct {
+	titles     []string
+	categories []string
+	created    *githubv4.CreateDiscussionInput
+}
+
+func (m *mockV4) Query(ctx context.Context, q interface{}, vars map[string]interface{}) error {
+	r := reflect.ValueOf(q).Elem().FieldByName("Repository")
+	r.FieldByName("ID").Set(reflect.ValueOf(githubv4.ID("R1")))
+	ds := r.FieldByName("Discussions").FieldByName("Nodes")
+	for _, t := range m.titles {
+		n := reflect.New(ds.Type().Elem()).Elem()
+		n.FieldByName("Title").SetString(t)
+		ds.Set(reflect.Append(ds, n))
+	}
+	cs := r.FieldByName("DiscussionCategories").FieldByName("Nodes")
+	for _, c := range m.categories {
+		n := reflect.New(cs.Type().Elem()).Elem()
+		n.FieldByName("ID").Set(reflect.ValueOf(githubv4.ID("C-" + c)))
+		n.FieldByName("Name").SetString(c)
+		cs.Set(reflect.Append(cs, n))
+	}
+	return nil
+}
+
+func (m *mockV4) Mutate(ctx context.Context, mut interface{}, input githubv4.Input, vars map[string]interface{}) error {
+	in := input.(githubv4.CreateDiscussionInput)
+	m.created = &in
+	return nil
+}
+
+func TestEnsureDiscussion(t *testing.T) {
+	m := &mockV4{categories: []string{"Announcements", "General"}}
+	if err := ensureDiscussion(context.Background(), m, "org", "repo", "SECURITY.md", "text"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.created == nil {
+		t.Fatal("Expected discussion to be created.")
+	}
+	if m.created.CategoryID != githubv4.ID("C-General") || m.created.Title != "Security Policy violation SECURITY.md" {
+		t.Errorf("Unexpected discussion: %+v", m.created)
+	}
+
+	m = &mockV4{titles: []string{"Security Policy violation SECURITY.md"}, categories: []string{"General"}}
+	if err := ensureDiscussion(context.Background(), m, "org", "repo", "SECURITY.md", "text"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.created != nil {
+		t.Error("Expected existing discussion to be reused.")
+	}
+
+	m = &mockV4{}
+	if err := ensureDiscussion(context.Background(), m, "org", "repo", "SECURITY.md", "text"); err != errNoDiscussionCategory {
+		t.Errorf("Expected no category error, got: %v", err)
+	}
+}
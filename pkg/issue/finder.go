@@ -0,0 +1,144 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config/operator"
+)
+
+// markerFormat is a hidden fingerprint added to the body of policy issues, so
+// they can be found even if the title is edited.
+const markerFormat = "<!-- allstar-policy: %v -->"
+
+// IssueFinder locates the existing Allstar issue for a policy on a repo. Ensure
+// and Close use it to decide whether to create, reopen, ping, or close an
+// issue.
+type IssueFinder interface {
+	// Find returns the open or closed issue for the policy, or nil if there is
+	// none.
+	Find(ctx context.Context, owner, repo, policy string) (*github.Issue, error)
+}
+
+var newFinder func(*github.Client) IssueFinder
+
+func init() {
+	newFinder = func(c *github.Client) IssueFinder {
+		return markerFinder{issues: c.Issues}
+	}
+}
+
+// SetIssueFinder replaces how Ensure and Close find existing issues, such as
+// to look them up in an external tracker. f is called with the installation
+// client for each repo. It is intended to be called at startup.
+func SetIssueFinder(f func(*github.Client) IssueFinder) {
+	newFinder = f
+}
+
+// markerFinder is the default IssueFinder. It looks for an Allstar labeled
+// issue with the policy marker in the body, falling back to the title for
+// issues created before markers were added. If duplicates exist, such as from
+// an older Allstar version, an open issue is preferred over a closed one, so
+// that it is updated instead of another being reopened.
+type markerFinder struct {
+	issues issues
+}
+
+func (m markerFinder) Find(ctx context.Context, owner, repo, policy string) (*github.Issue, error) {
+	return findIssue(ctx, m.issues, owner, repo, fmt.Sprintf(markerFormat, policy), fmt.Sprintf(title, policy))
+}
+
+// findIssue returns the Allstar labeled issue with marker in the body, or
+// failing that with title t, preferring an open issue over a closed one.
+func findIssue(ctx context.Context, issues issues, owner, repo, marker, t string) (*github.Issue, error) {
+	all, err := listAllstarIssues(ctx, issues, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var byMarker, byTitle *github.Issue
+	for _, i := range all {
+		switch {
+		case strings.Contains(i.GetBody(), marker):
+			if i.GetState() == "open" {
+				return i, nil
+			}
+			if byMarker == nil {
+				byMarker = i
+			}
+		case i.GetTitle() == t:
+			if byTitle == nil || (byTitle.GetState() != "open" && i.GetState() == "open") {
+				byTitle = i
+			}
+		}
+	}
+	if byTitle.GetState() == "open" || byMarker == nil {
+		return byTitle, nil
+	}
+	return byMarker, nil
+}
+
+// withMarker returns the body of issue with marker appended, if it is missing.
+// Issues found by title predate the marker, adding it lets them be found even
+// if the title is edited.
+func withMarker(issue *github.Issue, marker string) (string, bool) {
+	b := issue.GetBody()
+	if strings.Contains(b, marker) {
+		return b, false
+	}
+	return b + "\n\n" + marker, true
+}
+
+func listAllstarIssues(ctx context.Context, issues issues, owner, repo string) ([]*github.Issue, error) {
+	opt := &github.IssueListByRepoOptions{
+		State:  "all",
+		Labels: []string{operator.GitHubIssueLabel},
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	var allIssues []*github.Issue
+	retries := 0
+	for {
+		is, resp, err := issues.ListByRepo(ctx, owner, repo, opt)
+		if err != nil {
+			if retries >= maxRateLimitRetries {
+				return nil, err
+			}
+			if werr := waitRateLimit(ctx, err); werr != nil {
+				return nil, werr
+			}
+			retries++
+			continue
+		}
+		retries = 0
+		for _, i := range is {
+			if !i.IsPullRequest() {
+				allIssues = append(allIssues, i)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		if err := waitRemaining(ctx, resp.Rate); err != nil {
+			return nil, err
+		}
+		opt.Page = resp.NextPage
+	}
+	return allIssues, nil
+}
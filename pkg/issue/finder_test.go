@@ -0,0 +1,130 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestMarkerFinder(t *testing.T) {
+	renamed := "Renamed by maintainer"
+	marked := fmt.Sprintf("Status\n\n"+markerFormat, "thispolicy")
+	oldTitle := fmt.Sprintf(title, "thispolicy")
+	otherTitle := fmt.Sprintf(title, "otherpolicy")
+	open := "open"
+	closed := "closed"
+	tests := []struct {
+		Name   string
+		Issues []*github.Issue
+		Expect int
+	}{
+		{
+			Name: "Marker",
+			Issues: []*github.Issue{
+				{Number: github.Int(1), Title: &otherTitle},
+				{Number: github.Int(2), Title: &renamed, Body: &marked},
+			},
+			Expect: 2,
+		},
+		{
+			Name: "TitleFallback",
+			Issues: []*github.Issue{
+				{Number: github.Int(3), Title: &oldTitle},
+			},
+			Expect: 3,
+		},
+		{
+			Name: "PreferOpen",
+			Issues: []*github.Issue{
+				{Number: github.Int(1), Title: &renamed, Body: &marked, State: &closed},
+				{Number: github.Int(2), Title: &renamed, Body: &marked, State: &open},
+			},
+			Expect: 2,
+		},
+		{
+			Name: "PreferOpenTitle",
+			Issues: []*github.Issue{
+				{Number: github.Int(1), Title: &renamed, Body: &marked, State: &closed},
+				{Number: github.Int(3), Title: &oldTitle, State: &open},
+			},
+			Expect: 3,
+		},
+		{
+			Name: "PreferMarkerWhenClosed",
+			Issues: []*github.Issue{
+				{Number: github.Int(3), Title: &oldTitle, State: &closed},
+				{Number: github.Int(1), Title: &renamed, Body: &marked, State: &closed},
+			},
+			Expect: 1,
+		},
+		{
+			Name: "None",
+			Issues: []*github.Issue{
+				{Number: github.Int(1), Title: &otherTitle},
+			},
+			Expect: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			listByRepo = func(ctx context.Context, owner string, repo string,
+				opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+				return test.Issues, &github.Response{NextPage: 0}, nil
+			}
+			i, err := markerFinder{mockIssues{}}.Find(context.Background(), "", "", "thispolicy")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if i.GetNumber() != test.Expect {
+				t.Errorf("Unexpected issue. Expected: %v Got: %v", test.Expect, i.GetNumber())
+			}
+		})
+	}
+}
+
+type staticFinder struct {
+	issue *github.Issue
+}
+
+func (s staticFinder) Find(ctx context.Context, owner, repo, policy string) (*github.Issue, error) {
+	return s.issue, nil
+}
+
+func TestCustomFinder(t *testing.T) {
+	// List should not be used when a custom finder is provided.
+	listByRepo = nil
+	closed := "closed"
+	edited := 0
+	edit = func(ctx context.Context, owner string, repo string, number int,
+		issue *github.IssueRequest) (*github.Issue, *github.Response, error) {
+		edited = number
+		return nil, nil, nil
+	}
+	createComment = func(ctx context.Context, owner string, repo string, number int,
+		comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+		return nil, nil, nil
+	}
+	f := staticFinder{&github.Issue{Number: github.Int(42), State: &closed}}
+	if err := ensure(context.Background(), mockIssues{}, mockPerms{}, f, "", "", "thispolicy", "Status text"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if edited != 42 {
+		t.Errorf("Expected issue from custom finder to be reopened, got: %v", edited)
+	}
+}
@@ -35,68 +35,67 @@ type issues interface {
 		*github.Issue, *github.Response, error)
 	CreateComment(context.Context, string, string, int, *github.IssueComment) (
 		*github.IssueComment, *github.Response, error)
-}
-
-func getPolicyIssue(ctx context.Context, issues issues, owner, repo, policy string) (*github.Issue, error) {
-	opt := &github.IssueListByRepoOptions{
-		State:  "all",
-		Labels: []string{operator.GitHubIssueLabel},
-		ListOptions: github.ListOptions{
-			PerPage: 100,
-		},
-	}
-	var allIssues []*github.Issue
-	for {
-		is, resp, err := issues.ListByRepo(ctx, owner, repo, opt)
-		if err != nil {
-			return nil, err
-		}
-		allIssues = append(allIssues, is...)
-		if resp.NextPage == 0 {
-			break
-		}
-		opt.Page = resp.NextPage
-	}
-	var issue *github.Issue
-	t := fmt.Sprintf(title, policy)
-	for _, i := range allIssues {
-		if i.GetTitle() == t {
-			issue = i
-			break
-		}
-	}
-	return issue, nil
+	ListComments(context.Context, string, string, int, *github.IssueListCommentsOptions) (
+		[]*github.IssueComment, *github.Response, error)
+	ListIssueEvents(context.Context, string, string, int, *github.ListOptions) (
+		[]*github.IssueEvent, *github.Response, error)
 }
 
 // Ensure ensures an issue exists and is open for the provided repo and
 // policy. If opening, re-opening, or pinging an issue, the provided text will
-// be included.
+// be included. An existing issue is found by its label and a hidden marker in
+// the body, and reused rather than opening another. An issue found by title
+// alone, from before the marker, has it added. A repo admin can suppress
+// reopening and pings for a time by commenting "/allstar snooze 14d" on the
+// issue.
+//
+// Pings happen at most every operator.NoticePingDuration, or the interval set
+// with WithPingInterval. If an appeal label is set with WithAppealLabel, and a
+// repo admin added it to the issue, the issue is not reopened or pinged.
 func Ensure(ctx context.Context, c *github.Client, owner, repo, policy, text string) error {
-	return ensure(ctx, c.Issues, owner, repo, policy, text)
+	return ensure(ctx, c.Issues, c.Repositories, newFinder(c), owner, repo, policy, text)
 }
 
-func ensure(ctx context.Context, issues issues, owner, repo, policy, text string) error {
-	issue, err := getPolicyIssue(ctx, issues, owner, repo, policy)
+func ensure(ctx context.Context, issues issues, perms repoPermissions, f IssueFinder, owner, repo, policy, text string) error {
+	issue, err := f.Find(ctx, owner, repo, policy)
 	if err != nil {
 		return err
 	}
 	if issue == nil {
-		body := fmt.Sprintf("Allstar has detected that this repository’s %v security policy is out of compliance. Status:\n%v\n\n%v",
-			policy, text, operator.GitHubIssueFooter)
-		t := fmt.Sprintf(title, policy)
+		p := Render(policy, text)
 		new := &github.IssueRequest{
-			Title:  &t,
-			Body:   &body,
-			Labels: &[]string{operator.GitHubIssueLabel},
+			Title:  &p.Title,
+			Body:   &p.Body,
+			Labels: &p.Labels,
 		}
 		_, _, err := issues.Create(ctx, owner, repo, new)
 		return err
 	}
-	if issue.GetState() == "closed" {
+	ping := issue.GetUpdatedAt().Before(time.Now().Add(-1 * pingInterval(ctx)))
+	quiet := false
+	if issue.GetState() == "closed" || ping {
+		snoozed, err := checkSnooze(ctx, issues, perms, owner, repo, issue.GetNumber())
+		if err != nil {
+			return err
+		}
+		appealed := false
+		if !snoozed {
+			appealed, err = checkAppeal(ctx, issues, perms, owner, repo, issue)
+			if err != nil {
+				return err
+			}
+		}
+		quiet = snoozed || appealed
+	}
+	body, backfill := withMarker(issue, fmt.Sprintf(markerFormat, policy))
+	if issue.GetState() == "closed" && !quiet {
 		state := "open"
 		update := &github.IssueRequest{
 			State: &state,
 		}
+		if backfill {
+			update.Body = &body
+		}
 		if _, _, err := issues.Edit(ctx, owner, repo, issue.GetNumber(), update); err != nil {
 			return err
 		}
@@ -107,7 +106,15 @@ func ensure(ctx context.Context, issues issues, owner, repo, policy, text string
 		_, _, err := issues.CreateComment(ctx, owner, repo, issue.GetNumber(), comment)
 		return err
 	}
-	if issue.GetUpdatedAt().Before(time.Now().Add(-1 * operator.NoticePingDuration)) {
+	if backfill {
+		if _, _, err := issues.Edit(ctx, owner, repo, issue.GetNumber(), &github.IssueRequest{Body: &body}); err != nil {
+			return err
+		}
+	}
+	if quiet {
+		return nil
+	}
+	if ping {
 		body := "Updating issue after ping interval. Status:\n" + text
 		comment := &github.IssueComment{
 			Body: &body,
@@ -118,17 +125,80 @@ func ensure(ctx context.Context, issues issues, owner, repo, policy, text string
 	return nil
 }
 
+// Pending returns whether Ensure would create, reopen, or ping the issue for
+// the provided repo and policy, with the ping interval of ctx. Snoozes and
+// appeals are not checked, so it may report a change Ensure would skip.
+func Pending(ctx context.Context, c *github.Client, owner, repo, policy string) (bool, error) {
+	return pending(ctx, newFinder(c), owner, repo, policy)
+}
+
+func pending(ctx context.Context, f IssueFinder, owner, repo, policy string) (bool, error) {
+	issue, err := f.Find(ctx, owner, repo, policy)
+	if err != nil {
+		return false, err
+	}
+	if issue == nil || issue.GetState() == "closed" {
+		return true, nil
+	}
+	return issue.GetUpdatedAt().Before(time.Now().Add(-1 * pingInterval(ctx))), nil
+}
+
+// Payload is the content of a new policy issue.
+type Payload struct {
+	Title string
+	Body  string
+
+	// Labels are the labels added to the issue.
+	Labels []string
+
+	// Assignees are the users assigned to the issue. Allstar does not assign
+	// issues, so this is empty, users to notify are mentioned in the body.
+	Assignees []string
+}
+
+// Render returns the issue Ensure creates for policy with text, including the
+// marker used to find it later, without creating it. It lets operators review
+// issue wording before enabling the issue action.
+func Render(policy, text string) *Payload {
+	return &Payload{
+		Title: fmt.Sprintf(title, policy),
+		Body: fmt.Sprintf("Allstar has detected that this repository’s %v security policy is out of compliance. Status:\n%v\n\n%v\n\n"+markerFormat,
+			policy, text, operator.GitHubIssueFooter, policy),
+		Labels: []string{operator.GitHubIssueLabel},
+	}
+}
+
+type pingIntervalKey struct{}
+
+// WithPingInterval returns a copy of ctx that makes Ensure wait at least d
+// between pings of an open issue, instead of operator.NoticePingDuration.
+func WithPingInterval(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, pingIntervalKey{}, d)
+}
+
+func pingInterval(ctx context.Context) time.Duration {
+	if d, ok := ctx.Value(pingIntervalKey{}).(time.Duration); ok && d > 0 {
+		return d
+	}
+	return operator.NoticePingDuration
+}
+
 // Close ensures that there is not an issue open for the provided repo and
 // policy. If open it closes it with a message.
 func Close(ctx context.Context, c *github.Client, owner, repo, policy string) error {
-	return closeIssue(ctx, c.Issues, owner, repo, policy)
+	return closeIssue(ctx, c.Issues, newFinder(c), owner, repo, policy)
 }
 
-func closeIssue(ctx context.Context, issues issues, owner, repo, policy string) error {
-	issue, err := getPolicyIssue(ctx, issues, owner, repo, policy)
+func closeIssue(ctx context.Context, issues issues, f IssueFinder, owner, repo, policy string) error {
+	issue, err := f.Find(ctx, owner, repo, policy)
 	if err != nil {
 		return err
 	}
+	return closeFound(ctx, issues, owner, repo, issue, fmt.Sprintf(markerFormat, policy))
+}
+
+// closeFound closes issue if it is open, adding marker to the body if missing.
+func closeFound(ctx context.Context, issues issues, owner, repo string, issue *github.Issue, marker string) error {
 	if issue.GetState() == "open" {
 		body := "Policy is now in compliance. Closing issue."
 		comment := &github.IssueComment{
@@ -141,6 +211,9 @@ func closeIssue(ctx context.Context, issues issues, owner, repo, policy string)
 		update := &github.IssueRequest{
 			State: &state,
 		}
+		if b, backfill := withMarker(issue, marker); backfill {
+			update.Body = &b
+		}
 		if _, _, err := issues.Edit(ctx, owner, repo, issue.GetNumber(), update); err != nil {
 			return err
 		}
@@ -34,6 +34,9 @@ var edit func(context.Context, string, string, int, *github.IssueRequest) (
 var createComment func(context.Context, string, string, int,
 	*github.IssueComment) (*github.IssueComment, *github.Response, error)
 
+var listComments func(context.Context, string, string, int,
+	*github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error)
+
 type mockIssues struct{}
 
 func (m mockIssues) ListByRepo(ctx context.Context, owner string, repo string,
@@ -56,10 +59,40 @@ func (m mockIssues) CreateComment(ctx context.Context, owner string, repo string
 	return createComment(ctx, owner, repo, number, comment)
 }
 
+func (m mockIssues) ListComments(ctx context.Context, owner string, repo string,
+	number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	if listComments == nil {
+		return nil, &github.Response{}, nil
+	}
+	return listComments(ctx, owner, repo, number, opts)
+}
+
+var listIssueEvents func(context.Context, string, string, int,
+	*github.ListOptions) ([]*github.IssueEvent, *github.Response, error)
+
+func (m mockIssues) ListIssueEvents(ctx context.Context, owner string, repo string,
+	number int, opts *github.ListOptions) ([]*github.IssueEvent, *github.Response, error) {
+	if listIssueEvents == nil {
+		return nil, &github.Response{}, nil
+	}
+	return listIssueEvents(ctx, owner, repo, number, opts)
+}
+
+var getPermissionLevel func(context.Context, string, string, string) (
+	*github.RepositoryPermissionLevel, *github.Response, error)
+
+type mockPerms struct{}
+
+func (m mockPerms) GetPermissionLevel(ctx context.Context, owner, repo, user string) (
+	*github.RepositoryPermissionLevel, *github.Response, error) {
+	return getPermissionLevel(ctx, owner, repo, user)
+}
+
 func TestEnsure(t *testing.T) {
 	issueTitle := fmt.Sprintf(title, "thispolicy")
 	closed := "closed"
 	open := "open"
+	markedBody := "Status\n\n" + fmt.Sprintf(markerFormat, "thispolicy")
 	t.Run("NoIssue", func(t *testing.T) {
 		listByRepo = func(ctx context.Context, owner string, repo string,
 			opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
@@ -79,7 +112,7 @@ func TestEnsure(t *testing.T) {
 		}
 		edit = nil
 		createComment = nil
-		err := ensure(context.Background(), mockIssues{}, "", "", "thispolicy", "Status text")
+		err := ensure(context.Background(), mockIssues{}, mockPerms{}, markerFinder{mockIssues{}}, "", "", "thispolicy", "Status text")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -104,6 +137,9 @@ func TestEnsure(t *testing.T) {
 			if issue.GetState() != "open" {
 				t.Errorf("Unexpected state: %v", issue.GetState())
 			}
+			if !strings.Contains(issue.GetBody(), fmt.Sprintf(markerFormat, "thispolicy")) {
+				t.Errorf("Expected marker to be added: %v", issue.GetBody())
+			}
 			editCalled = true
 			return nil, nil, nil
 		}
@@ -116,7 +152,7 @@ func TestEnsure(t *testing.T) {
 			commentCalled = true
 			return nil, nil, nil
 		}
-		err := ensure(context.Background(), mockIssues{}, "", "", "thispolicy", "Status text")
+		err := ensure(context.Background(), mockIssues{}, mockPerms{}, markerFinder{mockIssues{}}, "", "", "thispolicy", "Status text")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -134,6 +170,7 @@ func TestEnsure(t *testing.T) {
 			return []*github.Issue{
 				&github.Issue{
 					Title:     &issueTitle,
+					Body:      &markedBody,
 					State:     &open,
 					UpdatedAt: &now,
 				},
@@ -143,10 +180,45 @@ func TestEnsure(t *testing.T) {
 		create = nil
 		edit = nil
 		createComment = nil
-		err := ensure(context.Background(), mockIssues{}, "", "", "thispolicy", "Status text")
+		err := ensure(context.Background(), mockIssues{}, mockPerms{}, markerFinder{mockIssues{}}, "", "", "thispolicy", "Status text")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+	t.Run("OpenIssueByTitle", func(t *testing.T) {
+		now := time.Now()
+		listByRepo = func(ctx context.Context, owner string, repo string,
+			opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+			return []*github.Issue{
+				&github.Issue{
+					Title:     &issueTitle,
+					State:     &open,
+					UpdatedAt: &now,
+				},
+			}, &github.Response{NextPage: 0}, nil
+		}
+		editCalled := false
+		edit = func(ctx context.Context, owner string, repo string, number int,
+			issue *github.IssueRequest) (*github.Issue, *github.Response, error) {
+			if issue.State != nil {
+				t.Errorf("Unexpected state change: %v", issue.GetState())
+			}
+			if !strings.Contains(issue.GetBody(), fmt.Sprintf(markerFormat, "thispolicy")) {
+				t.Errorf("Expected marker to be added: %v", issue.GetBody())
+			}
+			editCalled = true
+			return nil, nil, nil
+		}
+		// Expect to not call nil functions
+		create = nil
+		createComment = nil
+		err := ensure(context.Background(), mockIssues{}, mockPerms{}, markerFinder{mockIssues{}}, "", "", "thispolicy", "Status text")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
+		if !editCalled {
+			t.Error("Expected marker to be backfilled")
+		}
 	})
 	t.Run("OpenStaleIssue", func(t *testing.T) {
 		stale := time.Now().Add(-10 * operator.NoticePingDuration)
@@ -155,6 +227,7 @@ func TestEnsure(t *testing.T) {
 			return []*github.Issue{
 				&github.Issue{
 					Title:     &issueTitle,
+					Body:      &markedBody,
 					State:     &open,
 					UpdatedAt: &stale,
 				},
@@ -172,7 +245,7 @@ func TestEnsure(t *testing.T) {
 		// Expect to not call nil functions
 		create = nil
 		edit = nil
-		err := ensure(context.Background(), mockIssues{}, "", "", "thispolicy", "Status text")
+		err := ensure(context.Background(), mockIssues{}, mockPerms{}, markerFinder{mockIssues{}}, "", "", "thispolicy", "Status text")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -180,6 +253,29 @@ func TestEnsure(t *testing.T) {
 			t.Error("Expected comment to be left")
 		}
 	})
+	t.Run("OpenIssueWithinPingInterval", func(t *testing.T) {
+		stale := time.Now().Add(-3 * operator.NoticePingDuration)
+		listByRepo = func(ctx context.Context, owner string, repo string,
+			opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+			return []*github.Issue{
+				&github.Issue{
+					Title:     &issueTitle,
+					Body:      &markedBody,
+					State:     &open,
+					UpdatedAt: &stale,
+				},
+			}, &github.Response{NextPage: 0}, nil
+		}
+		// Expect to not call nil functions
+		create = nil
+		edit = nil
+		createComment = nil
+		ctx := WithPingInterval(context.Background(), 7*24*time.Hour)
+		err := ensure(ctx, mockIssues{}, mockPerms{}, markerFinder{mockIssues{}}, "", "", "thispolicy", "Status text")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
 }
 
 func TestClose(t *testing.T) {
@@ -192,7 +288,7 @@ func TestClose(t *testing.T) {
 		// Expect to not call nil functions
 		createComment = nil
 		edit = nil
-		err := closeIssue(context.Background(), mockIssues{}, "", "", "thispolicy")
+		err := closeIssue(context.Background(), mockIssues{}, markerFinder{mockIssues{}}, "", "", "thispolicy")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -209,7 +305,7 @@ func TestClose(t *testing.T) {
 		// Expect to not call nil functions
 		createComment = nil
 		edit = nil
-		err := closeIssue(context.Background(), mockIssues{}, "", "", "thispolicy")
+		err := closeIssue(context.Background(), mockIssues{}, markerFinder{mockIssues{}}, "", "", "thispolicy")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -243,7 +339,7 @@ func TestClose(t *testing.T) {
 			editCalled = true
 			return nil, nil, nil
 		}
-		err := closeIssue(context.Background(), mockIssues{}, "", "", "thispolicy")
+		err := closeIssue(context.Background(), mockIssues{}, markerFinder{mockIssues{}}, "", "", "thispolicy")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -256,3 +352,49 @@ func TestClose(t *testing.T) {
 	})
 
 }
+
+func TestPending(t *testing.T) {
+	issueTitle := fmt.Sprintf(title, "thispolicy")
+	open := "open"
+	closed := "closed"
+	now := time.Now()
+	stale := now.Add(-10 * operator.NoticePingDuration)
+	tests := []struct {
+		Name     string
+		Existing []*github.Issue
+		Expect   bool
+	}{
+		{Name: "NoIssue", Expect: true},
+		{Name: "Closed", Existing: []*github.Issue{{Title: &issueTitle, State: &closed, UpdatedAt: &now}}, Expect: true},
+		{Name: "OpenFresh", Existing: []*github.Issue{{Title: &issueTitle, State: &open, UpdatedAt: &now}}, Expect: false},
+		{Name: "OpenStale", Existing: []*github.Issue{{Title: &issueTitle, State: &open, UpdatedAt: &stale}}, Expect: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			listByRepo = func(ctx context.Context, owner string, repo string,
+				opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+				return test.Existing, &github.Response{NextPage: 0}, nil
+			}
+			got, err := pending(context.Background(), markerFinder{mockIssues{}}, "", "", "thispolicy")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != test.Expect {
+				t.Errorf("Unexpected pending. Expected: %v Got: %v", test.Expect, got)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	p := Render("SECURITY.md", "Security policy not enabled.")
+	if p.Title != "Security Policy violation SECURITY.md" {
+		t.Errorf("Unexpected title: %q", p.Title)
+	}
+	if !strings.Contains(p.Body, "Security policy not enabled.") || !strings.Contains(p.Body, "<!-- allstar-policy: SECURITY.md -->") {
+		t.Errorf("Unexpected body: %q", p.Body)
+	}
+	if len(p.Labels) != 1 || p.Labels[0] != operator.GitHubIssueLabel {
+		t.Errorf("Unexpected labels: %v", p.Labels)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config/operator"
+)
+
+// maxRateLimitRetries is the number of times a single page is retried after
+// waiting out a rate limit error.
+const maxRateLimitRetries = 3
+
+var sleep func(context.Context, time.Duration) error
+
+func init() {
+	sleep = func(ctx context.Context, d time.Duration) error {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			return nil
+		}
+	}
+}
+
+// waitRateLimit waits out err if it is a GitHub rate limit error, so the
+// request can be retried. Other errors, and limits that reset after
+// operator.RateLimitMaxWait, are returned.
+func waitRateLimit(ctx context.Context, err error) error {
+	var rle *github.RateLimitError
+	if errors.As(err, &rle) {
+		return waitUntil(ctx, rle.Rate.Reset.Time, err)
+	}
+	var are *github.AbuseRateLimitError
+	if errors.As(err, &are) && are.RetryAfter != nil {
+		return waitUntil(ctx, timeNow().Add(*are.RetryAfter), err)
+	}
+	return err
+}
+
+// waitRemaining waits for the rate limit to reset if fewer than
+// operator.RateLimitMinRemaining requests remain, so that paging doesn't use
+// up the limit the next request needs.
+func waitRemaining(ctx context.Context, r github.Rate) error {
+	if r.Limit == 0 || r.Remaining >= operator.RateLimitMinRemaining {
+		return nil
+	}
+	return waitUntil(ctx, r.Reset.Time, fmt.Errorf("rate limit low: %v of %v remaining", r.Remaining, r.Limit))
+}
+
+func waitUntil(ctx context.Context, t time.Time, cause error) error {
+	d := t.Sub(timeNow())
+	if d > operator.RateLimitMaxWait {
+		return cause
+	}
+	if d <= 0 {
+		return nil
+	}
+	return sleep(ctx, d)
+}
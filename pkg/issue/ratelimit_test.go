@@ -0,0 +1,89 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestListAllstarIssuesRateLimit(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	var slept []time.Duration
+	sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	calls := 0
+	listByRepo = func(ctx context.Context, owner string, repo string,
+		opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+		calls++
+		switch calls {
+		case 1:
+			return nil, nil, &github.RateLimitError{
+				Rate: github.Rate{Reset: github.Timestamp{Time: now.Add(time.Minute)}},
+			}
+		case 2:
+			resp := &github.Response{
+				NextPage: 2,
+				Rate: github.Rate{
+					Limit:     5000,
+					Remaining: 1,
+					Reset:     github.Timestamp{Time: now.Add(2 * time.Minute)},
+				},
+			}
+			return []*github.Issue{
+				{Number: github.Int(1)},
+				{Number: github.Int(2), PullRequestLinks: &github.PullRequestLinks{}},
+			}, resp, nil
+		default:
+			if opts.Page != 2 {
+				t.Errorf("Expected second page, got: %v", opts.Page)
+			}
+			return []*github.Issue{{Number: github.Int(3)}}, &github.Response{}, nil
+		}
+	}
+	is, err := listAllstarIssues(context.Background(), mockIssues{}, "", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(is) != 2 || is[0].GetNumber() != 1 || is[1].GetNumber() != 3 {
+		t.Errorf("Unexpected issues: %v", is)
+	}
+	if len(slept) != 2 || slept[0] != time.Minute || slept[1] != 2*time.Minute {
+		t.Errorf("Unexpected waits: %v", slept)
+	}
+}
+
+func TestListAllstarIssuesRateLimitTooLong(t *testing.T) {
+	sleep = func(ctx context.Context, d time.Duration) error {
+		t.Error("Unexpected wait")
+		return nil
+	}
+	listByRepo = func(ctx context.Context, owner string, repo string,
+		opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+		return nil, nil, &github.RateLimitError{
+			Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(time.Hour)}},
+		}
+	}
+	if _, err := listAllstarIssues(context.Background(), mockIssues{}, "", ""); err == nil {
+		t.Error("Expected error")
+	}
+}
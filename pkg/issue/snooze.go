@@ -0,0 +1,132 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// maxSnoozeDays is the longest a snooze command may suppress an issue.
+const maxSnoozeDays = 90
+
+// snoozeMarker identifies Allstar's acknowledgement of a snooze command.
+const snoozeMarker = "<!-- allstar-snooze -->"
+
+// snoozeRegex matches the snooze comment command, ex: "/allstar snooze 14d".
+var snoozeRegex = regexp.MustCompile(`(?im)^\s*/allstar\s+snooze\s+(\d+)d\b`)
+
+type repoPermissions interface {
+	GetPermissionLevel(context.Context, string, string, string) (
+		*github.RepositoryPermissionLevel, *github.Response, error)
+}
+
+var timeNow func() time.Time
+
+func init() {
+	timeNow = time.Now
+}
+
+// snooze is an accepted snooze command.
+type snooze struct {
+	until time.Time
+	by    string
+	acked bool
+}
+
+// findSnooze returns the most recent snooze command on the issue from a repo
+// admin, or nil if there is none. Commands from other users are ignored.
+func findSnooze(ctx context.Context, issues issues, perms repoPermissions, owner, repo string, number int) (*snooze, error) {
+	opt := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	var all []*github.IssueComment
+	for {
+		cs, resp, err := issues.ListComments(ctx, owner, repo, number, opt)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, cs...)
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	// Newest first, an acknowledgement seen before a command means that command
+	// was already acknowledged.
+	acked := false
+	for i := len(all) - 1; i >= 0; i-- {
+		c := all[i]
+		if strings.Contains(c.GetBody(), snoozeMarker) {
+			acked = true
+			continue
+		}
+		m := snoozeRegex.FindStringSubmatch(c.GetBody())
+		if m == nil {
+			continue
+		}
+		login := c.GetUser().GetLogin()
+		p, _, err := perms.GetPermissionLevel(ctx, owner, repo, login)
+		if err != nil {
+			return nil, err
+		}
+		if p.GetPermission() != "admin" {
+			continue
+		}
+		days, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		if days > maxSnoozeDays {
+			days = maxSnoozeDays
+		}
+		return &snooze{
+			until: c.GetCreatedAt().AddDate(0, 0, days),
+			by:    login,
+			acked: acked,
+		}, nil
+	}
+	return nil, nil
+}
+
+// checkSnooze returns true if the issue is snoozed, acknowledging a new snooze
+// command with a comment recording when it ends.
+func checkSnooze(ctx context.Context, issues issues, perms repoPermissions, owner, repo string, number int) (bool, error) {
+	s, err := findSnooze(ctx, issues, perms, owner, repo, number)
+	if err != nil {
+		return false, err
+	}
+	if s == nil || !timeNow().Before(s.until) {
+		return false, nil
+	}
+	if !s.acked {
+		body := fmt.Sprintf("Snoozed by @%v until %v. Allstar will not reopen or update this issue until then.\n\n%v",
+			s.by, s.until.Format("2006-01-02"), snoozeMarker)
+		if _, _, err := issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{
+			Body: &body,
+		}); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
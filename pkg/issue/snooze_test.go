@@ -0,0 +1,119 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestEnsureSnooze(t *testing.T) {
+	now := time.Date(2021, 9, 10, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	getPermissionLevel = func(ctx context.Context, owner, repo, user string) (
+		*github.RepositoryPermissionLevel, *github.Response, error) {
+		p := "write"
+		if user == "admin" {
+			p = "admin"
+		}
+		return &github.RepositoryPermissionLevel{Permission: &p}, nil, nil
+	}
+	defer func() { listComments = nil }()
+	comment := func(user, body string, at time.Time) *github.IssueComment {
+		return &github.IssueComment{
+			User:      &github.User{Login: &user},
+			Body:      &body,
+			CreatedAt: &at,
+		}
+	}
+	tests := []struct {
+		Name     string
+		Comments []*github.IssueComment
+		Reopen   bool
+		Ack      bool
+	}{
+		{
+			Name:     "AdminSnooze",
+			Comments: []*github.IssueComment{comment("admin", "/allstar snooze 14d", now.AddDate(0, 0, -1))},
+			Ack:      true,
+		},
+		{
+			Name: "AlreadyAcked",
+			Comments: []*github.IssueComment{
+				comment("admin", "/allstar snooze 14d", now.AddDate(0, 0, -1)),
+				comment("allstar", "Snoozed\n"+snoozeMarker, now.AddDate(0, 0, -1)),
+			},
+		},
+		{
+			Name:     "Expired",
+			Comments: []*github.IssueComment{comment("admin", "/allstar snooze 3d", now.AddDate(0, 0, -4))},
+			Reopen:   true,
+		},
+		{
+			Name:     "NonAdmin",
+			Comments: []*github.IssueComment{comment("dev", "/allstar snooze 14d", now.AddDate(0, 0, -1))},
+			Reopen:   true,
+		},
+		{
+			Name:     "Capped",
+			Comments: []*github.IssueComment{comment("admin", "/allstar snooze 365d", now.AddDate(0, 0, -100))},
+			Reopen:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			closed := "closed"
+			listByRepo = func(ctx context.Context, owner string, repo string,
+				opts *github.IssueListByRepoOptions) ([]*github.Issue, *github.Response, error) {
+				return []*github.Issue{{Number: github.Int(1), State: &closed,
+					Body: github.String("<!-- allstar-policy: thispolicy -->")}}, &github.Response{}, nil
+			}
+			listComments = func(ctx context.Context, owner, repo string, number int,
+				opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+				return test.Comments, &github.Response{}, nil
+			}
+			reopened := false
+			edit = func(ctx context.Context, owner string, repo string, number int,
+				issue *github.IssueRequest) (*github.Issue, *github.Response, error) {
+				reopened = true
+				return nil, nil, nil
+			}
+			acked := false
+			createComment = func(ctx context.Context, owner string, repo string, number int,
+				c *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+				if strings.Contains(c.GetBody(), snoozeMarker) {
+					acked = true
+				}
+				return nil, nil, nil
+			}
+			err := ensure(context.Background(), mockIssues{}, mockPerms{}, markerFinder{mockIssues{}},
+				"", "", "thispolicy", "Status text")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if reopened != test.Reopen {
+				t.Errorf("Unexpected reopen. Expected: %v Got: %v", test.Reopen, reopened)
+			}
+			if acked != test.Ack {
+				t.Errorf("Unexpected acknowledgement. Expected: %v Got: %v", test.Ack, acked)
+			}
+		})
+	}
+}
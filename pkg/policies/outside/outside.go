@@ -215,10 +215,10 @@ func getConfig(ctx context.Context, c *github.Client, owner, repo string) (*OrgC
 		Action:      "log",
 		PushAllowed: true,
 	}
-	if err := configFetchConfig(ctx, c, owner, operator.OrgConfigRepo, configFile, oc); err != nil {
+	if err := configFetchConfig(ctx, c, owner, config.ConfigRepo(ctx), configFile, oc); err != nil {
 		log.Error().
 			Str("org", owner).
-			Str("repo", operator.OrgConfigRepo).
+			Str("repo", config.ConfigRepo(ctx)).
 			Str("area", polName).
 			Str("file", configFile).
 			Err(err).
@@ -0,0 +1,55 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// severityHigh is the details Severity of a missing security policy on a repo
+// with open vulnerability alerts.
+const severityHigh = "high"
+
+const advisoriesText = `**This repository has %v open security vulnerability alert(s), but no security policy.** Reporters of these and future vulnerabilities have no documented private channel, so please add a security policy as soon as possible.
+
+`
+
+// openAdvisories returns the number of open vulnerability alerts on the repo,
+// from the vulnerabilityAlerts GraphQL connection.
+func openAdvisories(ctx context.Context, v4c v4client, owner, repo string) (int, error) {
+	var q struct {
+		Repository struct {
+			VulnerabilityAlerts struct {
+				TotalCount int
+			} `graphql:"vulnerabilityAlerts(states: OPEN)"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	variables := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+	}
+	if err := v4c.Query(ctx, &q, variables); err != nil {
+		return 0, err
+	}
+	return q.Repository.VulnerabilityAlerts.TotalCount, nil
+}
+
+// advisoriesNotifyText prepends an urgent notice about n open alerts to text.
+func advisoriesNotifyText(n int, text string) string {
+	return fmt.Sprintf(advisoriesText, n) + text
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ossf/allstar/pkg/config"
+)
+
+func TestCheckEscalateOnAdvisories(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Alerts   int
+		AlertErr error
+		Reasons  []string
+		Urgent   bool
+	}{
+		{
+			Name:    "Alerts",
+			Alerts:  3,
+			Reasons: []string{ReasonMissing, ReasonMissingWithAdvisories},
+			Urgent:  true,
+		},
+		{
+			Name:    "NoAlerts",
+			Alerts:  0,
+			Reasons: []string{ReasonMissing},
+		},
+		{
+			Name:     "AlertsUnavailable",
+			AlertErr: errors.New("resource not accessible by integration"),
+			Reasons:  []string{ReasonMissing},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				switch qc := q.(type) {
+				case *struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				}:
+					qc.Repository.DefaultBranchRef.Name = "main"
+				case *struct {
+					Repository struct {
+						VulnerabilityAlerts struct {
+							TotalCount int
+						} `graphql:"vulnerabilityAlerts(states: OPEN)"`
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				}:
+					if test.AlertErr != nil {
+						return test.AlertErr
+					}
+					qc.Repository.VulnerabilityAlerts.TotalCount = test.Alerts
+				default:
+					t.Fatalf("Query() called with unexpected query structure.")
+				}
+				return nil
+			}
+			oc := &OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+				},
+				EscalateOnAdvisories: true,
+			}
+			getContents = contentsMock(nil)
+			res, err := checkConfig(context.Background(), mockRepos{}, nil, mockClient{},
+				"", "thisrepo", oc, &RepoConfig{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Pass {
+				t.Errorf("Expected fail")
+			}
+			if diff := cmp.Diff(test.Reasons, res.Reasons); diff != "" {
+				t.Errorf("Unexpected reasons. (-want +got):\n%s", diff)
+			}
+			urgent := strings.Contains(res.NotifyText, "open security vulnerability alert")
+			if urgent != test.Urgent {
+				t.Errorf("Unexpected urgency. Expected: %v Got: %v", test.Urgent, urgent)
+			}
+			if d := res.Details.(SecurityDetails); test.Urgent && (d.Severity != severityHigh || d.OpenAdvisories != test.Alerts) {
+				t.Errorf("Unexpected details: %+v", d)
+			}
+		})
+	}
+}
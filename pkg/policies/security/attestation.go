@@ -0,0 +1,116 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+const attestationFile = "security-attestation.yaml"
+
+// maxAttestationDuration is the longest time an attestation may be valid for,
+// to force periodic re-attestation.
+const maxAttestationDuration = 366 * 24 * time.Hour
+
+// Attestation is the definition of the repo-level attestation file,
+// .allstar/security-attestation.yaml. It is an assertion by a maintainer that
+// the repo complies with the policy in a way the automated check can not
+// verify. It is only honored when AllowAttestation is set in the org-level
+// config.
+type Attestation struct {
+	// AttestedBy is the GitHub login of the maintainer making the attestation,
+	// required.
+	AttestedBy string `yaml:"attestedBy"`
+
+	// Reason explains how the repo complies with the policy, required.
+	Reason string `yaml:"reason"`
+
+	// Expires is the date (YYYY-MM-DD) after which the attestation is no longer
+	// valid, required. Must be no more than a year in the future.
+	Expires string `yaml:"expires"`
+}
+
+// Validate returns an error if the attestation is missing required fields or
+// is expired.
+func (a *Attestation) Validate() error {
+	if a.AttestedBy == "" {
+		return errors.New("attestation missing attestedBy")
+	}
+	if a.Reason == "" {
+		return errors.New("attestation missing reason")
+	}
+	if a.Expires == "" {
+		return errors.New("attestation missing expires")
+	}
+	e, err := time.Parse("2006-01-02", a.Expires)
+	if err != nil {
+		return fmt.Errorf("attestation has invalid expires date: %w", err)
+	}
+	now := timeNow()
+	if !now.Before(e.AddDate(0, 0, 1)) {
+		return fmt.Errorf("attestation expired on %v", a.Expires)
+	}
+	if e.Sub(now) > maxAttestationDuration {
+		return fmt.Errorf("attestation expires %v, more than a year in the future", a.Expires)
+	}
+	return nil
+}
+
+// getAttestation returns the repo's attestation if present and valid, or nil
+// otherwise.
+func getAttestation(ctx context.Context, c *github.Client, owner, repo string) *Attestation {
+	p := path.Join(operator.RepoConfigDir, attestationFile)
+	a := &Attestation{}
+	if err := configFetchConfig(ctx, c, owner, repo, p, a); err != nil {
+		runid.Logger(ctx).Error().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("file", p).
+			Err(err).
+			Msg("Unexpected error reading attestation.")
+		return nil
+	}
+	if *a == (Attestation{}) {
+		return nil
+	}
+	if err := a.Validate(); err != nil {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("file", p).
+			Err(err).
+			Msg("Ignoring invalid attestation.")
+		return nil
+	}
+	runid.Logger(ctx).Info().
+		Str("org", owner).
+		Str("repo", repo).
+		Str("area", polName).
+		Str("attestedBy", a.AttestedBy).
+		Str("expires", a.Expires).
+		Msg("Repo compliance attested.")
+	return a
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttestationValidate(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2021, 9, 10, 12, 0, 0, 0, time.UTC)
+	}
+	defer func() { timeNow = time.Now }()
+	tests := []struct {
+		Name  string
+		A     Attestation
+		Valid bool
+	}{
+		{
+			Name:  "Valid",
+			A:     Attestation{AttestedBy: "a", Reason: "r", Expires: "2021-12-01"},
+			Valid: true,
+		},
+		{
+			Name:  "ExpiresToday",
+			A:     Attestation{AttestedBy: "a", Reason: "r", Expires: "2021-09-10"},
+			Valid: true,
+		},
+		{
+			Name: "Expired",
+			A:    Attestation{AttestedBy: "a", Reason: "r", Expires: "2021-09-09"},
+		},
+		{
+			Name: "TooLong",
+			A:    Attestation{AttestedBy: "a", Reason: "r", Expires: "2023-01-01"},
+		},
+		{
+			Name: "NoExpiry",
+			A:    Attestation{AttestedBy: "a", Reason: "r"},
+		},
+		{
+			Name: "NoReason",
+			A:    Attestation{AttestedBy: "a", Expires: "2021-12-01"},
+		},
+		{
+			Name: "NoAttester",
+			A:    Attestation{Reason: "r", Expires: "2021-12-01"},
+		},
+		{
+			Name: "BadDate",
+			A:    Attestation{AttestedBy: "a", Reason: "r", Expires: "soon"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := test.A.Validate()
+			if test.Valid && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !test.Valid && err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
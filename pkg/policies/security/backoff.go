@@ -0,0 +1,175 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/runid"
+)
+
+var backoffThreshold = operator.CheckErrorBackoffThreshold
+var backoffBase = operator.CheckErrorBackoffBase
+var backoffMax = operator.CheckErrorBackoffMax
+
+// Backoff is the error backoff state of a repo.
+type Backoff struct {
+	// Errors is the number of consecutive check errors.
+	Errors int
+
+	// Until is when the repo is next checked, if in backoff.
+	Until time.Time
+}
+
+// BackoffStore persists per-repo error backoff state between runs.
+type BackoffStore interface {
+	// Get returns the backoff state of the repo, or nil if there is none.
+	Get(ctx context.Context, owner, repo string) (*Backoff, error)
+
+	// Put stores the backoff state of the repo.
+	Put(ctx context.Context, owner, repo string, b *Backoff) error
+
+	// Delete clears the backoff state of the repo.
+	Delete(ctx context.Context, owner, repo string) error
+}
+
+// memoryBackoffStore is the default BackoffStore, state is lost on restart.
+type memoryBackoffStore struct {
+	mu sync.Mutex
+	m  map[string]*Backoff
+}
+
+func backoffKey(owner, repo string) string {
+	return strings.ToLower(owner) + "/" + strings.ToLower(repo)
+}
+
+func (s *memoryBackoffStore) Get(ctx context.Context, owner, repo string) (*Backoff, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.m[backoffKey(owner, repo)]
+	if !ok {
+		return nil, nil
+	}
+	bc := *b
+	return &bc, nil
+}
+
+func (s *memoryBackoffStore) Put(ctx context.Context, owner, repo string, b *Backoff) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bc := *b
+	s.m[backoffKey(owner, repo)] = &bc
+	return nil
+}
+
+func (s *memoryBackoffStore) Delete(ctx context.Context, owner, repo string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, backoffKey(owner, repo))
+	return nil
+}
+
+var backoffs BackoffStore = &memoryBackoffStore{m: make(map[string]*Backoff)}
+
+// SetBackoffStore replaces where error backoff state is persisted, such as with
+// a shared cache so that it is shared between instances. It is intended to be
+// called at startup.
+func SetBackoffStore(s BackoffStore) {
+	backoffs = s
+}
+
+// backoffInterval returns how long to skip a repo after errors consecutive
+// errors.
+func backoffInterval(errors int) time.Duration {
+	d := backoffBase
+	for i := backoffThreshold; i < errors && d < backoffMax; i++ {
+		d *= 2
+	}
+	if d > backoffMax {
+		d = backoffMax
+	}
+	return d
+}
+
+// backoffResult returns a skipped result if the repo is in error backoff, or nil
+// if it should be checked. A forced recheck ignores the backoff.
+func backoffResult(ctx context.Context, owner, repo string) *policydef.Result {
+	if backoffThreshold <= 0 || policydef.IsForceRecheck(ctx) {
+		return nil
+	}
+	b, err := backoffs.Get(ctx, owner, repo)
+	if err != nil || b == nil || !timeNow().Before(b.Until) {
+		return nil
+	}
+	return &policydef.Result{
+		Enabled:    false,
+		Pass:       true,
+		NotifyText: "",
+		Details: SecurityDetails{
+			Skipped: "error backoff",
+			Source:  SourceSkipped,
+		},
+	}
+}
+
+// recordCheckError updates the backoff state of the repo with the result of a
+// check. A success clears it. Once the threshold is reached, the repo is put in
+// backoff, with a warning logged only when the backoff starts.
+func recordCheckError(ctx context.Context, owner, repo string, checkErr error) {
+	if backoffThreshold <= 0 {
+		return
+	}
+	var err error
+	if checkErr == nil {
+		err = backoffs.Delete(ctx, owner, repo)
+	} else {
+		var b *Backoff
+		b, err = backoffs.Get(ctx, owner, repo)
+		if err == nil {
+			if b == nil {
+				b = &Backoff{}
+			}
+			b.Errors++
+			if b.Errors >= backoffThreshold {
+				d := backoffInterval(b.Errors)
+				b.Until = timeNow().Add(d)
+				if b.Errors == backoffThreshold {
+					runid.Logger(ctx).Warn().
+						Str("org", owner).
+						Str("repo", repo).
+						Str("area", polName).
+						Int("errors", b.Errors).
+						Dur("backoff", d).
+						Err(checkErr).
+						Msg("Repo check repeatedly errored, backing off.")
+				}
+			}
+			err = backoffs.Put(ctx, owner, repo, b)
+		}
+	}
+	if err != nil {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Err(err).
+			Msg("Unable to update error backoff state.")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func TestBackoff(t *testing.T) {
+	now := time.Date(2021, 9, 10, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	backoffThreshold = 2
+	backoffBase = time.Hour
+	backoffMax = 3 * time.Hour
+	defer func() {
+		backoffThreshold = operator.CheckErrorBackoffThreshold
+		backoffBase = operator.CheckErrorBackoffBase
+		backoffMax = operator.CheckErrorBackoffMax
+	}()
+	ctx := context.Background()
+	owner, repo := "org", "backoffrepo"
+	checkErr := errors.New("boom")
+
+	recordCheckError(ctx, owner, repo, checkErr)
+	if r := backoffResult(ctx, owner, repo); r != nil {
+		t.Fatalf("Expected no backoff below threshold")
+	}
+	recordCheckError(ctx, owner, repo, checkErr)
+	r := backoffResult(ctx, owner, repo)
+	if r == nil {
+		t.Fatalf("Expected backoff at threshold")
+	}
+	if r.Enabled || !r.Pass || r.Details.(SecurityDetails).Skipped != "error backoff" {
+		t.Errorf("Unexpected backoff result: %+v", r)
+	}
+	if r := backoffResult(policydef.WithForceRecheck(ctx), owner, repo); r != nil {
+		t.Errorf("Expected forced recheck to ignore backoff")
+	}
+	now = now.Add(time.Hour)
+	if r := backoffResult(ctx, owner, repo); r != nil {
+		t.Fatalf("Expected backoff to expire")
+	}
+	recordCheckError(ctx, owner, repo, checkErr)
+	b, _ := backoffs.Get(ctx, owner, repo)
+	if b.Errors != 3 || !b.Until.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("Expected doubled backoff, got: %+v", b)
+	}
+	if d := backoffInterval(10); d != backoffMax {
+		t.Errorf("Expected backoff capped at %v, got %v", backoffMax, d)
+	}
+	recordCheckError(ctx, owner, repo, nil)
+	if b, _ := backoffs.Get(ctx, owner, repo); b != nil {
+		t.Errorf("Expected success to clear backoff, got: %+v", b)
+	}
+}
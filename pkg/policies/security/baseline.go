@@ -0,0 +1,111 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultMinLengthFraction is the fraction of its baseline length the security
+// policy may shrink to before it is considered weakened.
+const defaultMinLengthFraction = 0.5
+
+// Baseline is the recorded state of a repo's security policy, used to detect
+// when it has been weakened.
+type Baseline struct {
+	// Length is the security policy length in bytes.
+	Length int
+
+	// Channels are the contact channel types found in the security policy.
+	Channels []string
+}
+
+// BaselineStore persists per-repo baselines between runs.
+type BaselineStore interface {
+	// Get returns the baseline of the repo, or nil if there is none.
+	Get(ctx context.Context, owner, repo string) (*Baseline, error)
+
+	// Put stores the baseline of the repo.
+	Put(ctx context.Context, owner, repo string, b *Baseline) error
+}
+
+// memoryBaselineStore is the default BaselineStore, baselines are lost on
+// restart.
+type memoryBaselineStore struct {
+	mu sync.Mutex
+	m  map[string]*Baseline
+}
+
+func (s *memoryBaselineStore) Get(ctx context.Context, owner, repo string) (*Baseline, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.m[owner+"/"+repo], nil
+}
+
+func (s *memoryBaselineStore) Put(ctx context.Context, owner, repo string, b *Baseline) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[owner+"/"+repo] = b
+	return nil
+}
+
+var baselines BaselineStore = &memoryBaselineStore{m: make(map[string]*Baseline)}
+
+// SetBaselineStore replaces where baselines are persisted, such as with a
+// shared database so baselines survive restarts and are shared between
+// instances. It is intended to be called at startup.
+func SetBaselineStore(s BaselineStore) {
+	baselines = s
+}
+
+const regressionText = `This repository requires that the security policy is not weakened over time. Compared to a previous version, the security policy has lost information that reporters rely on.
+
+To fix this, restore the removed contact channels or content to the security policy.`
+
+// checkRegression compares the security policy content to the repo's stored
+// baseline, and returns a description of what was lost, or empty if nothing
+// was. The baseline is updated when there is no regression, so a weakened
+// policy keeps failing until it is restored.
+func checkRegression(ctx context.Context, owner, repo, content string, fraction float64) (string, error) {
+	cur := &Baseline{
+		Length:   len(content),
+		Channels: findContactChannels(content),
+	}
+	if fraction <= 0 {
+		fraction = defaultMinLengthFraction
+	}
+	old, err := baselines.Get(ctx, owner, repo)
+	if err != nil {
+		return "", err
+	}
+	var lost []string
+	if old != nil {
+		for _, ch := range old.Channels {
+			if !contains(cur.Channels, ch) {
+				lost = append(lost, fmt.Sprintf("%v contact channel removed", ch))
+			}
+		}
+		if float64(cur.Length) < fraction*float64(old.Length) {
+			lost = append(lost, fmt.Sprintf("length dropped from %v to %v bytes", old.Length, cur.Length))
+		}
+	}
+	if len(lost) > 0 {
+		return strings.Join(lost, ", "), nil
+	}
+	return "", baselines.Put(ctx, owner, repo, cur)
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCheckRegression(t *testing.T) {
+	defer SetBaselineStore(&memoryBaselineStore{m: make(map[string]*Baseline)})
+	SetBaselineStore(&memoryBaselineStore{m: make(map[string]*Baseline)})
+	full := "Email security@example.com or use https://github.com/org/repo/security/advisories/new.\n" +
+		strings.Repeat("Details about our process. ", 10)
+	steps := []struct {
+		Name    string
+		Content string
+		Lost    string
+	}{
+		{
+			Name:    "Baseline",
+			Content: full,
+		},
+		{
+			Name:    "ChannelRemoved",
+			Content: strings.Replace(full, "security@example.com", "the maintainers", 1),
+			Lost:    "email contact channel removed",
+		},
+		{
+			Name:    "Shrank",
+			Content: "Email security@example.com or use https://github.com/org/repo/security/advisories/new.\n",
+			Lost:    "length dropped from 357 to 87 bytes",
+		},
+		{
+			Name:    "Restored",
+			Content: full + "More details.",
+		},
+	}
+	for _, step := range steps {
+		lost, err := checkRegression(context.Background(), "org", "repo", step.Content, 0)
+		if err != nil {
+			t.Fatalf("%v: Unexpected error: %v", step.Name, err)
+		}
+		if lost != step.Lost {
+			t.Errorf("%v: Unexpected results. Expected: %q Got: %q", step.Name, step.Lost, lost)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// FixResult is the outcome of FixAll for a single repo.
+type FixResult struct {
+	// Fixed is true if the repo was failing and Fix succeeded.
+	Fixed bool
+
+	// Err is the error from checking or fixing the repo, if any.
+	Err error
+}
+
+var securityCheck func(context.Context, *github.Client, string, string) (*policydef.Result, error)
+var securityFix func(context.Context, *github.Client, string, string) error
+
+func init() {
+	var s Security
+	securityCheck = s.Check
+	securityFix = s.Fix
+}
+
+// FixAll checks each of the provided repos and calls Fix on those that are
+// enabled and missing a security policy. Up to operator.BatchConcurrency repos
+// are processed at once. An error on one repo does not stop the others, the
+// outcome for every repo is returned keyed by repo name.
+func FixAll(ctx context.Context, c *github.Client, owner string, repos []string) map[string]*FixResult {
+	results := make(map[string]*FixResult, len(repos))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, operator.BatchConcurrency)
+	for _, repo := range repos {
+		repo := repo
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			fr := fixOne(ctx, c, owner, repo)
+			mu.Lock()
+			results[repo] = fr
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func fixOne(ctx context.Context, c *github.Client, owner, repo string) *FixResult {
+	if err := ctx.Err(); err != nil {
+		return &FixResult{Err: err}
+	}
+	r, err := securityCheck(ctx, c, owner, repo)
+	if err != nil {
+		return &FixResult{Err: err}
+	}
+	if !r.Enabled || r.Pass || !fixable(r.Reasons) {
+		return &FixResult{}
+	}
+	if err := securityFix(policydef.WithReasons(ctx, r.Reasons), c, owner, repo); err != nil {
+		runid.Logger(ctx).Error().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Err(err).
+			Msg("Fix failed, continuing with other repos.")
+		return &FixResult{Err: err}
+	}
+	return &FixResult{Fixed: true}
+}
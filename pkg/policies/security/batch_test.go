@@ -0,0 +1,73 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func TestFixAll(t *testing.T) {
+	checkErr := errors.New("check failed")
+	fixErr := errors.New("fix failed")
+	securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+		switch repo {
+		case "passing":
+			return &policydef.Result{Enabled: true, Pass: true}, nil
+		case "disabled":
+			return &policydef.Result{Enabled: false, Pass: false}, nil
+		case "checkerr":
+			return nil, checkErr
+		case "tooshort":
+			return &policydef.Result{Enabled: true, Pass: false, Reasons: []string{ReasonTooShort}}, nil
+		}
+		return &policydef.Result{Enabled: true, Pass: false, Reasons: []string{ReasonMissing}}, nil
+	}
+	var mu sync.Mutex
+	var fixed []string
+	securityFix = func(ctx context.Context, c *github.Client, owner, repo string) error {
+		if repo == "fixerr" {
+			return fixErr
+		}
+		mu.Lock()
+		fixed = append(fixed, repo)
+		mu.Unlock()
+		return nil
+	}
+
+	got := FixAll(context.Background(), nil, "org",
+		[]string{"passing", "disabled", "checkerr", "tooshort", "fixerr", "failing"})
+	want := map[string]*FixResult{
+		"passing":  {},
+		"disabled": {},
+		"checkerr": {Err: checkErr},
+		"tooshort": {},
+		"fixerr":   {Err: fixErr},
+		"failing":  {Fixed: true},
+	}
+	c := cmp.Comparer(func(x, y error) bool { return x == y })
+	if diff := cmp.Diff(want, got, c); diff != "" {
+		t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"failing"}, fixed); diff != "" {
+		t.Errorf("Unexpected fixed repos. (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v39/github"
+)
+
+const branchesText = `This repository requires a security policy on each of its long-lived branches, such as release branches, so that users of every maintained version know how to report a vulnerability.
+
+To fix this, add or backport the security policy to each listed branch.`
+
+type branchRepositories interface {
+	repositories
+	ListBranches(context.Context, string, string, *github.BranchListOptions) (
+		[]*github.Branch, *github.Response, error)
+}
+
+var newBranchRepositories func(*github.Client) branchRepositories
+
+func init() {
+	newBranchRepositories = func(c *github.Client) branchRepositories {
+		return c.Repositories
+	}
+}
+
+// checkBranches returns which of the repo's branches matching any of patterns
+// have a non-empty security policy, and which do not.
+func checkBranches(ctx context.Context, rep branchRepositories, owner, repo string, patterns []string) ([]string, []string, error) {
+	opt := &github.BranchListOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	var passed, missing []string
+	for {
+		bs, resp, err := rep.ListBranches(ctx, owner, repo, opt)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, b := range bs {
+			if !matchBranch(b.GetName(), patterns) {
+				continue
+			}
+			_, con, _, err := getFirstEntryAt(ctx, rep, owner, repo, b.GetName(), policyPaths)
+			if err != nil {
+				return nil, nil, err
+			}
+			if strings.TrimSpace(con) == "" {
+				missing = append(missing, b.GetName())
+			} else {
+				passed = append(passed, b.GetName())
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return passed, missing, nil
+}
+
+// matchBranch returns whether name matches any of patterns, see path.Match.
+func matchBranch(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(p, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// branchQuorum returns how many of n matching branches must have a security
+// policy. A quorum of 0, or more than n, requires all of them.
+func branchQuorum(quorum, n int) int {
+	if quorum <= 0 || quorum > n {
+		return n
+	}
+	return quorum
+}
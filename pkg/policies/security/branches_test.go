@@ -0,0 +1,90 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+)
+
+type mockBranchRepos struct {
+	branches []string
+	// policies maps branch to security policy contents.
+	policies map[string]string
+}
+
+func (m mockBranchRepos) ListBranches(ctx context.Context, owner, repo string,
+	opts *github.BranchListOptions) ([]*github.Branch, *github.Response, error) {
+	var bs []*github.Branch
+	for _, b := range m.branches {
+		bs = append(bs, &github.Branch{Name: github.String(b)})
+	}
+	return bs, &github.Response{}, nil
+}
+
+func (m mockBranchRepos) GetContents(ctx context.Context, owner, repo, path string,
+	opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+	[]*github.RepositoryContent, *github.Response, error) {
+	c, ok := m.policies[opts.Ref]
+	if !ok || path != "SECURITY.md" {
+		return nil, nil, &github.Response{
+			Response: &http.Response{StatusCode: http.StatusNotFound},
+		}, &github.ErrorResponse{}
+	}
+	return &github.RepositoryContent{Content: &c}, nil, nil, nil
+}
+
+func TestCheckBranches(t *testing.T) {
+	rep := mockBranchRepos{
+		branches: []string{"main", "release/1.x", "release/2.x", "release/3.x", "feature"},
+		policies: map[string]string{
+			"main":        "Email security@example.com",
+			"release/1.x": "Email security@example.com",
+			"release/2.x": "",
+		},
+	}
+	passed, missing, err := checkBranches(context.Background(), rep, "org", "thisrepo", []string{"release/*", "main"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"main", "release/1.x"}, passed); diff != "" {
+		t.Errorf("Unexpected passed branches. (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"release/2.x", "release/3.x"}, missing); diff != "" {
+		t.Errorf("Unexpected missing branches. (-want +got):\n%s", diff)
+	}
+}
+
+func TestBranchQuorum(t *testing.T) {
+	tests := []struct {
+		Quorum int
+		N      int
+		Exp    int
+	}{
+		{0, 4, 4},
+		{2, 4, 2},
+		{5, 4, 4},
+		{0, 0, 0},
+	}
+	for _, test := range tests {
+		if got := branchQuorum(test.Quorum, test.N); got != test.Exp {
+			t.Errorf("Unexpected quorum for %v of %v. Expected: %v Got: %v", test.Quorum, test.N, test.Exp, got)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/issue"
+
+	"github.com/google/go-github/v39/github"
+	"github.com/shurcooL/githubv4"
+)
+
+// cassette is a recorded GitHub interaction for the full enforce path of the
+// policy on org/thisrepo: check, then the configured action.
+type cassette struct {
+	// Interactions are the recorded requests and responses. Every interaction
+	// must be used.
+	Interactions []*interaction `json:"interactions"`
+
+	// Pass and Reasons are the expected check result.
+	Pass    bool     `json:"pass"`
+	Reasons []string `json:"reasons"`
+
+	// Action is the expected action.
+	Action string `json:"action"`
+}
+
+type interaction struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Match, if set, must be contained in the request body, to tell GraphQL
+	// queries apart.
+	Match  string `json:"match"`
+	Status int    `json:"status"`
+	// Contents, if set, is served as a contents API file response.
+	Contents *string `json:"contents"`
+	// Body is the response body, used if Contents is not set.
+	Body json.RawMessage `json:"body"`
+
+	used bool
+}
+
+func (i *interaction) matches(r *http.Request, body string) bool {
+	return i.Method == r.Method && i.Path == r.URL.Path && strings.Contains(body, i.Match)
+}
+
+func (i *interaction) write(w http.ResponseWriter) {
+	body := []byte(i.Body)
+	if i.Contents != nil {
+		body, _ = json.Marshal(map[string]string{
+			"type":     "file",
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString([]byte(*i.Contents)),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if i.Status != 0 {
+		w.WriteHeader(i.Status)
+	}
+	w.Write(body)
+}
+
+// replay serves the interactions of cs, failing t on any unrecorded request.
+func replay(t *testing.T, cs *cassette) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, i := range cs.Interactions {
+			if i.matches(r, string(b)) {
+				i.used = true
+				i.write(w)
+				return
+			}
+		}
+		t.Errorf("Unrecorded request: %v %v %s", r.Method, r.URL.Path, b)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+}
+
+// resetClients restores the real GitHub access that other tests mock.
+func resetClients() {
+	configFetchConfig = fetchConfigRetry
+	newV4Client = defaultV4Client
+	timeNow = time.Now
+	configCacheDuration = 0
+}
+
+func TestCassettes(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "cassettes", "*.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatal("No cassettes found")
+	}
+	resetClients()
+	for _, f := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(f), ".json"), func(t *testing.T) {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var cs cassette
+			if err := json.Unmarshal(b, &cs); err != nil {
+				t.Fatalf("Invalid cassette: %v", err)
+			}
+			srv := replay(t, &cs)
+			defer srv.Close()
+			c := github.NewClient(srv.Client())
+			c.BaseURL, _ = url.Parse(srv.URL + "/")
+
+			ctx := context.Background()
+			s := Security(true)
+			res, err := s.Check(ctx, c, "org", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected check error: %v", err)
+			}
+			if res.Pass != cs.Pass {
+				t.Errorf("Unexpected pass. Expected: %v Got: %v", cs.Pass, res.Pass)
+			}
+			if strings.Join(res.Reasons, ",") != strings.Join(cs.Reasons, ",") {
+				t.Errorf("Unexpected reasons. Expected: %v Got: %v", cs.Reasons, res.Reasons)
+			}
+			a := s.GetAction(ctx, c, "org", "thisrepo")
+			if a != cs.Action {
+				t.Errorf("Unexpected action. Expected: %v Got: %v", cs.Action, a)
+			}
+			switch {
+			case !res.Pass && a == "issue":
+				err = issue.Ensure(ctx, c, "org", "thisrepo", s.Name(), res.NotifyText)
+			case !res.Pass && a == "fix":
+				err = s.Fix(ctx, c, "org", "thisrepo")
+			case res.Pass && a == "issue":
+				err = issue.Close(ctx, c, "org", "thisrepo", s.Name())
+			}
+			if err != nil {
+				t.Fatalf("Unexpected action error: %v", err)
+			}
+			for _, i := range cs.Interactions {
+				if !i.used {
+					t.Errorf("Recorded interaction not used: %v %v %v", i.Method, i.Path, i.Match)
+				}
+			}
+		})
+	}
+}
+
+func TestV4Endpoint(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+		fmt.Fprint(w, `{"data": {"repository": {"isSecurityPolicyEnabled": true}}}`)
+	}))
+	defer srv.Close()
+	old := operator.V4Endpoint
+	operator.V4Endpoint = srv.URL + "/enterprise/api/graphql"
+	defer func() { operator.V4Endpoint = old }()
+
+	c := github.NewClient(nil)
+	var q struct {
+		Repository struct {
+			IsSecurityPolicyEnabled bool
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	v := map[string]interface{}{
+		"owner": githubv4.String("org"),
+		"name":  githubv4.String("thisrepo"),
+	}
+	if err := defaultV4Client(c).Query(context.Background(), &q, v); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "/enterprise/api/graphql" {
+		t.Errorf("Unexpected GraphQL path: %v", got)
+	}
+	if !q.Repository.IsSecurityPolicyEnabled {
+		t.Errorf("Unexpected response: %+v", q)
+	}
+}
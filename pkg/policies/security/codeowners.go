@@ -0,0 +1,218 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// codeOwnersPaths are the locations GitHub recognizes for a CODEOWNERS file,
+// in the order GitHub resolves them.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+const codeOwnerReviewText = `This repository requires that changes to the security policy are reviewed and approved by an owner of the file, as listed in CODEOWNERS. The most recent change to the security policy was not approved by a code owner.
+
+To fix this, open a pull request that updates the security policy, or reverts to a reviewed version, and have it approved by a code owner before merging.`
+
+type reviewRepositories interface {
+	repositories
+	ListCommits(context.Context, string, string, *github.CommitsListOptions) (
+		[]*github.RepositoryCommit, *github.Response, error)
+}
+
+type reviewPulls interface {
+	ListPullRequestsWithCommit(context.Context, string, string, string,
+		*github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error)
+	ListReviews(context.Context, string, string, int, *github.ListOptions) (
+		[]*github.PullRequestReview, *github.Response, error)
+}
+
+type reviewTeams interface {
+	GetTeamMembershipBySlug(context.Context, string, string, string) (
+		*github.Membership, *github.Response, error)
+}
+
+// reviewClients holds the GitHub services used to verify code owner review, to
+// allow mocking.
+type reviewClients struct {
+	repos reviewRepositories
+	pulls reviewPulls
+	teams reviewTeams
+}
+
+var newReviewClients func(*github.Client) reviewClients
+
+func init() {
+	newReviewClients = func(c *github.Client) reviewClients {
+		return reviewClients{
+			repos: c.Repositories,
+			pulls: c.PullRequests,
+			teams: c.Teams,
+		}
+	}
+}
+
+// codeOwnersFor returns the owners of file p according to the CODEOWNERS
+// contents. As in GitHub, the last matching rule takes precedence.
+func codeOwnersFor(codeowners, p string) []string {
+	var owners []string
+	for _, line := range strings.Split(codeowners, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if codeOwnersMatch(fields[0], p) {
+			owners = fields[1:]
+		}
+	}
+	return owners
+}
+
+// codeOwnersMatch reports whether a CODEOWNERS pattern matches file p. It
+// supports the common gitignore forms: "*", anchored and unanchored paths,
+// directory patterns ending in "/", and "*" / "**" wildcards.
+func codeOwnersMatch(pattern, p string) bool {
+	if pattern == "*" {
+		return true
+	}
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	if !anchored {
+		// Unanchored patterns match at any depth.
+		parts := strings.Split(p, "/")
+		for i := range parts {
+			if globMatch(pattern, strings.Join(parts[i:], "/")) {
+				return true
+			}
+		}
+		return false
+	}
+	return globMatch(pattern, p)
+}
+
+func globMatch(pattern, p string) bool {
+	if strings.HasSuffix(pattern, "/**") {
+		prefix := strings.TrimSuffix(pattern, "**")
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+		pattern = strings.TrimSuffix(pattern, "/**")
+	}
+	if m, _ := path.Match(pattern, p); m {
+		return true
+	}
+	// A pattern naming a directory matches the files it contains.
+	for d := path.Dir(p); d != "." && d != "/"; d = path.Dir(d) {
+		if m, _ := path.Match(pattern, d); m {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCodeOwnerReview verifies that the last commit touching file p was merged
+// through a pull request approved by one of the file's code owners. It returns
+// a description of what was found, and whether the requirement is met.
+func checkCodeOwnerReview(ctx context.Context, rc reviewClients, owner, repo, p string) (string, bool, error) {
+	_, co, err := getFirstFile(ctx, rc.repos, owner, repo, codeOwnersPaths)
+	if err != nil {
+		return "", false, err
+	}
+	owners := codeOwnersFor(co, p)
+	if len(owners) == 0 {
+		return fmt.Sprintf("no code owners for %v", p), false, nil
+	}
+	commits, _, err := rc.repos.ListCommits(ctx, owner, repo, &github.CommitsListOptions{
+		Path:        p,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if len(commits) == 0 {
+		return fmt.Sprintf("no commits found for %v", p), false, nil
+	}
+	sha := commits[0].GetSHA()
+	prs, _, err := rc.pulls.ListPullRequestsWithCommit(ctx, owner, repo, sha, nil)
+	if err != nil {
+		return "", false, err
+	}
+	for _, pr := range prs {
+		if pr.MergedAt == nil {
+			continue
+		}
+		reviews, _, err := rc.pulls.ListReviews(ctx, owner, repo, pr.GetNumber(),
+			&github.ListOptions{PerPage: 100})
+		if err != nil {
+			return "", false, err
+		}
+		for _, r := range reviews {
+			if r.GetState() != "APPROVED" {
+				continue
+			}
+			login := r.GetUser().GetLogin()
+			ok, err := isCodeOwner(ctx, rc.teams, owners, login)
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				return fmt.Sprintf("commit %v approved by code owner %v in PR #%v",
+					sha, login, pr.GetNumber()), true, nil
+			}
+		}
+		return fmt.Sprintf("commit %v merged in PR #%v without code owner approval",
+			sha, pr.GetNumber()), false, nil
+	}
+	return fmt.Sprintf("commit %v not merged through a pull request", sha), false, nil
+}
+
+// isCodeOwner reports whether login is one of owners, directly as @user or
+// through an @org/team owner. Email owners can't be resolved to a login and are
+// not matched.
+func isCodeOwner(ctx context.Context, t reviewTeams, owners []string, login string) (bool, error) {
+	for _, o := range owners {
+		o = strings.TrimPrefix(o, "@")
+		if strings.EqualFold(o, login) {
+			return true, nil
+		}
+		parts := strings.SplitN(o, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		m, rsp, err := t.GetTeamMembershipBySlug(ctx, parts[0], parts[1], login)
+		if err != nil {
+			if rsp != nil && rsp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return false, err
+		}
+		if m.GetState() == "active" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
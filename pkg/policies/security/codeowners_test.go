@@ -0,0 +1,149 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+)
+
+func TestCodeOwnersFor(t *testing.T) {
+	co := `# Default owners
+*                @org/maintainers
+docs/            @docs-team
+*.md             @writer
+/SECURITY.md     @org/security # security team
+`
+	tests := []struct {
+		Path   string
+		Expect []string
+	}{
+		{Path: "SECURITY.md", Expect: []string{"@org/security"}},
+		{Path: ".github/SECURITY.md", Expect: []string{"@writer"}},
+		{Path: "docs/SECURITY.md", Expect: []string{"@writer"}},
+		{Path: "docs/guide.txt", Expect: []string{"@docs-team"}},
+		{Path: "main.go", Expect: []string{"@org/maintainers"}},
+	}
+	for _, test := range tests {
+		t.Run(test.Path, func(t *testing.T) {
+			if diff := cmp.Diff(test.Expect, codeOwnersFor(co, test.Path)); diff != "" {
+				t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type mockReview struct {
+	mockRepos
+	prs     []*github.PullRequest
+	reviews []*github.PullRequestReview
+	members map[string]bool
+}
+
+func (m mockReview) ListCommits(ctx context.Context, owner, repo string,
+	opts *github.CommitsListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+	sha := "abc123"
+	return []*github.RepositoryCommit{{SHA: &sha}}, nil, nil
+}
+
+func (m mockReview) ListPullRequestsWithCommit(ctx context.Context, owner, repo, sha string,
+	opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	return m.prs, nil, nil
+}
+
+func (m mockReview) ListReviews(ctx context.Context, owner, repo string, number int,
+	opts *github.ListOptions) ([]*github.PullRequestReview, *github.Response, error) {
+	return m.reviews, nil, nil
+}
+
+func (m mockReview) GetTeamMembershipBySlug(ctx context.Context, org, slug, user string) (
+	*github.Membership, *github.Response, error) {
+	if !m.members[org+"/"+slug+"/"+user] {
+		return nil, &github.Response{
+			Response: &http.Response{StatusCode: http.StatusNotFound},
+		}, &github.ErrorResponse{}
+	}
+	active := "active"
+	return &github.Membership{State: &active}, nil, nil
+}
+
+func TestCheckCodeOwnerReview(t *testing.T) {
+	getContents = contentsMock(map[string]string{
+		".github/CODEOWNERS": "SECURITY.md @org/security @lead\n",
+	})
+	merged := time.Now()
+	num := 7
+	pr := &github.PullRequest{Number: &num, MergedAt: &merged}
+	review := func(login, state string) *github.PullRequestReview {
+		return &github.PullRequestReview{
+			User:  &github.User{Login: &login},
+			State: &state,
+		}
+	}
+	members := map[string]bool{"org/security/alice": true}
+	tests := []struct {
+		Name    string
+		PRs     []*github.PullRequest
+		Reviews []*github.PullRequestReview
+		Expect  bool
+	}{
+		{
+			Name:    "TeamApproved",
+			PRs:     []*github.PullRequest{pr},
+			Reviews: []*github.PullRequestReview{review("alice", "APPROVED")},
+			Expect:  true,
+		},
+		{
+			Name:    "UserApproved",
+			PRs:     []*github.PullRequest{pr},
+			Reviews: []*github.PullRequestReview{review("Lead", "APPROVED")},
+			Expect:  true,
+		},
+		{
+			Name:    "NonOwnerApproved",
+			PRs:     []*github.PullRequest{pr},
+			Reviews: []*github.PullRequestReview{review("bob", "APPROVED"), review("alice", "COMMENTED")},
+			Expect:  false,
+		},
+		{
+			Name:   "NoPR",
+			Expect: false,
+		},
+		{
+			Name:    "NotMerged",
+			PRs:     []*github.PullRequest{{Number: &num}},
+			Reviews: []*github.PullRequestReview{review("alice", "APPROVED")},
+			Expect:  false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			m := mockReview{prs: test.PRs, reviews: test.Reviews, members: members}
+			found, ok, err := checkCodeOwnerReview(context.Background(),
+				reviewClients{repos: m, pulls: m, teams: m}, "org", "thisrepo", "SECURITY.md")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ok != test.Expect {
+				t.Errorf("Unexpected results. Expected: %v Got: %v (%v)", test.Expect, ok, found)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifUpload is the request body of the code scanning SARIF upload API.
+type sarifUpload struct {
+	CommitSHA string `json:"commit_sha"`
+	Ref       string `json:"ref"`
+	Sarif     string `json:"sarif"`
+	ToolName  string `json:"tool_name"`
+}
+
+// toSARIF returns a SARIF log with a result for each reason code of res. A
+// passing res has no results, so that previous alerts are closed.
+func toSARIF(res *policydef.Result) *sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "Allstar",
+				InformationURI: "https://github.com/ossf/allstar",
+				Rules:          []sarifRule{},
+			},
+		},
+		Results: []sarifResult{},
+	}
+	if !res.Pass {
+		for _, r := range res.Reasons {
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               r,
+				ShortDescription: sarifMessage{Text: polName + " policy: " + r},
+			})
+			run.Results = append(run.Results, sarifResult{
+				RuleID:  r,
+				Level:   "error",
+				Message: sarifMessage{Text: res.NotifyText},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: fixPath},
+					},
+				}},
+			})
+		}
+	}
+	return &sarifLog{
+		Version: "2.1.0",
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	}
+}
+
+// encodeSARIF returns the gzip compressed and base64 encoded SARIF, as
+// expected by the upload API.
+func encodeSARIF(s *sarifLog) (string, error) {
+	j, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	if _, err := gz.Write(j); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b.Bytes()), nil
+}
+
+var defaultBranchHead func(ctx context.Context, c *github.Client, owner, repo string) (string, string, error)
+var uploadSARIF func(ctx context.Context, c *github.Client, owner, repo string, u *sarifUpload) error
+
+func init() {
+	defaultBranchHead = func(ctx context.Context, c *github.Client, owner, repo string) (string, string, error) {
+		r, _, err := c.Repositories.Get(ctx, owner, repo)
+		if err != nil {
+			return "", "", err
+		}
+		ref, _, err := c.Git.GetRef(ctx, owner, repo, "heads/"+r.GetDefaultBranch())
+		if err != nil {
+			return "", "", err
+		}
+		return ref.GetRef(), ref.GetObject().GetSHA(), nil
+	}
+	uploadSARIF = func(ctx context.Context, c *github.Client, owner, repo string, u *sarifUpload) error {
+		req, err := c.NewRequest("POST", fmt.Sprintf("repos/%v/%v/code-scanning/sarifs", owner, repo), u)
+		if err != nil {
+			return err
+		}
+		_, err = c.Do(ctx, req, nil)
+		return err
+	}
+}
+
+// uploaded stores the reasons last uploaded per repo, so that unchanged
+// results aren't uploaded again.
+var uploaded = struct {
+	sync.Mutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+// uploadCodeScanning uploads the findings of res as code scanning alerts on
+// the repo's default branch, if they changed since the last upload. Errors are
+// logged, as they should not fail the check.
+func uploadCodeScanning(ctx context.Context, c *github.Client, owner, repo string, res *policydef.Result) {
+	key := owner + "/" + repo
+	findings := ""
+	if !res.Pass {
+		findings = strings.Join(res.Reasons, ",")
+	}
+	uploaded.Lock()
+	last, ok := uploaded.m[key]
+	uploaded.Unlock()
+	if ok && last == findings && !policydef.IsForceRecheck(ctx) {
+		return
+	}
+	err := func() error {
+		sarif, err := encodeSARIF(toSARIF(res))
+		if err != nil {
+			return err
+		}
+		ref, sha, err := defaultBranchHead(ctx, c, owner, repo)
+		if err != nil {
+			return err
+		}
+		return uploadSARIF(ctx, c, owner, repo, &sarifUpload{
+			CommitSHA: sha,
+			Ref:       ref,
+			Sarif:     sarif,
+			ToolName:  "Allstar",
+		})
+	}()
+	if err != nil {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Err(err).
+			Msg("Unable to upload code scanning results.")
+		return
+	}
+	uploaded.Lock()
+	uploaded.m[key] = findings
+	uploaded.Unlock()
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func decodeSARIF(t *testing.T, s string) *sarifLog {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	j, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var l sarifLog
+	if err := json.Unmarshal(j, &l); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return &l
+}
+
+func TestUploadCodeScanning(t *testing.T) {
+	defaultBranchHead = func(ctx context.Context, c *github.Client, owner, repo string) (string, string, error) {
+		return "refs/heads/main", "abc123", nil
+	}
+	var uploads []*sarifUpload
+	uploadSARIF = func(ctx context.Context, c *github.Client, owner, repo string, u *sarifUpload) error {
+		uploads = append(uploads, u)
+		return nil
+	}
+	fail := &policydef.Result{
+		Enabled:    true,
+		Pass:       false,
+		NotifyText: "Security policy not enabled.",
+		Reasons:    []string{ReasonMissing},
+	}
+	uploadCodeScanning(context.Background(), nil, "org", "scanned", fail)
+	uploadCodeScanning(context.Background(), nil, "org", "scanned", fail)
+	if len(uploads) != 1 {
+		t.Fatalf("Expected 1 upload for unchanged result, got: %v", len(uploads))
+	}
+	u := uploads[0]
+	if u.CommitSHA != "abc123" || u.Ref != "refs/heads/main" {
+		t.Errorf("Unexpected upload target: %v %v", u.CommitSHA, u.Ref)
+	}
+	l := decodeSARIF(t, u.Sarif)
+	if len(l.Runs) != 1 || len(l.Runs[0].Results) != 1 {
+		t.Fatalf("Unexpected SARIF: %+v", l)
+	}
+	if r := l.Runs[0].Results[0]; r.RuleID != ReasonMissing || r.Message.Text != fail.NotifyText {
+		t.Errorf("Unexpected SARIF result: %+v", r)
+	}
+
+	uploadCodeScanning(context.Background(), nil, "org", "scanned", &policydef.Result{Enabled: true, Pass: true})
+	if len(uploads) != 2 {
+		t.Fatalf("Expected upload for passing result, got: %v", len(uploads))
+	}
+	if l := decodeSARIF(t, uploads[1].Sarif); len(l.Runs[0].Results) != 0 {
+		t.Errorf("Expected no SARIF results for pass, got: %+v", l.Runs[0].Results)
+	}
+}
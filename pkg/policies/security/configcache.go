@@ -0,0 +1,87 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+
+	"github.com/google/go-github/v39/github"
+)
+
+var configCacheDuration time.Duration = operator.ConfigCacheDuration
+
+type cachedConfig struct {
+	v       interface{}
+	fetched time.Time
+}
+
+var configCache = struct {
+	sync.Mutex
+	m map[string]cachedConfig
+}{m: make(map[string]cachedConfig)}
+
+func configCacheKey(owner, repo, path string) string {
+	return strings.ToLower(owner) + "/" + strings.ToLower(repo) + "/" + path
+}
+
+// fetchConfigCached is configFetchConfig, with successful results cached for
+// configCacheDuration. The cache is bypassed for a forced recheck, see
+// policydef.WithForceRecheck. out must be a pointer to a struct.
+func fetchConfigCached(ctx context.Context, c *github.Client, owner, repo, path string,
+	out interface{}) error {
+	if configCacheDuration <= 0 {
+		return configFetchConfig(ctx, c, owner, repo, path, out)
+	}
+	key := configCacheKey(owner, repo, path)
+	dst := reflect.ValueOf(out).Elem()
+	now := timeNow()
+	configCache.Lock()
+	e, ok := configCache.m[key]
+	configCache.Unlock()
+	if ok && !policydef.IsForceRecheck(ctx) && now.Sub(e.fetched) < configCacheDuration &&
+		reflect.TypeOf(e.v) == dst.Type() {
+		dst.Set(reflect.ValueOf(e.v))
+		return nil
+	}
+	if err := configFetchConfig(ctx, c, owner, repo, path, out); err != nil {
+		return err
+	}
+	configCache.Lock()
+	configCache.m[key] = cachedConfig{v: dst.Interface(), fetched: now}
+	configCache.Unlock()
+	return nil
+}
+
+// InvalidateConfig clears the cached SECURITY.md policy config of the org and
+// all of its repos, so that the next check re-reads it. It is intended to be
+// called when the config changes, such as from a push webhook on the org config
+// repo.
+func InvalidateConfig(owner string) {
+	prefix := strings.ToLower(owner) + "/"
+	configCache.Lock()
+	defer configCache.Unlock()
+	for k := range configCache.m {
+		if strings.HasPrefix(k, prefix) {
+			delete(configCache.m, k)
+		}
+	}
+}
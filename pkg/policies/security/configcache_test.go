@@ -0,0 +1,107 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func TestInvalidateConfig(t *testing.T) {
+	configCacheDuration = time.Hour
+	defer func() { configCacheDuration = 0 }()
+	action := "log"
+	fetches := 0
+	configFetchConfig = func(ctx context.Context, c *github.Client, owner, repo, path string,
+		out interface{}) error {
+		fetches++
+		if oc, ok := out.(*OrgConfig); ok {
+			oc.Action = action
+		}
+		return nil
+	}
+	oc, _ := getConfig(context.Background(), nil, "Org", "thisrepo")
+	action = "issue"
+	oc2, _ := getConfig(context.Background(), nil, "org", "thisrepo")
+	if fetches != 2 {
+		t.Errorf("Expected cached config on second read, got %v fetches", fetches)
+	}
+	if oc.Action != "log" || oc2.Action != "log" {
+		t.Errorf("Unexpected cached action: %v %v", oc.Action, oc2.Action)
+	}
+	InvalidateConfig("ORG")
+	oc3, _ := getConfig(context.Background(), nil, "org", "thisrepo")
+	if fetches != 4 {
+		t.Errorf("Expected config to be re-read after invalidate, got %v fetches", fetches)
+	}
+	if oc3.Action != "issue" {
+		t.Errorf("Expected updated action, got: %v", oc3.Action)
+	}
+}
+
+func TestFetchConfigForceRecheck(t *testing.T) {
+	configCacheDuration = time.Hour
+	defer func() { configCacheDuration = 0 }()
+	InvalidateConfig("forced")
+	action := "log"
+	configFetchConfig = func(ctx context.Context, c *github.Client, owner, repo, path string,
+		out interface{}) error {
+		if oc, ok := out.(*OrgConfig); ok {
+			oc.Action = action
+		}
+		return nil
+	}
+	getConfig(context.Background(), nil, "forced", "thisrepo")
+	action = "issue"
+	oc, _ := getConfig(policydef.WithForceRecheck(context.Background()), nil, "forced", "thisrepo")
+	if oc.Action != "issue" {
+		t.Errorf("Expected fresh config on forced recheck, got: %v", oc.Action)
+	}
+	action = "fix"
+	oc, _ = getConfig(context.Background(), nil, "forced", "thisrepo")
+	if oc.Action != "issue" {
+		t.Errorf("Expected forced recheck to refresh cache, got: %v", oc.Action)
+	}
+}
+
+func TestGetConfigRunCache(t *testing.T) {
+	fetches := 0
+	configFetchConfig = func(ctx context.Context, c *github.Client, owner, repo, path string,
+		out interface{}) error {
+		fetches++
+		if oc, ok := out.(*OrgConfig); ok {
+			oc.RenotifyIntervalDays = 3
+		}
+		return nil
+	}
+	ctx := policydef.WithRunCache(context.Background())
+	var s Security
+	if d := s.RenotifyInterval(ctx, nil, "org", "thisrepo"); d != 3*24*time.Hour {
+		t.Errorf("Unexpected renotify interval: %v", d)
+	}
+	s.DryRun(ctx, nil, "org", "thisrepo")
+	resolveConfig(ctx, nil, "org", "thisrepo")
+	if fetches != 2 {
+		t.Errorf("Expected org and repo config to be fetched once per run, got %v fetches", fetches)
+	}
+	getConfig(ctx, nil, "org", "otherrepo")
+	if fetches != 4 {
+		t.Errorf("Expected other repo config to be fetched, got %v fetches", fetches)
+	}
+}
@@ -0,0 +1,564 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// policyPaths are the locations GitHub recognizes for a security policy, in
+// the order GitHub resolves them.
+var policyPaths = []string{".github/SECURITY.md", "SECURITY.md", "docs/SECURITY.md"}
+
+const publicDisclosureText = `The security policy appears to instruct reporters to open a public issue for security vulnerabilities. Public issues are visible to everyone, including attackers, before a fix is available.
+
+To fix this, update the security policy to direct reporters to a private channel, such as GitHub private vulnerability reporting or a security contact email, and ask them not to open public issues for vulnerabilities.`
+
+// defaultPublicDisclosurePatterns match instructions to file a public issue.
+var defaultPublicDisclosurePatterns = []string{
+	`(open|file|create|submit|raise)\s+(a|an)?\s*(new\s+)?(public\s+|github\s+)?issue`,
+	`report\s+(it|them|this)?\s*(in|on|via|to)\s+(the\s+)?(public\s+|github\s+)?issue`,
+}
+
+var securityKeywordRegex = regexp.MustCompile(`(?i)(security|vulnerabilit|exploit|cve)`)
+var negationRegex = regexp.MustCompile(`(?i)\b(not|don't|dont|never|avoid|instead of)\b`)
+
+type repositories interface {
+	GetContents(context.Context, string, string, string,
+		*github.RepositoryContentGetOptions) (*github.RepositoryContent,
+		[]*github.RepositoryContent, *github.Response, error)
+}
+
+// getPolicyFile returns the path and contents of the repo's security policy
+// file. If no file is found, path is empty.
+func getPolicyFile(ctx context.Context, rep repositories, owner, repo string) (string, string, error) {
+	return getFirstFile(ctx, rep, owner, repo, policyPaths)
+}
+
+// confirmPolicyFile looks up the security policy file of a repo that GitHub
+// reports as having no security policy, as GitHub may lag in detecting a newly
+// added file. It returns the path of the file if found. The discrepancy is
+// logged, so that how often it happens can be tracked. Errors are logged and
+// treated as not found.
+func confirmPolicyFile(ctx context.Context, rep repositories, owner, repo string) string {
+	p, _, err := getPolicyFile(ctx, rep, owner, repo)
+	if err != nil {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Err(err).
+			Msg("Unable to confirm security policy is missing.")
+		return ""
+	}
+	if p != "" {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("path", p).
+			Msg("Security policy file found, but GitHub reports it not enabled.")
+	}
+	return p
+}
+
+// getPolicyEntry is like getPolicyFile, at ref if not empty, and also returns
+// the entry type reported by the contents API, such as "file", "symlink", or
+// "submodule".
+func getPolicyEntry(ctx context.Context, rep repositories, owner, repo, ref string) (string, string, string, error) {
+	return getFirstEntryAt(ctx, rep, owner, repo, ref, policyPaths)
+}
+
+// getFirstFile returns the path and contents of the first of paths found in
+// the repo. If none is found, path is empty.
+func getFirstFile(ctx context.Context, rep repositories, owner, repo string, paths []string) (string, string, error) {
+	p, con, _, err := getFirstEntry(ctx, rep, owner, repo, paths)
+	return p, con, err
+}
+
+// getFirstEntry returns the path, contents, and type of the first of paths
+// found in the repo. A symlink to a file is resolved by the contents API and
+// reported as a file. If none is found, path is empty.
+func getFirstEntry(ctx context.Context, rep repositories, owner, repo string, paths []string) (string, string, string, error) {
+	return getFirstEntryAt(ctx, rep, owner, repo, "", paths)
+}
+
+// getFirstEntryAt is like getFirstEntry, for the given ref. An empty ref is the
+// default branch.
+func getFirstEntryAt(ctx context.Context, rep repositories, owner, repo, ref string, paths []string) (string, string, string, error) {
+	var opts *github.RepositoryContentGetOptions
+	if ref != "" {
+		opts = &github.RepositoryContentGetOptions{Ref: ref}
+	}
+	for _, p := range paths {
+		fc, _, rsp, err := rep.GetContents(ctx, owner, repo, p, opts)
+		debugResponse(ctx, owner, repo, "contents "+p, fc)
+		if err != nil {
+			if rsp != nil && rsp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return "", "", "", err
+		}
+		if fc == nil {
+			// Path is a directory
+			continue
+		}
+		typ := fc.GetType()
+		if typ == "" {
+			typ = "file"
+		}
+		if typ != "file" {
+			// Symlink target or submodule, there is no content to decode.
+			return p, "", typ, nil
+		}
+		con, err := fc.GetContent()
+		if err != nil {
+			return "", "", "", err
+		}
+		return p, con, typ, nil
+	}
+	return "", "", "", nil
+}
+
+const notRegularFileText = `This repository requires the security policy to be a regular, non-empty file. A symlink that GitHub can not resolve, a submodule, or an empty file gives users no information on how to report a vulnerability.
+
+To fix this, replace the security policy with a file that describes how to report vulnerabilities.`
+
+const badEncodingText = `This repository requires the security policy to be UTF-8 text. A binary or garbled file gives users no information on how to report a vulnerability.
+
+To fix this, replace the security policy with a UTF-8 text file that describes how to report vulnerabilities.`
+
+// encodingProblem describes why content is not UTF-8 text, or returns an empty
+// string if it is.
+func encodingProblem(content string) string {
+	switch {
+	case strings.HasPrefix(content, "\xff\xfe") || strings.HasPrefix(content, "\xfe\xff"):
+		return "UTF-16 encoded"
+	case strings.ContainsRune(content, 0):
+		return "binary content"
+	case !utf8.ValidString(content):
+		return "invalid UTF-8"
+	}
+	return ""
+}
+
+const subPathsText = `This repository requires a SECURITY.md in each of the listed subdirectories, so that users of each component know how to report a vulnerability in it.
+
+To fix this, add a SECURITY.md to each listed subdirectory describing how to report vulnerabilities in that component.`
+
+// checkSubPaths returns which of subPaths contain a non-empty SECURITY.md, and
+// which do not.
+func checkSubPaths(ctx context.Context, rep repositories, owner, repo string, subPaths []string) ([]string, []string, error) {
+	var passed, missing []string
+	for _, sp := range subPaths {
+		sp = strings.Trim(sp, "/")
+		_, con, err := getFirstFile(ctx, rep, owner, repo, []string{path.Join(sp, "SECURITY.md")})
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.TrimSpace(con) == "" {
+			missing = append(missing, sp)
+		} else {
+			passed = append(passed, sp)
+		}
+	}
+	return passed, missing, nil
+}
+
+// findPathPolicy returns the first of paths that is a non-empty file in the
+// repo, at ref if not empty, or an empty string if there is none.
+func findPathPolicy(ctx context.Context, rep repositories, owner, repo, ref string, paths []string) (string, error) {
+	for _, p := range paths {
+		p = strings.Trim(p, "/")
+		found, con, typ, err := getFirstEntryAt(ctx, rep, owner, repo, ref, []string{p})
+		if err != nil {
+			return "", err
+		}
+		if found != "" && typ == "file" && strings.TrimSpace(con) != "" {
+			return found, nil
+		}
+	}
+	return "", nil
+}
+
+// findPublicDisclosure returns the first line of content that instructs
+// reporters to open a public issue for a security bug, or an empty string if
+// none is found. Lines which are negated, ex: "do not open an issue", are not
+// considered.
+func findPublicDisclosure(content string, patterns []string) (string, error) {
+	if len(patterns) == 0 {
+		patterns = defaultPublicDisclosurePatterns
+	}
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return "", err
+		}
+		res = append(res, re)
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if !securityKeywordRegex.MatchString(line) || negationRegex.MatchString(line) {
+			continue
+		}
+		for _, re := range res {
+			if re.MatchString(line) {
+				return strings.TrimSpace(line), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+const placeholderText = `This repository has a security policy file, but it only contains boilerplate from a template generator, so it does not yet tell reporters how to report a vulnerability.
+
+To fix this, replace the boilerplate with instructions for reporting vulnerabilities, such as a contact email or a link to private vulnerability reporting.`
+
+// defaultPlaceholderPatterns match GitHub's security policy setup text and
+// links to the security policy template chooser.
+var defaultPlaceholderPatterns = []string{
+	`to set up a security policy`,
+	`click here to (set up|add|create) a security policy`,
+	`choose a security policy template`,
+	`github\.com/[^/\s]+/[^/\s]+/security/policy(/new)?\b`,
+	`^[\s*>-]*(todo|tbd|coming soon)\.?[\s*]*$`,
+}
+
+// findPlaceholder returns the first line of content matching one of the
+// placeholder patterns if every other line, ignoring blank lines and headings,
+// also matches, or an empty string if the content has anything more.
+func findPlaceholder(content string, patterns []string) (string, error) {
+	if len(patterns) == 0 {
+		patterns = defaultPlaceholderPatterns
+	}
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return "", err
+		}
+		res = append(res, re)
+	}
+	var first string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		matched := false
+		for _, re := range res {
+			if re.MatchString(line) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "", nil
+		}
+		if first == "" {
+			first = line
+		}
+	}
+	return first, nil
+}
+
+const tooShortText = `This repository requires a security policy of a minimum length, so that a one-line stub does not count as a security policy.
+
+To fix this, expand the security policy with instructions for reporting vulnerabilities, such as a contact email or a link to private vulnerability reporting, and what reporters can expect in response.`
+
+const requiredContentsText = `This repository requires that the security policy includes certain content, as set by the organization.
+
+To fix this, add the missing content to the security policy.`
+
+// contentLength returns the number of characters in content, ignoring leading
+// and trailing whitespace.
+func contentLength(content string) int {
+	return utf8.RuneCountInString(strings.TrimSpace(content))
+}
+
+// missingContents returns the patterns that content does not contain. Each is
+// matched as a case-insensitive regular expression, or as a plain substring if
+// it is not a valid regular expression.
+func missingContents(content string, patterns []string) []string {
+	var missing []string
+	for _, p := range patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(p))
+		}
+		if !re.MatchString(content) {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// requiredContentsNotifyText lists the missing required contents of the
+// security policy at path.
+func requiredContentsNotifyText(path string, missing []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Security policy %v is missing required content:\n", path)
+	for _, m := range missing {
+		fmt.Fprintf(&b, "- %v\n", m)
+	}
+	b.WriteString("\n")
+	return b.String() + requiredContentsText
+}
+
+const timelineText = `This repository requires that the security policy states how quickly reports are handled, so that reporters know when to expect a response and when the vulnerability may be disclosed.
+
+To fix this, add a response-time commitment to the security policy, such as "We aim to respond to reports within 48 hours" or "We follow a 90-day disclosure timeline".`
+
+// defaultTimelinePatterns match a duration, such as "48 hours" or "90-day".
+var defaultTimelinePatterns = []string{
+	`\b(\d+|one|two|three|four|five|seven|ten|fourteen|thirty|sixty|ninety)[\s-]+((business|working|calendar)[\s-]+)?(hours?|days?|weeks?|months?)\b`,
+}
+
+var timelineKeywordRegex = regexp.MustCompile(`(?i)(respon|acknowledg|triage|disclos|fix|patch|release|reply)`)
+
+// findTimeline returns the first line of content that commits to a response or
+// disclosure time, or an empty string if none is found.
+func findTimeline(content string, patterns []string) (string, error) {
+	if len(patterns) == 0 {
+		patterns = defaultTimelinePatterns
+	}
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return "", err
+		}
+		res = append(res, re)
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if !timelineKeywordRegex.MatchString(line) {
+			continue
+		}
+		for _, re := range res {
+			if re.MatchString(line) {
+				return strings.TrimSpace(line), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+const supportedVersionsText = `This repository requires that the security policy lists which versions receive security updates, under a "Supported Versions" heading, so that users know whether they are affected and need to upgrade.
+
+To fix this, add a "Supported Versions" section to the security policy with a list or table of the supported versions. The example versions in GitHub's template (5.1.x, 5.0.x, 4.0.x, < 4.0) are not accepted.`
+
+var supportedVersionsHeading = regexp.MustCompile(`(?i)^(#+)\s*supported\s+versions\b`)
+var markdownHeading = regexp.MustCompile(`^(#+)\s`)
+var tableSeparator = regexp.MustCompile(`^\|?[\s:|-]+\|?$`)
+
+// templateVersions are the example rows of GitHub's SECURITY.md template.
+var templateVersions = []string{"5.1.x", "5.0.x", "4.0.x", "< 4.0"}
+
+// findSupportedVersions returns whether content has a "Supported Versions"
+// heading, and the versions listed in the table rows or list items of that
+// section, excluding GitHub's template examples.
+func findSupportedVersions(content string) (bool, []string) {
+	found := false
+	level := 0
+	var versions []string
+	header := true
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if !found {
+			if m := supportedVersionsHeading.FindStringSubmatch(line); m != nil {
+				found = true
+				level = len(m[1])
+			}
+			continue
+		}
+		if m := markdownHeading.FindStringSubmatch(line); m != nil && len(m[1]) <= level {
+			break
+		}
+		var v string
+		switch {
+		case strings.HasPrefix(line, "|"):
+			if tableSeparator.MatchString(line) {
+				header = false
+				continue
+			}
+			if header {
+				// Table header row, before the separator.
+				continue
+			}
+			v = strings.TrimSpace(strings.Split(strings.Trim(line, "|"), "|")[0])
+		case strings.HasPrefix(line, "- "), strings.HasPrefix(line, "* "):
+			v = strings.TrimSpace(line[2:])
+		default:
+			continue
+		}
+		if v == "" || contains(templateVersions, v) {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return found, versions
+}
+
+const readmeLinkText = `This repository requires that the README links to the security policy, so that users can find how to report a vulnerability.
+
+To fix this, add a "Security" section to the README that links to SECURITY.md, for example: "See [SECURITY.md](SECURITY.md) for how to report a vulnerability."`
+
+// readmePaths are the README locations checked for a security policy link, in
+// the order GitHub resolves them.
+var readmePaths = []string{".github/README.md", "README.md", "README", "README.rst", "README.txt", "docs/README.md"}
+
+var readmeLinkRegex = regexp.MustCompile(`(?i)(security\.md|/security/policy\b)`)
+
+// checkReadmeLink returns whether the repo has a README, and whether it
+// references the security policy.
+func checkReadmeLink(ctx context.Context, rep repositories, owner, repo string) (bool, bool, error) {
+	p, content, err := getFirstFile(ctx, rep, owner, repo, readmePaths)
+	if err != nil {
+		return false, false, err
+	}
+	if p == "" {
+		return false, false, nil
+	}
+	return true, readmeLinkRegex.MatchString(content), nil
+}
+
+// Contact channel types detected in a security policy.
+const (
+	channelEmail            = "email"
+	channelBugBounty        = "bugBounty"
+	channelPrivateReporting = "privateReporting"
+	channelPGP              = "pgp"
+)
+
+// channelPatterns detect each contact channel type, in reporting order.
+var channelPatterns = []struct {
+	channel string
+	regex   *regexp.Regexp
+}{
+	{channelEmail, regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`)},
+	{channelBugBounty, regexp.MustCompile(`(?i)(hackerone\.com/|bugcrowd\.com/|intigriti\.com/|yeswehack\.com/|bug\s+bounty)`)},
+	{channelPrivateReporting, regexp.MustCompile(`(?i)(/security/advisories/new|private\s+vulnerability\s+reporting|report\s+a\s+vulnerability"?\s+button)`)},
+	{channelPGP, regexp.MustCompile(`(?i)(BEGIN PGP PUBLIC KEY BLOCK|keys\.openpgp\.org|\b(pgp|gpg)\s+key)`)},
+}
+
+const contactChannelsText = `This repository requires that the security policy lists enough private contact channels for reporting vulnerabilities, such as a security email address, a bug bounty program, GitHub private vulnerability reporting, or a PGP key for encrypted reports.
+
+To fix this, add the missing contact channels to the security policy.`
+
+// findContactChannels returns the contact channel types found in content.
+func findContactChannels(content string) []string {
+	var found []string
+	for _, p := range channelPatterns {
+		if p.regex.MatchString(content) {
+			found = append(found, p.channel)
+		}
+	}
+	return found
+}
+
+const contactDomainText = `This repository requires that vulnerability reports go to contacts controlled by the organization, so that reports are not sent to personal addresses or third-party services.
+
+To fix this, replace the listed contacts with email addresses or URLs on an approved domain.`
+
+// ContactDomain is an email address or URL found in a security policy, and
+// whether its domain is approved by RequireContactDomains.
+type ContactDomain struct {
+	Contact string
+	Domain  string
+	Allowed bool
+}
+
+var (
+	contactEmailRegex = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@([a-z0-9-]+(\.[a-z0-9-]+)*\.[a-z]{2,})\b`)
+	contactURLRegex   = regexp.MustCompile(`(?i)\bhttps?://([a-z0-9-]+(\.[a-z0-9-]+)*\.[a-z]{2,})(:\d+)?(/[^\s)\]>"'<]*)?`)
+)
+
+// checkContactDomains returns the email addresses and URLs in content with
+// whether each is on one of domains. Links to the owner on GitHub are
+// skipped.
+func checkContactDomains(content, owner string, domains []string) []ContactDomain {
+	var found []ContactDomain
+	seen := make(map[string]bool)
+	add := func(contact, domain string) {
+		if seen[contact] {
+			return
+		}
+		seen[contact] = true
+		found = append(found, ContactDomain{
+			Contact: contact,
+			Domain:  strings.ToLower(domain),
+			Allowed: domainAllowed(domain, domains),
+		})
+	}
+	for _, m := range contactURLRegex.FindAllStringSubmatch(content, -1) {
+		if strings.EqualFold(m[1], "github.com") &&
+			strings.HasPrefix(strings.ToLower(m[4]+"/"), "/"+strings.ToLower(owner)+"/") {
+			continue
+		}
+		add(strings.TrimRight(m[0], ".,;:!?"), m[1])
+	}
+	for _, m := range contactEmailRegex.FindAllStringSubmatch(content, -1) {
+		add(m[0], m[1])
+	}
+	return found
+}
+
+// domainAllowed returns whether host is one of domains or a subdomain of one.
+func domainAllowed(host string, domains []string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(d), "*"), ".")
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// offDomainContacts returns the contacts that are not on an approved domain.
+func offDomainContacts(cds []ContactDomain) []string {
+	var off []string
+	for _, cd := range cds {
+		if !cd.Allowed {
+			off = append(off, cd.Contact)
+		}
+	}
+	return off
+}
+
+// countAccepted returns how many of found are in accepted. If accepted is
+// empty, all channel types are accepted.
+func countAccepted(found, accepted []string) int {
+	if len(accepted) == 0 {
+		return len(found)
+	}
+	n := 0
+	for _, f := range found {
+		if contains(accepted, f) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,558 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+)
+
+func TestFindPublicDisclosure(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Content  string
+		Patterns []string
+		Expect   string
+		Err      bool
+	}{
+		{
+			Name:    "Private",
+			Content: "# Security\n\nPlease email security@example.com to report a vulnerability.",
+			Expect:  "",
+		},
+		{
+			Name:    "Negated",
+			Content: "# Security\n\nPlease do not open a public issue for security vulnerabilities.",
+			Expect:  "",
+		},
+		{
+			Name:    "PublicIssue",
+			Content: "# Security\n\nTo report a security vulnerability, open an issue on GitHub.\n",
+			Expect:  "To report a security vulnerability, open an issue on GitHub.",
+		},
+		{
+			Name:    "IssueNotSecurity",
+			Content: "# Security\n\nFor feature requests, open an issue.",
+			Expect:  "",
+		},
+		{
+			Name:     "CustomPattern",
+			Content:  "Post security bugs to the forum.",
+			Patterns: []string{`post .* forum`},
+			Expect:   "Post security bugs to the forum.",
+		},
+		{
+			Name:     "BadPattern",
+			Content:  "Post security bugs to the forum.",
+			Patterns: []string{`(`},
+			Err:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			got, err := findPublicDisclosure(test.Content, test.Patterns)
+			if test.Err {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != test.Expect {
+				t.Errorf("Unexpected result. Expected: %q Got: %q", test.Expect, got)
+			}
+		})
+	}
+}
+
+func TestFindSupportedVersions(t *testing.T) {
+	template := `# Security Policy
+
+## Supported Versions
+
+Use this section to tell people about which versions of your project are
+currently being supported with security updates.
+
+| Version | Supported          |
+| ------- | ------------------ |
+| 5.1.x   | :white_check_mark: |
+| 5.0.x   | :x:                |
+| 4.0.x   | :white_check_mark: |
+| < 4.0   | :x:                |
+
+## Reporting a Vulnerability
+`
+	tests := []struct {
+		Name     string
+		Content  string
+		Found    bool
+		Versions []string
+	}{
+		{
+			Name:    "Template",
+			Content: template,
+			Found:   true,
+		},
+		{
+			Name:     "Table",
+			Content:  strings.Replace(template, "| 5.1.x   |", "| 2.3.x   |", 1),
+			Found:    true,
+			Versions: []string{"2.3.x"},
+		},
+		{
+			Name:     "List",
+			Content:  "# Policy\n### Supported versions\n- v1.2\n- v1.1\n## Reporting\n- email us\n",
+			Found:    true,
+			Versions: []string{"v1.2", "v1.1"},
+		},
+		{
+			Name:    "Missing",
+			Content: "# Policy\nEmail security@example.com\n",
+			Found:   false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			found, versions := findSupportedVersions(test.Content)
+			if found != test.Found {
+				t.Errorf("Unexpected found. Expected: %v Got: %v", test.Found, found)
+			}
+			if diff := cmp.Diff(test.Versions, versions); diff != "" {
+				t.Errorf("Unexpected versions. (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckReadmeLink(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Files  map[string]string
+		Found  bool
+		Linked bool
+	}{
+		{
+			Name:   "Linked",
+			Files:  map[string]string{"README.md": "## Security\nSee [SECURITY.md](SECURITY.md).\n"},
+			Found:  true,
+			Linked: true,
+		},
+		{
+			Name:   "SecurityTab",
+			Files:  map[string]string{"README": "Report at https://github.com/org/repo/security/policy\n"},
+			Found:  true,
+			Linked: true,
+		},
+		{
+			Name:  "NotLinked",
+			Files: map[string]string{"README.md": "# Project\nA thing.\n"},
+			Found: true,
+		},
+		{
+			Name:  "NoReadme",
+			Files: map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			getContents = contentsMock(test.Files)
+			found, linked, err := checkReadmeLink(context.Background(), mockRepos{}, "org", "repo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if found != test.Found || linked != test.Linked {
+				t.Errorf("Unexpected results. Expected: %v %v Got: %v %v",
+					test.Found, test.Linked, found, linked)
+			}
+		})
+	}
+}
+
+func TestFindContactChannels(t *testing.T) {
+	content := `# Security Policy
+
+Email security@example.com, encrypted with our PGP key at
+https://keys.openpgp.org/search?q=security@example.com.
+
+You can also use https://github.com/org/repo/security/advisories/new.
+`
+	got := findContactChannels(content)
+	want := []string{channelEmail, channelPrivateReporting, channelPGP}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+	}
+	if n := countAccepted(got, nil); n != 3 {
+		t.Errorf("Expected all channels accepted, got: %v", n)
+	}
+	if n := countAccepted(got, []string{channelEmail, channelBugBounty}); n != 1 {
+		t.Errorf("Expected 1 accepted channel, got: %v", n)
+	}
+	if got := findContactChannels("See https://hackerone.com/org"); !cmp.Equal(got, []string{channelBugBounty}) {
+		t.Errorf("Expected bug bounty channel, got: %v", got)
+	}
+}
+
+func TestEncodingProblem(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Content string
+		Exp     string
+	}{
+		{Name: "Text", Content: "# Security\nEmail security@example.com, merci à vous.\n", Exp: ""},
+		{Name: "UTF16", Content: "\xff\xfe#\x00 \x00", Exp: "UTF-16 encoded"},
+		{Name: "Binary", Content: "\x89PNG\r\n\x1a\n\x00\x00", Exp: "binary content"},
+		{Name: "Invalid", Content: "Email \xe9t\xe9@example.com", Exp: "invalid UTF-8"},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if got := encodingProblem(test.Content); got != test.Exp {
+				t.Errorf("Unexpected problem. Expected: %q Got: %q", test.Exp, got)
+			}
+		})
+	}
+}
+
+func TestFindTimeline(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Content  string
+		Patterns []string
+		Exp      string
+		Err      bool
+	}{
+		{
+			Name:    "Respond",
+			Content: "# Security\n\nEmail security@example.com.\nWe aim to respond within 48 hours.\n",
+			Exp:     "We aim to respond within 48 hours.",
+		},
+		{
+			Name:    "Disclosure",
+			Content: "We follow a 90-day disclosure policy.",
+			Exp:     "We follow a 90-day disclosure policy.",
+		},
+		{
+			Name:    "BusinessDays",
+			Content: "Reports are acknowledged in two business days.",
+			Exp:     "Reports are acknowledged in two business days.",
+		},
+		{
+			Name:    "NoCommitment",
+			Content: "Email security@example.com and we will respond.\nThis project is 3 years old.",
+			Exp:     "",
+		},
+		{
+			Name:     "CustomPattern",
+			Content:  "We respond promptly, usually the same day.",
+			Patterns: []string{`same day`},
+			Exp:      "We respond promptly, usually the same day.",
+		},
+		{
+			Name:     "BadPattern",
+			Content:  "We respond within 2 days.",
+			Patterns: []string{`(`},
+			Err:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			got, err := findTimeline(test.Content, test.Patterns)
+			if (err != nil) != test.Err {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != test.Exp {
+				t.Errorf("Unexpected line. Expected: %q Got: %q", test.Exp, got)
+			}
+		})
+	}
+}
+
+func TestFindPlaceholder(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Content  string
+		Patterns []string
+		Exp      string
+		Err      bool
+	}{
+		{
+			Name:    "SetupText",
+			Content: "# Security Policy\n\nTo set up a security policy, click here.\n",
+			Exp:     "To set up a security policy, click here.",
+		},
+		{
+			Name:    "ChooserLink",
+			Content: "See [our policy](https://github.com/org/repo/security/policy/new).",
+			Exp:     "See [our policy](https://github.com/org/repo/security/policy/new).",
+		},
+		{
+			Name:    "Todo",
+			Content: "# Security\n\nTODO\n",
+			Exp:     "TODO",
+		},
+		{
+			Name:    "RealPolicy",
+			Content: "# Security\n\nTo set up a security policy, click here.\n\nEmail security@example.com to report a vulnerability.\n",
+			Exp:     "",
+		},
+		{
+			Name:    "HeadingsOnly",
+			Content: "# Security Policy\n\n## Reporting\n",
+			Exp:     "",
+		},
+		{
+			Name:     "CustomPattern",
+			Content:  "Generated by policybot. Edit me!",
+			Patterns: []string{`generated by policybot`},
+			Exp:      "Generated by policybot. Edit me!",
+		},
+		{
+			Name:     "BadPattern",
+			Content:  "TODO",
+			Patterns: []string{`(`},
+			Err:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			got, err := findPlaceholder(test.Content, test.Patterns)
+			if (err != nil) != test.Err {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != test.Exp {
+				t.Errorf("Unexpected line. Expected: %q Got: %q", test.Exp, got)
+			}
+		})
+	}
+}
+
+func TestCheckContactDomains(t *testing.T) {
+	content := `# Security
+
+Email security@example.com or [me](mailto:Jane.Doe@gmail.com).
+Report on [HackerOne](https://hackerone.com/example) or https://security.example.com/report.
+Or use https://github.com/ThisOrg/repo/security/advisories/new, see https://github.com/other/repo.
+`
+	got := checkContactDomains(content, "thisorg", []string{"example.com"})
+	want := []ContactDomain{
+		{Contact: "https://hackerone.com/example", Domain: "hackerone.com", Allowed: false},
+		{Contact: "https://security.example.com/report", Domain: "security.example.com", Allowed: true},
+		{Contact: "https://github.com/other/repo", Domain: "github.com", Allowed: false},
+		{Contact: "security@example.com", Domain: "example.com", Allowed: true},
+		{Contact: "Jane.Doe@gmail.com", Domain: "gmail.com", Allowed: false},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+	}
+	off := offDomainContacts(got)
+	if diff := cmp.Diff([]string{"https://hackerone.com/example", "https://github.com/other/repo", "Jane.Doe@gmail.com"}, off); diff != "" {
+		t.Errorf("Unexpected off domain contacts. (-want +got):\n%s", diff)
+	}
+}
+
+func TestDomainAllowed(t *testing.T) {
+	domains := []string{"example.com", "*.corp.example.org"}
+	tests := map[string]bool{
+		"example.com":          true,
+		"EXAMPLE.COM":          true,
+		"sec.example.com":      true,
+		"badexample.com":       false,
+		"example.com.evil.net": false,
+		"corp.example.org":     true,
+		"a.corp.example.org":   true,
+		"example.org":          false,
+	}
+	for host, exp := range tests {
+		if got := domainAllowed(host, domains); got != exp {
+			t.Errorf("Unexpected result for %v. Expected: %v Got: %v", host, exp, got)
+		}
+	}
+}
+
+func TestMissingContents(t *testing.T) {
+	content := `# Security
+
+Email Security@Example.com. We respond within 48 hours.
+`
+	patterns := []string{"security@example.com", `\d+ (hours|days)`, "hackerone", "[bug"}
+	got := missingContents(content, patterns)
+	if diff := cmp.Diff([]string{"hackerone", "[bug"}, got); diff != "" {
+		t.Errorf("Unexpected missing contents. (-want +got):\n%s", diff)
+	}
+	if got := contentLength("  \n# Sé\n\n"); got != 4 {
+		t.Errorf("Unexpected length. Expected: 4 Got: %v", got)
+	}
+}
+
+func TestCheckContentRequirements(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Required  []string
+		MinLength int
+		ExpReason string
+		ExpText   string
+	}{
+		{
+			Name:     "Present",
+			Required: []string{"security@example.com"},
+		},
+		{
+			Name:      "Missing",
+			Required:  []string{"security@example.com", "advisories/new", "48 hours"},
+			ExpReason: ReasonRequiredContents,
+			ExpText:   "Security policy SECURITY.md is missing required content:\n- advisories/new\n- 48 hours\n\n",
+		},
+		{
+			Name:      "TooShort",
+			MinLength: 100,
+			ExpReason: ReasonTooShort,
+			ExpText:   "Security policy SECURITY.md is 39 characters, shorter than the required 100.\n",
+		},
+		{
+			Name:      "LongEnough",
+			MinLength: 39,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner, repo, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					oc.RequiredContents = test.Required
+					oc.MinLength = test.MinLength
+				}
+				return nil
+			}
+			getContents = contentsMock(map[string]string{
+				"SECURITY.md": "# Security\n\nEmail security@example.com.",
+			})
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				qc, ok := q.(*struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				})
+				if !ok {
+					t.Fatalf("Query() called with unexpected query structure.")
+				}
+				qc.Repository.IsSecurityPolicyEnabled = true
+				qc.Repository.DefaultBranchRef.Name = "main"
+				return nil
+			}
+			res, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "org", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Pass != (test.ExpReason == "") {
+				t.Errorf("Unexpected pass: %v", res.Pass)
+			}
+			if test.ExpReason == "" {
+				return
+			}
+			if diff := cmp.Diff([]string{test.ExpReason}, res.Reasons); diff != "" {
+				t.Errorf("Unexpected reasons. (-want +got):\n%s", diff)
+			}
+			if !strings.HasPrefix(res.NotifyText, test.ExpText) {
+				t.Errorf("Unexpected notify text: %q", res.NotifyText)
+			}
+		})
+	}
+}
+
+func TestCheckPaths(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Paths   []string
+		Files   map[string]string
+		ExpPass bool
+		ExpPath string
+	}{
+		{
+			Name:  "NotConfigured",
+			Files: map[string]string{"docs/security/POLICY.md": "Email security@example.com"},
+		},
+		{
+			Name:    "Found",
+			Paths:   []string{"/SECURITY.txt", "docs/security/POLICY.md"},
+			Files:   map[string]string{"docs/security/POLICY.md": "Email security@example.com"},
+			ExpPass: true,
+			ExpPath: "docs/security/POLICY.md",
+		},
+		{
+			Name:  "Empty",
+			Paths: []string{"docs/security/POLICY.md"},
+			Files: map[string]string{"docs/security/POLICY.md": " \n"},
+		},
+		{
+			Name:  "NotFound",
+			Paths: []string{"docs/security/POLICY.md"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner, repo, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					oc.Paths = test.Paths
+				}
+				return nil
+			}
+			getContents = contentsMock(test.Files)
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				qc, ok := q.(*struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				})
+				if !ok {
+					t.Fatalf("Query() called with unexpected query structure.")
+				}
+				qc.Repository.DefaultBranchRef.Name = "main"
+				return nil
+			}
+			res, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "org", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Pass != test.ExpPass {
+				t.Errorf("Unexpected pass. Expected: %v Got: %v", test.ExpPass, res.Pass)
+			}
+			d := res.Details.(SecurityDetails)
+			if d.SatisfiedPath != test.ExpPath {
+				t.Errorf("Unexpected path. Expected: %v Got: %v", test.ExpPath, d.SatisfiedPath)
+			}
+			if test.ExpPass && d.Source != SourcePath {
+				t.Errorf("Unexpected source: %v", d.Source)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/runid"
+)
+
+var debugAPIResponses = operator.DebugAPIResponses
+
+var tokenRegex = regexp.MustCompile(`(?i)(token=)[^&"\s]+`)
+
+// redactTokens replaces token query parameters, such as in the download URLs
+// of private repo contents.
+func redactTokens(s string) string {
+	return tokenRegex.ReplaceAllString(s, "${1}REDACTED")
+}
+
+// debugResponse logs the raw API response v at debug level, if
+// operator.DebugAPIResponses is set.
+func debugResponse(ctx context.Context, owner, repo, api string, v interface{}) {
+	if !debugAPIResponses {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	runid.Logger(ctx).Debug().
+		Str("org", owner).
+		Str("repo", repo).
+		Str("area", polName).
+		Str("api", api).
+		RawJSON("response", []byte(redactJSON(ctx, owner, repo, redactTokens(string(b))))).
+		Msg("Raw GitHub API response.")
+}
@@ -0,0 +1,27 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"testing"
+)
+
+func TestRedactTokens(t *testing.T) {
+	in := `{"download_url":"https://raw.githubusercontent.com/org/repo/main/SECURITY.md?token=ABCD1234&x=1"}`
+	want := `{"download_url":"https://raw.githubusercontent.com/org/repo/main/SECURITY.md?token=REDACTED&x=1"}`
+	if got := redactTokens(in); got != want {
+		t.Errorf("Unexpected results. Expected: %v Got: %v", want, got)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ossf/allstar/pkg/config"
+
+	"github.com/google/go-github/v39/github"
+	"gopkg.in/yaml.v2"
+)
+
+// Origins of effective config values, see EffectiveConfig.
+const (
+	originDefault  = "default"
+	originOrg      = "org"
+	originRepo     = "repo override"
+	originRollout  = "org rollout stage"
+	originOperator = "operator"
+)
+
+// EffectiveConfig returns the SECURITY.md policy config that applies to the
+// repo, after merging org defaults, repo overrides, and operator restrictions,
+// as a YAML document in the format of the org-level config. Each option is
+// preceded by a comment noting where its value came from. It is intended for
+// troubleshooting config.
+func EffectiveConfig(ctx context.Context, c *github.Client, owner, repo string) ([]byte, error) {
+	oc, rc := getConfig(ctx, c, owner, repo)
+	mc := mergeConfig(ctx, oc, rc, repo)
+	return effectiveYAML(owner, repo, oc, rc, mc)
+}
+
+func effectiveYAML(owner, repo string, oc *OrgConfig, rc *RepoConfig, mc *mergedConfig) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Effective %v config for %v/%v.\n", polName, owner, repo)
+	allowed := allowedOptions()
+	ov := reflect.ValueOf(oc).Elem()
+	dv := reflect.ValueOf(defaultOrgConfig()).Elem()
+	rv := reflect.ValueOf(rc).Elem()
+	mv := reflect.ValueOf(mc).Elem()
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlName(f)
+		v := ov.Field(i).Interface()
+		if m := mv.FieldByName(f.Name); m.IsValid() {
+			v = m.Interface()
+		}
+		var origin string
+		switch {
+		case allowed != nil && !contains(alwaysAllowedOptions, name) && !contains(allowed, name):
+			origin = originOperator + ", option not permitted"
+		case !reflect.DeepEqual(v, ov.Field(i).Interface()):
+			if r := rv.FieldByName(f.Name); r.IsValid() && !r.IsZero() {
+				origin = originRepo
+			} else {
+				origin = originRollout
+			}
+		case reflect.DeepEqual(v, dv.Field(i).Interface()):
+			origin = originDefault
+		default:
+			origin = originOrg
+		}
+		if name == "action" && v != "log" && config.IsReadOnlyOrg(owner) {
+			v = "log"
+			origin = originOperator + ", read-only org"
+		}
+		y, err := yaml.Marshal(map[string]interface{}{name: v})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "# origin: %v\n%s", origin, y)
+	}
+	return []byte(b.String()), nil
+}
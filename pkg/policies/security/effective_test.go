@@ -0,0 +1,89 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"gopkg.in/yaml.v2"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	defer func() { allowedOptions = func() []string { return nil } }()
+	oc := defaultOrgConfig()
+	oc.Action = "issue"
+	oc.FixMode = "commit"
+	oc.AcceptSecurityTxt = "https://example.com/.well-known/security.txt"
+	days := 7
+	rc := &RepoConfig{RenotifyIntervalDays: &days}
+	ctx := context.Background()
+
+	origins := func(owner string) (*OrgConfig, map[string]string) {
+		mc := mergeConfig(ctx, oc, rc, "thisrepo")
+		out, err := effectiveYAML(owner, "thisrepo", oc, rc, mc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got := &OrgConfig{}
+		if err := yaml.UnmarshalStrict(out, got); err != nil {
+			t.Fatalf("Output is not a valid org config: %v\n%s", err, out)
+		}
+		m := make(map[string]string)
+		var origin string
+		for _, l := range strings.Split(string(out), "\n") {
+			if strings.HasPrefix(l, "# origin: ") {
+				origin = strings.TrimPrefix(l, "# origin: ")
+			} else if i := strings.Index(l, ":"); i > 0 && origin != "" {
+				m[l[:i]] = origin
+				origin = ""
+			}
+		}
+		return got, m
+	}
+
+	got, m := origins("thisorg")
+	if got.Action != "issue" || got.FixMode != "commit" || got.RenotifyIntervalDays != 7 {
+		t.Errorf("Unexpected effective values: %+v", got)
+	}
+	want := map[string]string{
+		"action":               originOrg,
+		"fixMode":              originOrg,
+		"renotifyIntervalDays": originRepo,
+		"minStarsForIssue":     originDefault,
+	}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("Unexpected origin for %v, got: %q want: %q", k, m[k], v)
+		}
+	}
+
+	allowedOptions = func() []string { return []string{"fixMode"} }
+	operator.ReadOnlyOrgs = []string{"thisorg"}
+	defer func() { operator.ReadOnlyOrgs = nil }()
+	_, m = origins("thisorg")
+	if !strings.HasPrefix(m["acceptSecurityTxt"], originOperator) {
+		t.Errorf("Expected operator origin for acceptSecurityTxt, got: %q", m["acceptSecurityTxt"])
+	}
+	if m["fixMode"] != originOrg {
+		t.Errorf("Expected org origin for fixMode, got: %q", m["fixMode"])
+	}
+	got, m = origins("thisorg")
+	if got.Action != "log" || !strings.HasPrefix(m["action"], originOperator) {
+		t.Errorf("Expected log action from operator for read-only org, got: %q %q", got.Action, m["action"])
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// Evidence identifies the security policy file that satisfied the policy, see
+// OrgConfig.CaptureEvidence.
+type Evidence struct {
+	// Path is the path of the security policy file in the repo.
+	Path string
+
+	// SHA is the git blob SHA of the file.
+	SHA string
+
+	// ContentSHA256 is the hex encoded SHA-256 of the file's content.
+	ContentSHA256 string
+
+	// URL is the web URL of the file.
+	URL string
+
+	// Ref is the commit SHA or ref the file was read at, empty for the default
+	// branch.
+	Ref string
+}
+
+// captureEvidence adds Evidence to the details of a passing result satisfied by
+// a security policy file in the repo. Errors are logged, and the result is left
+// without evidence.
+func captureEvidence(ctx context.Context, rep repositories, owner, repo string, res *policydef.Result) {
+	d, ok := res.Details.(SecurityDetails)
+	if !ok || d.Source != SourceRepoFile {
+		return
+	}
+	e, err := getEvidence(ctx, rep, owner, repo, checkRef(ctx))
+	if err != nil {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Err(err).
+			Msg("Unable to capture security policy evidence.")
+		return
+	}
+	d.Evidence = e
+	res.Details = d
+}
+
+func getEvidence(ctx context.Context, rep repositories, owner, repo, ref string) (*Evidence, error) {
+	var opts *github.RepositoryContentGetOptions
+	if ref != "" {
+		opts = &github.RepositoryContentGetOptions{Ref: ref}
+	}
+	for _, p := range policyPaths {
+		fc, _, rsp, err := rep.GetContents(ctx, owner, repo, p, opts)
+		if err != nil {
+			if rsp != nil && rsp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if fc == nil || (fc.GetType() != "" && fc.GetType() != "file") {
+			continue
+		}
+		con, err := fc.GetContent()
+		if err != nil {
+			return nil, err
+		}
+		return &Evidence{
+			Path:          p,
+			SHA:           fc.GetSHA(),
+			ContentSHA256: fmt.Sprintf("%x", sha256.Sum256([]byte(con))),
+			URL:           fc.GetHTMLURL(),
+			Ref:           ref,
+		}, nil
+	}
+	return nil, errors.New("security policy file not found")
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func TestCaptureEvidence(t *testing.T) {
+	getContents = func(ctx context.Context, owner, repo, path string,
+		opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+		[]*github.RepositoryContent, *github.Response, error) {
+		if path != ".github/SECURITY.md" {
+			return contentsMock(nil)(ctx, owner, repo, path, opts)
+		}
+		e := "base64"
+		c := base64.StdEncoding.EncodeToString([]byte("Email security@example.com"))
+		sha := "abc123"
+		u := "https://github.com/org/repo/blob/main/.github/SECURITY.md"
+		return &github.RepositoryContent{
+			Encoding: &e,
+			Content:  &c,
+			SHA:      &sha,
+			HTMLURL:  &u,
+		}, nil, nil, nil
+	}
+	res := &policydef.Result{Enabled: true, Pass: true, Details: SecurityDetails{Source: SourceRepoFile}}
+	captureEvidence(context.Background(), mockRepos{}, "org", "repo", res)
+	want := &Evidence{
+		Path:          ".github/SECURITY.md",
+		SHA:           "abc123",
+		ContentSHA256: "db0d68d3ebcc2384f98dc4bd6cf66820e3b078a9d07b54b57ed0bacc1a96309e",
+		URL:           "https://github.com/org/repo/blob/main/.github/SECURITY.md",
+	}
+	if diff := cmp.Diff(want, res.Details.(SecurityDetails).Evidence); diff != "" {
+		t.Errorf("Unexpected evidence. (-want +got):\n%s", diff)
+	}
+
+	res = &policydef.Result{Enabled: true, Pass: true, Details: SecurityDetails{Source: SourceOrgDefault}}
+	captureEvidence(context.Background(), mockRepos{}, "org", "repo", res)
+	if res.Details.(SecurityDetails).Evidence != nil {
+		t.Error("Expected no evidence for an org default policy.")
+	}
+}
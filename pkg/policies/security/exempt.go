@@ -0,0 +1,38 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+
+	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/runid"
+)
+
+// findExemption returns the exemption in the optConfig of oc matching repo, or
+// nil if none match. An invalid exemption, such as one missing a reason, is
+// logged and ignored.
+func findExemption(ctx context.Context, oc *OrgConfig, owner, repo string) *config.Exemption {
+	e, err := config.FindExemption(oc.OptConfig, repo)
+	if err != nil {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Err(err).
+			Msg("Ignoring invalid exemption.")
+	}
+	return e
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func TestFindExemption(t *testing.T) {
+	oc := &OrgConfig{
+		OptConfig: config.OrgOptConfig{
+			Exemptions: []config.Exemption{
+				{Repo: "template-*", Reason: "template", ApprovedBy: "secteam"},
+				{Repo: "[bad", Reason: "invalid", ApprovedBy: "secteam"},
+				{Repo: "legacy", Reason: "archived", ApprovedBy: "secteam"},
+				{Repo: "noreason", ApprovedBy: "secteam"},
+				{Repo: "mirror-*", ApprovedBy: "secteam"},
+				{Repo: "mirror-docs", Reason: "mirror", ApprovedBy: "secteam"},
+			},
+		},
+	}
+	tests := map[string]string{
+		"template-go": "template",
+		"legacy":      "archived",
+		"legacy-2":    "",
+		"noreason":    "",
+		"mirror-docs": "mirror",
+		"mirror-go":   "",
+		"thisrepo":    "",
+	}
+	for repo, exp := range tests {
+		got := ""
+		if e := findExemption(context.Background(), oc, "org", repo); e != nil {
+			got = e.Reason
+		}
+		if got != exp {
+			t.Errorf("Unexpected exemption for %v. Expected: %q Got: %q", repo, exp, got)
+		}
+	}
+}
+
+func TestCheckExempt(t *testing.T) {
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner, repo, path string, out interface{}) error {
+		if oc, ok := out.(*OrgConfig); ok {
+			oc.OptConfig.Exemptions = []config.Exemption{
+				{Repo: "template-*", Reason: "repo template", ApprovedBy: "secteam"},
+			}
+		}
+		return nil
+	}
+	getContents = contentsMock(nil)
+	queried := false
+	query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+		queried = true
+		return nil
+	}
+	res, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "org", "template-go")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := &policydef.Result{
+		Enabled:    false,
+		Pass:       true,
+		NotifyText: "",
+		Details: SecurityDetails{
+			Skipped: "exempt",
+			Exempt:  "repo template",
+			Source:  SourceSkipped,
+		},
+	}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+	}
+	if queried {
+		t.Error("Expected no query for an exempt repo")
+	}
+}
@@ -0,0 +1,518 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// Values of FixMode.
+const (
+	fixModePR     = "pr"
+	fixModeCommit = "commit"
+)
+
+// fixPath is where the fix action adds the security policy.
+const fixPath = "SECURITY.md"
+
+// fixBranch is the branch the fix action pushes to when opening a PR.
+const fixBranch = "allstar/security-policy"
+
+const defaultCommitMessage = `Add SECURITY.md security policy
+
+This file was added by Allstar to bring {{.Owner}}/{{.Repo}} into compliance with the SECURITY.md policy. See https://github.com/ossf/allstar/ for more information.`
+
+const defaultPRTitle = "Add SECURITY.md security policy"
+
+const defaultPRBody = `Allstar has detected that this repository does not have a security policy. This pull request adds a default {{.Path}}.
+
+Please review the contents, and update it with how vulnerabilities in this repository should be reported, before merging.
+
+For more information, see https://docs.github.com/en/code-security/getting-started/adding-a-security-policy-to-your-repository.`
+
+const defaultSecurityMD = `# Security Policy
+
+## Reporting a Vulnerability
+
+Please do not report security vulnerabilities through public GitHub issues.
+
+Instead, report them privately at https://github.com/{{.Owner}}/{{.Repo}}/security/advisories/new, or contact the maintainers of this repository privately. Include as much information as you can, such as the affected versions, steps to reproduce, and the impact of the issue. You will receive a response acknowledging your report, and updates as it is investigated.
+`
+
+type fixRepositories interface {
+	repositories
+	Get(context.Context, string, string) (*github.Repository,
+		*github.Response, error)
+	ListReleases(context.Context, string, string, *github.ListOptions) (
+		[]*github.RepositoryRelease, *github.Response, error)
+	ListBranches(context.Context, string, string, *github.BranchListOptions) (
+		[]*github.Branch, *github.Response, error)
+	CreateFile(context.Context, string, string, string,
+		*github.RepositoryContentFileOptions) (*github.RepositoryContentResponse,
+		*github.Response, error)
+	CreateComment(context.Context, string, string, string,
+		*github.RepositoryComment) (*github.RepositoryComment, *github.Response, error)
+}
+
+type fixGit interface {
+	GetRef(context.Context, string, string, string) (*github.Reference,
+		*github.Response, error)
+	CreateRef(context.Context, string, string, *github.Reference) (
+		*github.Reference, *github.Response, error)
+}
+
+type pullLister interface {
+	List(context.Context, string, string, *github.PullRequestListOptions) (
+		[]*github.PullRequest, *github.Response, error)
+}
+
+type fixPulls interface {
+	pullLister
+	Create(context.Context, string, string, *github.NewPullRequest) (
+		*github.PullRequest, *github.Response, error)
+}
+
+type fixIssues interface {
+	CreateComment(context.Context, string, string, int, *github.IssueComment) (
+		*github.IssueComment, *github.Response, error)
+}
+
+// fixClients holds the GitHub services used by the fix action, to allow
+// mocking.
+type fixClients struct {
+	repos  fixRepositories
+	git    fixGit
+	pulls  fixPulls
+	issues fixIssues
+}
+
+type templateData struct {
+	Owner string
+	Repo  string
+	Path  string
+
+	// The following are only set when rendering SecurityTemplate.
+	DefaultBranch string
+	Releases      []string
+	Branches      []string
+
+	// The following are only set when rendering notify text. Org is the same
+	// as Owner.
+	Org       string
+	PolicyURL string
+}
+
+func renderTemplate(name, text string, data templateData) (string, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// FixPlan is the set of changes Fix makes to a repo, computed without side
+// effects so that they can be reviewed before they are applied. See Plan.
+type FixPlan struct {
+	// Reasons are the reasons the policy is failing. If the policy is passing or
+	// not enabled, the plan is empty. If none of the reasons can be fixed by
+	// adding SECURITY.md, only Reasons is set.
+	Reasons []string
+
+	// Mode is how the fix is applied, "pr" or "commit".
+	Mode string
+
+	// Base is the default branch of the repo.
+	Base string
+
+	// Branch is the branch the file is committed to. In "pr" mode, it is
+	// created from Base.
+	Branch string
+
+	// Path and Content are the file to be written.
+	Path    string
+	Content string
+
+	// CommitMessage, AuthorName, and AuthorEmail describe the commit. The
+	// author is empty if the App commits as itself.
+	CommitMessage string
+	AuthorName    string
+	AuthorEmail   string
+
+	// PRTitle and PRBody describe the pull request, in "pr" mode.
+	PRTitle string
+	PRBody  string
+
+	// AuditComment is posted on the commit or pull request, if not empty.
+	AuditComment string
+
+	// Actions describe each change to be made, in order.
+	Actions []string
+}
+
+func newFixClients(c *github.Client) fixClients {
+	return fixClients{
+		repos:  c.Repositories,
+		git:    c.Git,
+		pulls:  c.PullRequests,
+		issues: c.Issues,
+	}
+}
+
+func plan(ctx context.Context, fc fixClients, c *github.Client, owner, repo string) (*FixPlan, error) {
+	r, err := securityCheck(ctx, c, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	if !r.Enabled || r.Pass {
+		return &FixPlan{}, nil
+	}
+	if !fixable(r.Reasons) {
+		return &FixPlan{Reasons: r.Reasons}, nil
+	}
+	p, err := planFix(ctx, fc, resolveConfig(ctx, c, owner, repo), owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	p.Reasons = r.Reasons
+	return p, nil
+}
+
+// planFix computes the fix plan for the repo, with the mode from config, or
+// the action option, default fixModePR.
+func planFix(ctx context.Context, fc fixClients, mc *mergedConfig, owner, repo string) (*FixPlan, error) {
+	r, _, err := fc.repos.Get(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	return planMode(ctx, fc, mc, owner, repo, r.GetDefaultBranch(), fixMode(ctx, mc))
+}
+
+// fixMode returns the mode from config, or the action option, default
+// fixModePR.
+func fixMode(ctx context.Context, mc *mergedConfig) string {
+	mode := mc.FixMode
+	if mode == "" {
+		mode = policydef.ActionOption(ctx)
+	}
+	if mode == "" {
+		mode = fixModePR
+	}
+	return mode
+}
+
+// planMode computes the fix plan for the repo in the given mode.
+func planMode(ctx context.Context, fc fixClients, mc *mergedConfig, owner, repo, base, mode string) (*FixPlan, error) {
+	data := templateData{
+		Owner: owner,
+		Repo:  repo,
+		Path:  fixPath,
+	}
+	p := &FixPlan{
+		Mode: mode,
+		Base: base,
+		Path: fixPath,
+	}
+	var err error
+	if mc.SecurityTemplate != "" {
+		p.Content, err = renderSecurityTemplate(ctx, fc.repos, mc.SecurityTemplate, data, base)
+	} else {
+		p.Content, err = renderTemplate("securityTemplate", defaultSecurityMD, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case fixModeCommit:
+		p.Branch = base
+	case fixModePR:
+		p.Branch = fixBranch
+		p.PRTitle, err = renderTemplate("prTitle", mc.PRTitle, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prTitle: %w", err)
+		}
+		p.PRBody, err = renderTemplate("prBody", mc.PRBody, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prBody: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown fixMode %q", mode)
+	}
+	msg, err := renderTemplate("commitMessage", mc.CommitMessage, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commitMessage: %w", err)
+	}
+	if mc.CommitAuthorName != "" || mc.CommitAuthorEmail != "" {
+		if mc.CommitAuthorName == "" || mc.CommitAuthorEmail == "" {
+			return nil, errors.New("both commitAuthorName and commitAuthorEmail must be set")
+		}
+		p.AuthorName = mc.CommitAuthorName
+		p.AuthorEmail = mc.CommitAuthorEmail
+	}
+	if mc.SignOff {
+		if p.AuthorName == "" {
+			return nil, errors.New("signOff requires commitAuthorName and commitAuthorEmail")
+		}
+		msg = fmt.Sprintf("%v\n\nSigned-off-by: %v <%v>", strings.TrimRight(msg, "\n"),
+			mc.CommitAuthorName, mc.CommitAuthorEmail)
+	}
+	p.CommitMessage = msg
+	if mc.AuditComment != "" {
+		// As with posting it, a bad audit comment doesn't stop the fix.
+		p.AuditComment, err = renderTemplate("auditComment", mc.AuditComment, data)
+		if err != nil {
+			runid.Logger(ctx).Warn().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Err(err).
+				Msg("Unable to add fix audit comment.")
+		}
+	}
+	if mode == fixModePR {
+		p.Actions = append(p.Actions, fmt.Sprintf("create branch %v from %v", p.Branch, base))
+	}
+	p.Actions = append(p.Actions, fmt.Sprintf("create file %v on branch %v", p.Path, p.Branch))
+	target := "commit"
+	if mode == fixModePR {
+		p.Actions = append(p.Actions, fmt.Sprintf("open pull request %q from %v into %v", p.PRTitle, p.Branch, base))
+		target = "pull request"
+	}
+	if p.AuditComment != "" {
+		p.Actions = append(p.Actions, "comment on the "+target)
+	}
+	return p, nil
+}
+
+// errReadOnlyOrg is returned when fixing a repo of an org in
+// operator.ReadOnlyOrgs.
+var errReadOnlyOrg = errors.New("org is read-only, not fixing")
+
+// fixable reports whether reasons include one that adding SECURITY.md fixes.
+// Other reasons are about an existing policy, which the fix action doesn't
+// edit.
+func fixable(reasons []string) bool {
+	for _, r := range reasons {
+		if r == ReasonMissing {
+			return true
+		}
+	}
+	return false
+}
+
+func fix(ctx context.Context, fc fixClients, c *github.Client, owner, repo string) error {
+	if config.IsReadOnlyOrg(owner) {
+		return errReadOnlyOrg
+	}
+	if reasons := policydef.Reasons(ctx); reasons != nil && !fixable(reasons) {
+		runid.Logger(ctx).Info().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Strs("reasons", reasons).
+			Msg("Security policy exists but fails, not fixable by adding SECURITY.md.")
+		return nil
+	}
+	mc := resolveConfig(ctx, c, owner, repo)
+	p, err := planFix(ctx, fc, mc, owner, repo)
+	if err != nil {
+		return err
+	}
+	if mc.DryRun {
+		runid.Logger(ctx).Info().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("mode", p.Mode).
+			Str("branch", p.Branch).
+			Str("path", p.Path).
+			Str("commitMessage", p.CommitMessage).
+			Str("title", p.PRTitle).
+			Str("body", p.PRBody).
+			Strs("actions", p.Actions).
+			Msg("Dry run, not applying fix.")
+		return nil
+	}
+	_, err = applyFix(ctx, fc, owner, repo, p)
+	return err
+}
+
+// fixPending returns whether fix would change the repo. It does not in a
+// read-only org, in a dry run, if the reasons are not fixable, or if a fix pull
+// request is already open.
+func fixPending(ctx context.Context, fc fixClients, c *github.Client, owner, repo string) (bool, error) {
+	if config.IsReadOnlyOrg(owner) {
+		return false, nil
+	}
+	if reasons := policydef.Reasons(ctx); reasons != nil && !fixable(reasons) {
+		return false, nil
+	}
+	mc := resolveConfig(ctx, c, owner, repo)
+	if mc.DryRun {
+		return false, nil
+	}
+	if fixMode(ctx, mc) != fixModePR {
+		return true, nil
+	}
+	open, err := findOpenFixPR(ctx, fc.pulls, owner, repo)
+	if err != nil {
+		return false, err
+	}
+	return open == nil, nil
+}
+
+// applyFix makes the changes of the plan, returning the pull request in "pr"
+// mode.
+func applyFix(ctx context.Context, fc fixClients, owner, repo string, p *FixPlan) (*github.PullRequest, error) {
+	opts := &github.RepositoryContentFileOptions{
+		Message: github.String(p.CommitMessage),
+		Content: []byte(p.Content),
+		Branch:  github.String(p.Branch),
+	}
+	if p.AuthorName != "" {
+		opts.Author = &github.CommitAuthor{
+			Name:  github.String(p.AuthorName),
+			Email: github.String(p.AuthorEmail),
+		}
+	}
+	if p.Mode == fixModeCommit {
+		cr, _, err := fc.repos.CreateFile(ctx, owner, repo, p.Path, opts)
+		if err != nil {
+			return nil, err
+		}
+		runid.Logger(ctx).Info().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("branch", p.Branch).
+			Msg("Fix committed security policy.")
+		auditComment(ctx, owner, repo, p, func(body string) error {
+			_, _, err := fc.repos.CreateComment(ctx, owner, repo, cr.Commit.GetSHA(),
+				&github.RepositoryComment{Body: &body})
+			return err
+		})
+		return nil, nil
+	}
+	open, err := findOpenFixPR(ctx, fc.pulls, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	if open != nil {
+		runid.Logger(ctx).Info().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Int("pr", open.GetNumber()).
+			Msg("Fix pull request already open, not opening another.")
+		return open, nil
+	}
+	exists, err := prepareFixBranch(ctx, fc, owner, repo, p)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if _, _, err := fc.repos.CreateFile(ctx, owner, repo, p.Path, opts); err != nil {
+			return nil, err
+		}
+	}
+	pr, _, err := fc.pulls.Create(ctx, owner, repo, &github.NewPullRequest{
+		Title: github.String(p.PRTitle),
+		Head:  github.String(p.Branch),
+		Base:  github.String(p.Base),
+		Body:  github.String(p.PRBody),
+	})
+	if err != nil {
+		return nil, err
+	}
+	runid.Logger(ctx).Info().
+		Str("org", owner).
+		Str("repo", repo).
+		Str("area", polName).
+		Int("pr", pr.GetNumber()).
+		Msg("Fix opened pull request with security policy.")
+	auditComment(ctx, owner, repo, p, func(body string) error {
+		_, _, err := fc.issues.CreateComment(ctx, owner, repo, pr.GetNumber(),
+			&github.IssueComment{Body: &body})
+		return err
+	})
+	return pr, nil
+}
+
+// findOpenFixPR returns the open pull request from the fix branch, if any.
+func findOpenFixPR(ctx context.Context, p pullLister, owner, repo string) (*github.PullRequest, error) {
+	prs, _, err := p.List(ctx, owner, repo, &github.PullRequestListOptions{
+		State:       "open",
+		Head:        owner + ":" + fixBranch,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil || len(prs) == 0 {
+		return nil, err
+	}
+	return prs[0], nil
+}
+
+// prepareFixBranch creates the plan's branch from its base, unless it is left
+// from an earlier fix. It returns true if the branch already has the security
+// policy file, so it must not be created again.
+func prepareFixBranch(ctx context.Context, fc fixClients, owner, repo string, p *FixPlan) (bool, error) {
+	_, rsp, err := fc.git.GetRef(ctx, owner, repo, "heads/"+p.Branch)
+	if err == nil {
+		f, _, _, err := getFirstEntryAt(ctx, fc.repos, owner, repo, p.Branch, []string{p.Path})
+		return f != "", err
+	}
+	if rsp == nil || rsp.StatusCode != http.StatusNotFound {
+		return false, err
+	}
+	ref, _, err := fc.git.GetRef(ctx, owner, repo, "heads/"+p.Base)
+	if err != nil {
+		return false, err
+	}
+	newRef := "refs/heads/" + p.Branch
+	_, _, err = fc.git.CreateRef(ctx, owner, repo, &github.Reference{
+		Ref:    &newRef,
+		Object: ref.Object,
+	})
+	return false, err
+}
+
+// auditComment posts the plan's AuditComment with post. As the fix has already
+// been applied, failures are logged rather than returned.
+func auditComment(ctx context.Context, owner, repo string, p *FixPlan, post func(string) error) {
+	if p.AuditComment == "" {
+		return
+	}
+	if err := post(p.AuditComment); err != nil {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Err(err).
+			Msg("Unable to add fix audit comment.")
+	}
+}
@@ -0,0 +1,578 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+type mockFix struct {
+	created       *github.RepositoryContentFileOptions
+	ref           *github.Reference
+	pr            *github.NewPullRequest
+	commitComment *github.RepositoryComment
+	openPR        *github.PullRequest
+	branchExists  bool
+	branchFile    bool
+}
+
+func (m *mockFix) Get(ctx context.Context, owner, repo string) (*github.Repository,
+	*github.Response, error) {
+	b := "main"
+	return &github.Repository{DefaultBranch: &b}, nil, nil
+}
+
+func (m *mockFix) ListReleases(ctx context.Context, owner, repo string,
+	opts *github.ListOptions) ([]*github.RepositoryRelease, *github.Response, error) {
+	return []*github.RepositoryRelease{
+		{TagName: github.String("v2.1.0")},
+		{TagName: github.String("v3.0.0-rc1"), Draft: github.Bool(true)},
+		{TagName: github.String("v2.0.0")},
+	}, nil, nil
+}
+
+func (m *mockFix) ListBranches(ctx context.Context, owner, repo string,
+	opts *github.BranchListOptions) ([]*github.Branch, *github.Response, error) {
+	return []*github.Branch{
+		{Name: github.String("main")},
+		{Name: github.String("release/2.1")},
+		{Name: github.String("release/2.0")},
+	}, nil, nil
+}
+
+func (m *mockFix) CreateFile(ctx context.Context, owner, repo, path string,
+	opts *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse,
+	*github.Response, error) {
+	m.created = opts
+	sha := "def456"
+	return &github.RepositoryContentResponse{Commit: github.Commit{SHA: &sha}}, nil, nil
+}
+
+func (m *mockFix) CreateComment(ctx context.Context, owner, repo, sha string,
+	comment *github.RepositoryComment) (*github.RepositoryComment, *github.Response, error) {
+	if sha != "def456" {
+		return nil, nil, fmt.Errorf("unexpected sha %v", sha)
+	}
+	m.commitComment = comment
+	return comment, nil, nil
+}
+
+type mockFixIssues struct {
+	number  int
+	comment *github.IssueComment
+}
+
+func (m *mockFixIssues) CreateComment(ctx context.Context, owner, repo string, number int,
+	comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	m.number = number
+	m.comment = comment
+	return comment, nil, nil
+}
+
+func (m *mockFix) GetContents(ctx context.Context, owner, repo, path string,
+	opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+	[]*github.RepositoryContent, *github.Response, error) {
+	if !m.branchFile || opts == nil || opts.Ref != fixBranch {
+		return nil, nil, &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+			errors.New("not found")
+	}
+	return &github.RepositoryContent{Content: github.String("# Security")}, nil, nil, nil
+}
+
+func (m *mockFix) GetRef(ctx context.Context, owner, repo, ref string) (*github.Reference,
+	*github.Response, error) {
+	if ref == "heads/"+fixBranch && !m.branchExists {
+		return nil, &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+			errors.New("not found")
+	}
+	sha := "abc123"
+	return &github.Reference{Object: &github.GitObject{SHA: &sha}}, nil, nil
+}
+
+func (m *mockFix) CreateRef(ctx context.Context, owner, repo string,
+	ref *github.Reference) (*github.Reference, *github.Response, error) {
+	m.ref = ref
+	return ref, nil, nil
+}
+
+func (m *mockFix) List(ctx context.Context, owner, repo string,
+	opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	if m.openPR == nil {
+		return nil, nil, nil
+	}
+	return []*github.PullRequest{m.openPR}, nil, nil
+}
+
+func (m *mockFix) Create(ctx context.Context, owner, repo string,
+	pr *github.NewPullRequest) (*github.PullRequest, *github.Response, error) {
+	m.pr = pr
+	return &github.PullRequest{Number: github.Int(7)}, nil, nil
+}
+
+func TestFix(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Org     OrgConfig
+		Err     bool
+		Branch  string
+		Message string
+		Author  string
+		PRTitle string
+	}{
+		{
+			Name:    "DefaultPR",
+			Org:     OrgConfig{},
+			Branch:  fixBranch,
+			Message: "Add SECURITY.md security policy\n\nThis file was added by Allstar to bring thisorg/thisrepo into compliance with the SECURITY.md policy. See https://github.com/ossf/allstar/ for more information.",
+			PRTitle: defaultPRTitle,
+		},
+		{
+			Name: "CommitSignOff",
+			Org: OrgConfig{
+				FixMode:           fixModeCommit,
+				CommitMessage:     "Add {{.Path}} to {{.Repo}}",
+				CommitAuthorName:  "Sec Team",
+				CommitAuthorEmail: "sec@example.com",
+				SignOff:           true,
+			},
+			Branch:  "main",
+			Message: "Add SECURITY.md to thisrepo\n\nSigned-off-by: Sec Team <sec@example.com>",
+			Author:  "Sec Team",
+		},
+		{
+			Name: "CustomPR",
+			Org: OrgConfig{
+				PRTitle: "[security] {{.Owner}}/{{.Repo}}",
+			},
+			Branch:  fixBranch,
+			Message: "Add SECURITY.md security policy\n\nThis file was added by Allstar to bring thisorg/thisrepo into compliance with the SECURITY.md policy. See https://github.com/ossf/allstar/ for more information.",
+			PRTitle: "[security] thisorg/thisrepo",
+		},
+		{
+			Name: "SignOffNoAuthor",
+			Org: OrgConfig{
+				SignOff: true,
+			},
+			Err: true,
+		},
+		{
+			Name: "PartialAuthor",
+			Org: OrgConfig{
+				CommitAuthorName: "Sec Team",
+			},
+			Err: true,
+		},
+		{
+			Name: "BadTemplate",
+			Org: OrgConfig{
+				CommitMessage: "{{.Missing",
+			},
+			Err: true,
+		},
+		{
+			Name: "BadMode",
+			Org: OrgConfig{
+				FixMode: "push",
+			},
+			Err: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner, repo, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					if test.Org.FixMode != "" {
+						oc.FixMode = test.Org.FixMode
+					}
+					if test.Org.CommitMessage != "" {
+						oc.CommitMessage = test.Org.CommitMessage
+					}
+					if test.Org.PRTitle != "" {
+						oc.PRTitle = test.Org.PRTitle
+					}
+					oc.CommitAuthorName = test.Org.CommitAuthorName
+					oc.CommitAuthorEmail = test.Org.CommitAuthorEmail
+					oc.SignOff = test.Org.SignOff
+				}
+				return nil
+			}
+			m := &mockFix{}
+			err := fix(context.Background(), fixClients{repos: m, git: m, pulls: m, issues: &mockFixIssues{}},
+				nil, "thisorg", "thisrepo")
+			if test.Err {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if m.created == nil {
+				t.Fatal("Expected file to be created")
+			}
+			if got := m.created.GetBranch(); got != test.Branch {
+				t.Errorf("Unexpected branch. Expected: %v Got: %v", test.Branch, got)
+			}
+			if got := m.created.GetMessage(); got != test.Message {
+				t.Errorf("Unexpected message. Expected: %q Got: %q", test.Message, got)
+			}
+			if got := m.created.GetAuthor().GetName(); got != test.Author {
+				t.Errorf("Unexpected author. Expected: %v Got: %v", test.Author, got)
+			}
+			if test.PRTitle == "" {
+				if m.pr != nil {
+					t.Error("Unexpected pull request")
+				}
+				return
+			}
+			if m.pr == nil {
+				t.Fatal("Expected pull request")
+			}
+			if got := m.pr.GetTitle(); got != test.PRTitle {
+				t.Errorf("Unexpected PR title. Expected: %v Got: %v", test.PRTitle, got)
+			}
+			if got := m.ref.GetRef(); got != "refs/heads/"+fixBranch {
+				t.Errorf("Unexpected ref: %v", got)
+			}
+		})
+	}
+}
+
+func TestFixAuditComment(t *testing.T) {
+	for _, mode := range []string{fixModeCommit, fixModePR} {
+		t.Run(mode, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner, repo, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					oc.FixMode = mode
+					oc.AuditComment = "Added by Allstar to {{.Repo}}, see https://example.com/policy"
+				}
+				return nil
+			}
+			m := &mockFix{}
+			mi := &mockFixIssues{}
+			err := fix(context.Background(), fixClients{repos: m, git: m, pulls: m, issues: mi},
+				nil, "thisorg", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			want := "Added by Allstar to thisrepo, see https://example.com/policy"
+			var got string
+			if mode == fixModeCommit {
+				got = m.commitComment.GetBody()
+			} else {
+				got = mi.comment.GetBody()
+				if mi.number != 7 {
+					t.Errorf("Unexpected PR number: %v", mi.number)
+				}
+			}
+			if got != want {
+				t.Errorf("Unexpected comment. Expected: %q Got: %q", want, got)
+			}
+		})
+	}
+}
+
+func TestFixActionOption(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Mode   string
+		Option string
+		Branch string
+	}{
+		{Name: "Default", Branch: fixBranch},
+		{Name: "Option", Option: fixModeCommit, Branch: "main"},
+		{Name: "OrgOverride", Mode: fixModePR, Option: fixModeCommit, Branch: fixBranch},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner, repo, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					oc.FixMode = test.Mode
+				}
+				return nil
+			}
+			m := &mockFix{}
+			ctx := policydef.WithActionOption(context.Background(), test.Option)
+			err := fix(ctx, fixClients{repos: m, git: m, pulls: m, issues: &mockFixIssues{}},
+				nil, "thisorg", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := m.created.GetBranch(); got != test.Branch {
+				t.Errorf("Unexpected branch. Expected: %v Got: %v", test.Branch, got)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner, repo, path string, out interface{}) error {
+		if oc, ok := out.(*OrgConfig); ok {
+			oc.AuditComment = "Added by Allstar"
+		}
+		return nil
+	}
+	pass := false
+	securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+		return &policydef.Result{Enabled: true, Pass: pass, Reasons: []string{ReasonMissing}}, nil
+	}
+	m := &mockFix{}
+	fc := fixClients{repos: m, git: m, pulls: m, issues: &mockFixIssues{}}
+	got, err := plan(context.Background(), fc, nil, "thisorg", "thisrepo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := &FixPlan{
+		Reasons:       []string{ReasonMissing},
+		Mode:          fixModePR,
+		Base:          "main",
+		Branch:        fixBranch,
+		Path:          fixPath,
+		Content:       strings.Replace(strings.Replace(defaultSecurityMD, "{{.Owner}}", "thisorg", 1), "{{.Repo}}", "thisrepo", 1),
+		CommitMessage: "Add SECURITY.md security policy\n\nThis file was added by Allstar to bring thisorg/thisrepo into compliance with the SECURITY.md policy. See https://github.com/ossf/allstar/ for more information.",
+		PRTitle:       defaultPRTitle,
+		PRBody:        strings.Replace(defaultPRBody, "{{.Path}}", fixPath, 1),
+		AuditComment:  "Added by Allstar",
+		Actions: []string{
+			"create branch allstar/security-policy from main",
+			"create file SECURITY.md on branch allstar/security-policy",
+			`open pull request "Add SECURITY.md security policy" from allstar/security-policy into main`,
+			"comment on the pull request",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected plan. (-want +got):\n%s", diff)
+	}
+	if m.created != nil || m.ref != nil || m.pr != nil {
+		t.Errorf("Expected no changes from plan")
+	}
+
+	pass = true
+	got, err = plan(context.Background(), fc, nil, "thisorg", "thisrepo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&FixPlan{}, got); diff != "" {
+		t.Errorf("Expected empty plan when passing. (-want +got):\n%s", diff)
+	}
+}
+
+func TestFixExistingPolicy(t *testing.T) {
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner, repo, path string, out interface{}) error {
+		return nil
+	}
+	securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+		return &policydef.Result{Enabled: true, Pass: false, Reasons: []string{ReasonPlaceholder}}, nil
+	}
+	m := &mockFix{}
+	fc := fixClients{repos: m, git: m, pulls: m, issues: &mockFixIssues{}}
+	ctx := policydef.WithReasons(context.Background(), []string{ReasonPlaceholder})
+	for _, mode := range []string{fixModeCommit, fixModePR} {
+		if err := fix(policydef.WithActionOption(ctx, mode), fc, nil, "thisorg", "thisrepo"); err != nil {
+			t.Errorf("Unexpected error in %v mode: %v", mode, err)
+		}
+	}
+	if m.created != nil || m.ref != nil || m.pr != nil {
+		t.Errorf("Expected no changes when SECURITY.md exists")
+	}
+	got, err := plan(context.Background(), fc, nil, "thisorg", "thisrepo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(&FixPlan{Reasons: []string{ReasonPlaceholder}}, got); diff != "" {
+		t.Errorf("Expected no actions for existing policy. (-want +got):\n%s", diff)
+	}
+}
+
+func TestFixSecurityTemplate(t *testing.T) {
+	tmpl := `# {{.Owner}}/{{.Repo}} Security Policy
+
+## Supported Versions
+{{range match "release/*" .Branches}}
+- {{trimPrefix "release/" .}}{{end}}
+
+Latest release: {{index .Releases 0}}, default branch {{.DefaultBranch}}.
+`
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner, repo, path string, out interface{}) error {
+		if oc, ok := out.(*OrgConfig); ok {
+			oc.SecurityTemplate = tmpl
+		}
+		return nil
+	}
+	m := &mockFix{}
+	err := fix(context.Background(), fixClients{repos: m, git: m, pulls: m, issues: &mockFixIssues{}},
+		nil, "thisorg", "thisrepo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `# thisorg/thisrepo Security Policy
+
+## Supported Versions
+
+- 2.1
+- 2.0
+
+Latest release: v2.1.0, default branch main.
+`
+	if diff := cmp.Diff(want, string(m.created.Content)); diff != "" {
+		t.Errorf("Unexpected content. (-want +got):\n%s", diff)
+	}
+}
+
+func TestFixIdempotent(t *testing.T) {
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner, repo, path string, out interface{}) error {
+		return nil
+	}
+	tests := []struct {
+		Name         string
+		Mock         *mockFix
+		ExpRef       bool
+		ExpCreate    bool
+		ExpPRCreated bool
+	}{
+		{
+			Name:         "New",
+			Mock:         &mockFix{},
+			ExpRef:       true,
+			ExpCreate:    true,
+			ExpPRCreated: true,
+		},
+		{
+			Name: "OpenPR",
+			Mock: &mockFix{openPR: &github.PullRequest{Number: github.Int(3)}},
+		},
+		{
+			Name:         "BranchExists",
+			Mock:         &mockFix{branchExists: true},
+			ExpCreate:    true,
+			ExpPRCreated: true,
+		},
+		{
+			Name:         "BranchHasFile",
+			Mock:         &mockFix{branchExists: true, branchFile: true},
+			ExpPRCreated: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			m := test.Mock
+			err := fix(context.Background(), fixClients{repos: m, git: m, pulls: m, issues: &mockFixIssues{}},
+				nil, "thisorg", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := m.ref != nil; got != test.ExpRef {
+				t.Errorf("Unexpected branch creation. Expected: %v Got: %v", test.ExpRef, got)
+			}
+			if got := m.created != nil; got != test.ExpCreate {
+				t.Errorf("Unexpected file creation. Expected: %v Got: %v", test.ExpCreate, got)
+			}
+			if got := m.pr != nil; got != test.ExpPRCreated {
+				t.Errorf("Unexpected pull request. Expected: %v Got: %v", test.ExpPRCreated, got)
+			}
+		})
+	}
+}
+
+func TestFixDryRun(t *testing.T) {
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner, repo, path string, out interface{}) error {
+		if oc, ok := out.(*OrgConfig); ok {
+			oc.DryRun = true
+		}
+		return nil
+	}
+	m := &mockFix{}
+	err := fix(context.Background(), fixClients{repos: m, git: m, pulls: m, issues: &mockFixIssues{}},
+		nil, "thisorg", "thisrepo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.created != nil || m.ref != nil || m.pr != nil {
+		t.Errorf("Expected no changes in dry run")
+	}
+}
+
+func TestFixPending(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Mock    *mockFix
+		DryRun  bool
+		Reasons []string
+		Exp     bool
+	}{
+		{
+			Name: "New",
+			Mock: &mockFix{},
+			Exp:  true,
+		},
+		{
+			Name: "OpenPR",
+			Mock: &mockFix{openPR: &github.PullRequest{Number: github.Int(3)}},
+		},
+		{
+			Name:   "DryRun",
+			Mock:   &mockFix{},
+			DryRun: true,
+		},
+		{
+			Name:    "NotFixable",
+			Mock:    &mockFix{},
+			Reasons: []string{ReasonTimeline},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			dry := test.DryRun
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner, repo, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					oc.DryRun = dry
+				}
+				return nil
+			}
+			ctx := context.Background()
+			if test.Reasons != nil {
+				ctx = policydef.WithReasons(ctx, test.Reasons)
+			}
+			m := test.Mock
+			got, err := fixPending(ctx, fixClients{repos: m, git: m, pulls: m, issues: &mockFixIssues{}},
+				nil, "thisorg", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != test.Exp {
+				t.Errorf("Unexpected pending. Expected: %v Got: %v", test.Exp, got)
+			}
+		})
+	}
+}
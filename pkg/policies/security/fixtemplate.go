@@ -0,0 +1,81 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// templateFuncs are the functions available to fix templates.
+var templateFuncs = template.FuncMap{
+	// match returns the items matching a path.Match glob, such as
+	// match "release/*" .Branches.
+	"match": func(pattern string, items []string) []string {
+		var out []string
+		for _, i := range items {
+			if m, _ := path.Match(pattern, i); m {
+				out = append(out, i)
+			}
+		}
+		return out
+	},
+	// first returns at most the first n items.
+	"first": func(n int, items []string) []string {
+		if n < len(items) {
+			return items[:n]
+		}
+		return items
+	},
+	"join":       func(sep string, items []string) string { return strings.Join(items, sep) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+}
+
+// renderSecurityTemplate renders the SecurityTemplate text, with data extended
+// with the repo's default branch, releases, and branches.
+func renderSecurityTemplate(ctx context.Context, rep fixRepositories, text string,
+	data templateData, base string) (string, error) {
+	data.DefaultBranch = base
+	rs, _, err := rep.ListReleases(ctx, data.Owner, data.Repo, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return "", err
+	}
+	for _, r := range rs {
+		if !r.GetDraft() {
+			data.Releases = append(data.Releases, r.GetTagName())
+		}
+	}
+	bs, _, err := rep.ListBranches(ctx, data.Owner, data.Repo, &github.BranchListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, b := range bs {
+		data.Branches = append(data.Branches, b.GetName())
+	}
+	content, err := renderTemplate("securityTemplate", text, data)
+	if err != nil {
+		return "", fmt.Errorf("invalid securityTemplate: %w", err)
+	}
+	return content, nil
+}
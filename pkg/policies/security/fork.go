@@ -0,0 +1,49 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// upstreamPolicy returns the name and security policy URL of the repo's fork
+// parent, if the repo is a fork and the parent has a security policy.
+// Otherwise the URL is empty.
+func upstreamPolicy(ctx context.Context, v4c v4client, owner, repo string) (string, string, error) {
+	var q struct {
+		Repository struct {
+			IsFork bool
+			Parent struct {
+				NameWithOwner           string
+				IsSecurityPolicyEnabled bool
+				SecurityPolicyUrl       string
+			}
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	variables := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+	}
+	if err := v4c.Query(ctx, &q, variables); err != nil {
+		return "", "", err
+	}
+	p := q.Repository.Parent
+	if !q.Repository.IsFork || !p.IsSecurityPolicyEnabled {
+		return p.NameWithOwner, "", nil
+	}
+	return p.NameWithOwner, p.SecurityPolicyUrl, nil
+}
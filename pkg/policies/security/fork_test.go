@@ -0,0 +1,103 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestCheckUpstream(t *testing.T) {
+	tests := []struct {
+		Name           string
+		Accept         bool
+		IsFork         bool
+		ParentEnabled  bool
+		Pass           bool
+		ExpUpstreamURL string
+	}{
+		{Name: "ForkCovered", Accept: true, IsFork: true, ParentEnabled: true, Pass: true,
+			ExpUpstreamURL: "https://github.com/up/thisrepo/security/policy"},
+		{Name: "ParentMissing", Accept: true, IsFork: true, Pass: false},
+		{Name: "NotFork", Accept: true, ParentEnabled: true, Pass: false},
+		{Name: "NotAccepted", IsFork: true, ParentEnabled: true, Pass: false},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner string, repo string, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					oc.OptConfig.OptOutStrategy = true
+					oc.AcceptUpstreamPolicy = test.Accept
+				}
+				return nil
+			}
+			getContents = contentsMock(nil)
+			upstreamQueried := false
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				switch qc := q.(type) {
+				case *struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				}:
+					qc.Repository.DefaultBranchRef.Name = "main"
+				case *struct {
+					Repository struct {
+						IsFork bool
+						Parent struct {
+							NameWithOwner           string
+							IsSecurityPolicyEnabled bool
+							SecurityPolicyUrl       string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				}:
+					upstreamQueried = true
+					qc.Repository.IsFork = test.IsFork
+					qc.Repository.Parent.NameWithOwner = "up/thisrepo"
+					qc.Repository.Parent.IsSecurityPolicyEnabled = test.ParentEnabled
+					if test.ParentEnabled {
+						qc.Repository.Parent.SecurityPolicyUrl = "https://github.com/up/thisrepo/security/policy"
+					}
+				default:
+					t.Errorf("Query() called with unexpected query structure.")
+				}
+				return nil
+			}
+			res, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "org", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Pass != test.Pass {
+				t.Errorf("Unexpected pass. Expected: %v Got: %v", test.Pass, res.Pass)
+			}
+			if upstreamQueried != test.Accept {
+				t.Errorf("Unexpected upstream query. Expected: %v Got: %v", test.Accept, upstreamQueried)
+			}
+			d := res.Details.(SecurityDetails)
+			if test.Pass && (d.Source != SourceUpstream || d.URL != test.ExpUpstreamURL || d.Upstream == "") {
+				t.Errorf("Unexpected details: %+v", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// RepoGroup is a family of related repos that must share the same security
+// policy, see OrgConfig.RepoGroups.
+type RepoGroup struct {
+	// Canonical is the repo whose security policy the others must match.
+	Canonical string `yaml:"canonical"`
+
+	// Repos are the other repos in the group.
+	Repos []string `yaml:"repos"`
+}
+
+// GroupDrift records a security policy that differs from its group's canonical
+// repo.
+type GroupDrift struct {
+	// Canonical is the canonical repo of the group.
+	Canonical string
+
+	// Hash and CanonicalHash are the content hashes of the repo's and the
+	// canonical repo's security policy, see contentHash.
+	Hash          string
+	CanonicalHash string
+}
+
+const groupDriftText = `This repository is part of a group of related repositories that share one security policy, so that reporters get the same instructions for each of them. This repository's security policy has drifted from the canonical copy.
+
+To fix this, copy the security policy of the canonical repository into this repository.`
+
+// canonicalRepo returns the canonical repo of the group repo is a member of,
+// or an empty string if it is not a member of a group, or is itself
+// canonical.
+func canonicalRepo(groups []RepoGroup, repo string) string {
+	for _, g := range groups {
+		if strings.EqualFold(g.Canonical, repo) {
+			return ""
+		}
+		for _, r := range g.Repos {
+			if strings.EqualFold(r, repo) {
+				return g.Canonical
+			}
+		}
+	}
+	return ""
+}
+
+// checkGroupDrift compares content with the security policy of the canonical
+// repo, and returns the drift if they differ. If the canonical repo has no
+// security policy there is nothing to compare, and no drift is returned.
+func checkGroupDrift(ctx context.Context, rep repositories, owner, canonical, content string) (*GroupDrift, error) {
+	p, canonContent, err := getPolicyFile(ctx, rep, owner, canonical)
+	if err != nil || p == "" {
+		return nil, err
+	}
+	h, ch := contentHash(content), contentHash(canonContent)
+	if h == ch {
+		return nil, nil
+	}
+	return &GroupDrift{
+		Canonical:     canonical,
+		Hash:          h,
+		CanonicalHash: ch,
+	}, nil
+}
+
+// contentHash returns the hex encoded SHA-256 of content, ignoring line ending
+// and trailing whitespace differences.
+func contentHash(content string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.TrimSpace(strings.Join(lines, "\n")))))
+}
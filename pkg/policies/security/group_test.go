@@ -0,0 +1,76 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestCanonicalRepo(t *testing.T) {
+	groups := []RepoGroup{{Canonical: "main", Repos: []string{"main-go", "Main-Py"}}}
+	tests := map[string]string{
+		"main":    "",
+		"main-go": "main",
+		"main-py": "main",
+		"other":   "",
+	}
+	for repo, exp := range tests {
+		if got := canonicalRepo(groups, repo); got != exp {
+			t.Errorf("Unexpected canonical repo for %v. Expected: %q Got: %q", repo, exp, got)
+		}
+	}
+}
+
+func TestCheckGroupDrift(t *testing.T) {
+	canon := "# Security\nEmail security@example.com.\n"
+	tests := []struct {
+		Name      string
+		Content   string
+		Canonical map[string]string
+		Drift     bool
+	}{
+		{Name: "Same", Content: canon, Canonical: map[string]string{"SECURITY.md": canon}},
+		{Name: "LineEndings", Content: "# Security\r\nEmail security@example.com.  \r\n",
+			Canonical: map[string]string{"SECURITY.md": canon}},
+		{Name: "Drifted", Content: "# Security\nEmail old@example.com.\n",
+			Canonical: map[string]string{".github/SECURITY.md": canon}, Drift: true},
+		{Name: "CanonicalMissing", Content: canon},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			getContents = func(ctx context.Context, owner, repo, path string,
+				opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+				[]*github.RepositoryContent, *github.Response, error) {
+				if repo != "main" {
+					t.Errorf("Unexpected repo fetched: %v", repo)
+				}
+				return contentsMock(test.Canonical)(ctx, owner, repo, path, opts)
+			}
+			drift, err := checkGroupDrift(context.Background(), mockRepos{}, "org", "main", test.Content)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (drift != nil) != test.Drift {
+				t.Fatalf("Unexpected drift. Expected: %v Got: %+v", test.Drift, drift)
+			}
+			if drift != nil && (drift.Canonical != "main" || drift.Hash == drift.CanonicalHash) {
+				t.Errorf("Unexpected drift: %+v", drift)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"time"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+var measureDetectionLag = operator.MeasureDetectionLag
+
+type commitRepositories interface {
+	ListCommits(context.Context, string, string, *github.CommitsListOptions) (
+		[]*github.RepositoryCommit, *github.Response, error)
+}
+
+var newCommitRepositories func(*github.Client) commitRepositories
+
+// DetectionLagRecorder receives detection lag samples, such as to export them
+// as a metric. lag is the time since the security policy file was last
+// committed, while GitHub still reports no security policy.
+type DetectionLagRecorder func(ctx context.Context, owner, repo string, lag time.Duration)
+
+var detectionLagRecorder DetectionLagRecorder
+
+func init() {
+	newCommitRepositories = func(c *github.Client) commitRepositories {
+		return c.Repositories
+	}
+}
+
+// SetDetectionLagRecorder sets a recorder for detection lag samples, in
+// addition to logging them, see operator.MeasureDetectionLag. It is intended to
+// be called at startup.
+func SetDetectionLagRecorder(r DetectionLagRecorder) {
+	detectionLagRecorder = r
+}
+
+// recordDetectionLag logs the time since the latest commit to the security
+// policy file at p, for a repo GitHub does not yet report as having a security
+// policy. Errors are logged, and no sample is recorded.
+func recordDetectionLag(ctx context.Context, cr commitRepositories, owner, repo, p string) {
+	commits, _, err := cr.ListCommits(ctx, owner, repo, &github.CommitsListOptions{
+		Path:        p,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil || len(commits) == 0 {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("path", p).
+			Err(err).
+			Msg("Unable to find security policy commit, not measuring detection lag.")
+		return
+	}
+	committed := commits[0].GetCommit().GetCommitter().GetDate()
+	lag := timeNow().Sub(committed)
+	runid.Logger(ctx).Info().
+		Str("org", owner).
+		Str("repo", repo).
+		Str("area", polName).
+		Str("path", p).
+		Time("committed", committed).
+		Dur("detectionLag", lag).
+		Msg("Security policy detection lag sample.")
+	if detectionLagRecorder != nil {
+		detectionLagRecorder(ctx, owner, repo, lag)
+	}
+}
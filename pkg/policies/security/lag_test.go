@@ -0,0 +1,59 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+type mockCommits struct {
+	commits []*github.RepositoryCommit
+}
+
+func (m mockCommits) ListCommits(ctx context.Context, owner, repo string,
+	opts *github.CommitsListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+	return m.commits, nil, nil
+}
+
+func TestRecordDetectionLag(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+		detectionLagRecorder = nil
+	}()
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	var got time.Duration
+	SetDetectionLagRecorder(func(ctx context.Context, owner, repo string, lag time.Duration) {
+		got = lag
+	})
+	committed := now.Add(-90 * time.Minute)
+	m := mockCommits{commits: []*github.RepositoryCommit{{
+		Commit: &github.Commit{Committer: &github.CommitAuthor{Date: &committed}},
+	}}}
+	recordDetectionLag(context.Background(), m, "org", "repo", "SECURITY.md")
+	if got != 90*time.Minute {
+		t.Errorf("Unexpected lag. Expected: %v Got: %v", 90*time.Minute, got)
+	}
+
+	got = 0
+	recordDetectionLag(context.Background(), mockCommits{}, "org", "repo", "SECURITY.md")
+	if got != 0 {
+		t.Errorf("Expected no sample without commits, got: %v", got)
+	}
+}
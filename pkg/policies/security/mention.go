@@ -0,0 +1,106 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+type mentionRepositories interface {
+	repositories
+	ListCollaborators(context.Context, string, string, *github.ListCollaboratorsOptions) (
+		[]*github.User, *github.Response, error)
+}
+
+var newMentionRepositories func(*github.Client) mentionRepositories
+
+func init() {
+	newMentionRepositories = func(c *github.Client) mentionRepositories {
+		return c.Repositories
+	}
+}
+
+// mentionText returns a line mentioning who is responsible for the repo's
+// security policy, or an empty string if no one is found. Errors are logged, as
+// the mention is a courtesy.
+func mentionText(ctx context.Context, rep mentionRepositories, owner, repo string, defaults []string) string {
+	users, err := responsibleUsers(ctx, rep, owner, repo, defaults)
+	if err != nil {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Err(err).
+			Msg("Unable to find code owners to mention.")
+	}
+	return mentionLine(users)
+}
+
+// mentionLine returns the line mentioning users, or an empty string if there
+// are none.
+func mentionLine(users []string) string {
+	if len(users) == 0 {
+		return ""
+	}
+	return "\n\ncc " + strings.Join(users, " ")
+}
+
+// responsibleUsers returns the mentionable CODEOWNERS owners of SECURITY.md,
+// falling back to defaults, then the repo admins.
+func responsibleUsers(ctx context.Context, rep mentionRepositories, owner, repo string, defaults []string) ([]string, error) {
+	_, co, err := getFirstFile(ctx, rep, owner, repo, codeOwnersPaths)
+	if err != nil {
+		return defaults, err
+	}
+	var users []string
+	for _, o := range codeOwnersFor(co, fixPath) {
+		// Email owners can't be mentioned.
+		if strings.HasPrefix(o, "@") {
+			users = append(users, o)
+		}
+	}
+	if len(users) > 0 {
+		return users, nil
+	}
+	if len(defaults) > 0 {
+		return defaults, nil
+	}
+	opt := &github.ListCollaboratorsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+	for {
+		cs, resp, err := rep.ListCollaborators(ctx, owner, repo, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range cs {
+			if u.Permissions["admin"] && u.GetType() != "Bot" {
+				users = append(users, "@"+u.GetLogin())
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return users, nil
+}
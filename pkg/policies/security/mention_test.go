@@ -0,0 +1,79 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+type mockMentionRepos struct {
+	mockRepos
+	admins []string
+}
+
+func (m mockMentionRepos) ListCollaborators(ctx context.Context, owner, repo string,
+	opts *github.ListCollaboratorsOptions) ([]*github.User, *github.Response, error) {
+	admin := map[string]bool{"admin": true}
+	var us []*github.User
+	for _, a := range m.admins {
+		us = append(us, &github.User{Login: github.String(a), Type: github.String("User"), Permissions: admin})
+	}
+	us = append(us,
+		&github.User{Login: github.String("dev"), Type: github.String("User"), Permissions: map[string]bool{"push": true}},
+		&github.User{Login: github.String("deploy[bot]"), Type: github.String("Bot"), Permissions: admin})
+	return us, &github.Response{}, nil
+}
+
+func TestMentionText(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Files    map[string]string
+		Defaults []string
+		Exp      string
+	}{
+		{
+			Name: "CodeOwners",
+			Files: map[string]string{
+				".github/CODEOWNERS": "* @org/devs\n/SECURITY.md @org/security security@example.com\n",
+			},
+			Exp: "\n\ncc @org/security",
+		},
+		{
+			Name: "NoOwnerDefaults",
+			Files: map[string]string{
+				"CODEOWNERS": "/docs/ @org/writers\n",
+			},
+			Defaults: []string{"@org/security-team"},
+			Exp:      "\n\ncc @org/security-team",
+		},
+		{
+			Name: "NoCodeOwnersAdmins",
+			Exp:  "\n\ncc @alice @bob",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			getContents = contentsMock(test.Files)
+			rep := mockMentionRepos{admins: []string{"alice", "bob"}}
+			got := mentionText(context.Background(), rep, "org", "thisrepo", test.Defaults)
+			if got != test.Exp {
+				t.Errorf("Unexpected mention. Expected: %q Got: %q", test.Exp, got)
+			}
+		})
+	}
+}
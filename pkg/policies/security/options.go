@@ -0,0 +1,82 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/runid"
+)
+
+// alwaysAllowedOptions can not be restricted by the operator.
+var alwaysAllowedOptions = []string{"optConfig", "action"}
+
+var allowedOptions func() []string
+
+func init() {
+	allowedOptions = func() []string { return operator.SecurityAllowedOptions }
+}
+
+// stripDisallowed resets any option in cfg which is not permitted by
+// operator.SecurityAllowedOptions back to its value in def, logging a warning
+// for each. cfg and def must be pointers to the same struct type.
+func stripDisallowed(ctx context.Context, owner, repo string, cfg, def interface{}) {
+	allowed := allowedOptions()
+	if allowed == nil {
+		return
+	}
+	cv := reflect.ValueOf(cfg).Elem()
+	dv := reflect.ValueOf(def).Elem()
+	t := cv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := yamlName(t.Field(i))
+		if contains(alwaysAllowedOptions, name) || contains(allowed, name) {
+			continue
+		}
+		if reflect.DeepEqual(cv.Field(i).Interface(), dv.Field(i).Interface()) {
+			continue
+		}
+		cv.Field(i).Set(dv.Field(i))
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("option", name).
+			Msg("Config option not permitted by operator, ignoring.")
+	}
+}
+
+func yamlName(f reflect.StructField) string {
+	tag := f.Tag.Get("yaml")
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "" {
+		return strings.ToLower(f.Name)
+	}
+	return tag
+}
+
+func contains(s []string, e string) bool {
+	for _, v := range s {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
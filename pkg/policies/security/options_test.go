@@ -0,0 +1,60 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ossf/allstar/pkg/config"
+)
+
+func TestStripDisallowed(t *testing.T) {
+	defer func() { allowedOptions = func() []string { return nil } }()
+	in := func() *OrgConfig {
+		oc := defaultOrgConfig()
+		oc.OptConfig = config.OrgOptConfig{
+			OptOutStrategy: true,
+		}
+		oc.Action = "issue"
+		oc.DisallowPublicDisclosure = true
+		oc.AcceptSecurityTxt = "https://example.com/.well-known/security.txt"
+		return oc
+	}
+
+	allowedOptions = func() []string { return nil }
+	oc := in()
+	stripDisallowed(context.Background(), "org", ".allstar", oc, defaultOrgConfig())
+	if diff := cmp.Diff(in(), oc); diff != "" {
+		t.Errorf("Unexpected change with no allowlist. (-want +got):\n%s", diff)
+	}
+
+	allowedOptions = func() []string { return []string{"disallowPublicDisclosure"} }
+	oc = in()
+	stripDisallowed(context.Background(), "org", ".allstar", oc, defaultOrgConfig())
+	want := in()
+	want.AcceptSecurityTxt = ""
+	if diff := cmp.Diff(want, oc); diff != "" {
+		t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+	}
+
+	txt := "https://example.com/.well-known/security.txt"
+	rc := &RepoConfig{AcceptSecurityTxt: &txt}
+	stripDisallowed(context.Background(), "org", "repo", rc, &RepoConfig{})
+	if rc.AcceptSecurityTxt != nil {
+		t.Errorf("Expected repo option to be stripped")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ossf/allstar/pkg/policydef"
+
+	"github.com/google/go-github/v39/github"
+	"github.com/shurcooL/githubv4"
+)
+
+// orgDefaultRepo is the repo GitHub uses for org-wide default community health
+// files, including SECURITY.md.
+const orgDefaultRepo = ".github"
+
+const orgNotifyText = `The organization does not have a default security policy. A SECURITY.md file in the organization's %v repository is used by GitHub as the security policy for every repository in the organization that does not have its own.
+
+To fix this, create a %v repository in the %v organization, if it does not exist, and add a SECURITY.md file that explains how to report vulnerabilities.
+
+For more information, see https://docs.github.com/en/communities/setting-up-your-project-for-healthy-contributions/creating-a-default-community-health-file.`
+
+const requireSecurityMDText = `This repository inherits the organization's default security policy, but the organization requires each repository to have its own SECURITY.md.
+
+`
+
+type orgDetails struct {
+	Repo  string
+	Found bool
+	Path  string
+}
+
+// CheckOrgDefault checks whether the org has a default SECURITY.md in its
+// .github repo. This is separate from the per-repo policy check, and is not
+// affected by any opt in/out config.
+func CheckOrgDefault(ctx context.Context, c *github.Client, owner string) (*policydef.Result, error) {
+	return checkOrgDefault(ctx, c.Repositories, owner)
+}
+
+func checkOrgDefault(ctx context.Context, rep repositories, owner string) (*policydef.Result, error) {
+	p, _, err := getPolicyFile(ctx, rep, owner, orgDefaultRepo)
+	if err != nil {
+		return nil, err
+	}
+	if p == "" {
+		return &policydef.Result{
+			Enabled:    true,
+			Pass:       false,
+			NotifyText: fmt.Sprintf(orgNotifyText, orgDefaultRepo, orgDefaultRepo, owner),
+			Details: orgDetails{
+				Repo:  orgDefaultRepo,
+				Found: false,
+			},
+		}, nil
+	}
+	return &policydef.Result{
+		Enabled:    true,
+		Pass:       true,
+		NotifyText: "",
+		Details: orgDetails{
+			Repo:  orgDefaultRepo,
+			Found: true,
+			Path:  p,
+		},
+	}, nil
+}
+
+// orgDefaultPolicy returns the security policy URL of the org's .github repo,
+// which GitHub uses for repos without their own. It is empty if the .github
+// repo has no security policy, or does not exist.
+func orgDefaultPolicy(ctx context.Context, v4c v4client, owner string) (string, error) {
+	var q struct {
+		Repository struct {
+			IsSecurityPolicyEnabled bool
+			SecurityPolicyUrl       string
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	variables := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(orgDefaultRepo),
+	}
+	if err := v4c.Query(ctx, &q, variables); err != nil {
+		// GraphQL errors are only available as messages.
+		if strings.Contains(err.Error(), "Could not resolve to a Repository") {
+			return "", nil
+		}
+		return "", err
+	}
+	if !q.Repository.IsSecurityPolicyEnabled {
+		return "", nil
+	}
+	return q.Repository.SecurityPolicyUrl, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func TestCheckOrgDefault(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Files map[string]string
+		Exp   policydef.Result
+	}{
+		{
+			Name:  "Missing",
+			Files: map[string]string{},
+			Exp: policydef.Result{
+				Enabled:    true,
+				Pass:       false,
+				NotifyText: "The organization does not have a default security policy.",
+				Details: orgDetails{
+					Repo:  ".github",
+					Found: false,
+				},
+			},
+		},
+		{
+			Name: "Found",
+			Files: map[string]string{
+				"SECURITY.md": "Email security@example.com",
+			},
+			Exp: policydef.Result{
+				Enabled:    true,
+				Pass:       true,
+				NotifyText: "",
+				Details: orgDetails{
+					Repo:  ".github",
+					Found: true,
+					Path:  "SECURITY.md",
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			getContents = contentsMock(test.Files)
+			res, err := checkOrgDefault(context.Background(), mockRepos{}, "org")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			c := cmp.Comparer(func(x, y string) bool { return trunc(x, 40) == trunc(y, 40) })
+			if diff := cmp.Diff(&test.Exp, res, c); diff != "" {
+				t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckInheritedPolicy(t *testing.T) {
+	orgURL := "https://github.com/org/.github/blob/main/SECURITY.md"
+	tests := []struct {
+		Name          string
+		SecEnabled    bool
+		URL           string
+		OrgDefaultURL string
+		Require       bool
+		ExpPass       bool
+		ExpInherited  bool
+		ExpSource     string
+	}{
+		{
+			Name:       "Local",
+			SecEnabled: true,
+			URL:        "https://github.com/org/thisrepo/blob/main/SECURITY.md",
+			ExpPass:    true,
+			ExpSource:  SourceRepoFile,
+		},
+		{
+			Name:         "ReportedOrgDefault",
+			SecEnabled:   true,
+			URL:          orgURL,
+			ExpPass:      true,
+			ExpInherited: true,
+			ExpSource:    SourceOrgDefault,
+		},
+		{
+			Name:          "FallbackOrgDefault",
+			OrgDefaultURL: orgURL,
+			ExpPass:       true,
+			ExpInherited:  true,
+			ExpSource:     SourceOrgDefault,
+		},
+		{
+			Name:         "RequireReported",
+			SecEnabled:   true,
+			URL:          orgURL,
+			Require:      true,
+			ExpInherited: true,
+		},
+		{
+			Name:          "RequireFallback",
+			OrgDefaultURL: orgURL,
+			Require:       true,
+			ExpInherited:  true,
+		},
+		{
+			Name: "NoOrgDefault",
+		},
+	}
+	defer func() { orgDefaultURL = "" }()
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner, repo, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					oc.RequireSecurityMD = test.Require
+				}
+				return nil
+			}
+			getContents = contentsMock(nil)
+			orgDefaultURL = test.OrgDefaultURL
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				qc, ok := q.(*struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				})
+				if !ok {
+					t.Fatalf("Query() called with unexpected query structure.")
+				}
+				qc.Repository.IsSecurityPolicyEnabled = test.SecEnabled
+				qc.Repository.SecurityPolicyUrl = test.URL
+				qc.Repository.DefaultBranchRef.Name = "main"
+				return nil
+			}
+			res, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "org", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Pass != test.ExpPass {
+				t.Errorf("Unexpected pass. Expected: %v Got: %v", test.ExpPass, res.Pass)
+			}
+			d := res.Details.(SecurityDetails)
+			if d.Inherited != test.ExpInherited {
+				t.Errorf("Unexpected inherited. Expected: %v Got: %v", test.ExpInherited, d.Inherited)
+			}
+			if d.Source != test.ExpSource {
+				t.Errorf("Unexpected source. Expected: %v Got: %v", test.ExpSource, d.Source)
+			}
+			if !test.ExpPass && (len(res.Reasons) == 0 || res.Reasons[0] != ReasonMissing) {
+				t.Errorf("Unexpected reasons: %v", res.Reasons)
+			}
+		})
+	}
+}
+
+type errV4 struct {
+	err error
+}
+
+func (m errV4) Query(ctx context.Context, q interface{}, v map[string]interface{}) error {
+	return m.err
+}
+
+func TestOrgDefaultPolicyNoRepo(t *testing.T) {
+	u, err := orgDefaultPolicy(context.Background(),
+		errV4{errors.New("Could not resolve to a Repository with the name 'org/.github'.")}, "org")
+	if err != nil || u != "" {
+		t.Errorf("Expected no org default and no error. Got: %q, %v", u, err)
+	}
+	_, err = orgDefaultPolicy(context.Background(), errV4{errors.New("timeout")}, "org")
+	if err == nil {
+		t.Error("Expected error")
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// OverrideStats collects, across a scan, which repo-level config overrides
+// took effect in each repo, and the resulting action. Attach it to the context
+// passed to Check or Scan with WithOverrideStats. It is safe for concurrent
+// use.
+type OverrideStats struct {
+	mu    sync.Mutex
+	repos map[string]overrideRecord
+}
+
+type overrideRecord struct {
+	options []string
+	action  string
+}
+
+// OverrideSummary is the aggregate of an OverrideStats.
+type OverrideSummary struct {
+	// Repos is the number of repos checked.
+	Repos int
+
+	// Overridden is the number of repos with at least one override in effect.
+	Overridden int
+
+	// ByOption counts the repos overriding each option, by yaml name.
+	ByOption map[string]int
+
+	// Actions counts the repos by resulting action, after overrides.
+	Actions map[string]int
+
+	// OverriddenRepos is the sorted list of repos with an override in effect.
+	OverriddenRepos []string
+}
+
+type overrideStatsKey struct{}
+
+// WithOverrideStats returns a context that records config overrides to s.
+func WithOverrideStats(ctx context.Context, s *OverrideStats) context.Context {
+	return context.WithValue(ctx, overrideStatsKey{}, s)
+}
+
+// recordOverrides records the options overridden for repo, if the context has
+// OverrideStats. It is called once per check, from checkConfig, rather than
+// each time the config is merged. A repo checked again replaces its record.
+func recordOverrides(ctx context.Context, repo string, options []string, action string) {
+	s, ok := ctx.Value(overrideStatsKey{}).(*OverrideStats)
+	if !ok || s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.repos == nil {
+		s.repos = make(map[string]overrideRecord)
+	}
+	s.repos[repo] = overrideRecord{options: options, action: action}
+}
+
+// withoutOverrideStats returns a context that does not record overrides, for
+// checks of configs that are not in effect, such as in Simulate.
+func withoutOverrideStats(ctx context.Context) context.Context {
+	return context.WithValue(ctx, overrideStatsKey{}, (*OverrideStats)(nil))
+}
+
+// Summary aggregates the recorded overrides.
+func (s *OverrideStats) Summary() OverrideSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sum := OverrideSummary{
+		Repos:    len(s.repos),
+		ByOption: make(map[string]int),
+		Actions:  make(map[string]int),
+	}
+	for repo, r := range s.repos {
+		sum.Actions[r.action]++
+		if len(r.options) == 0 {
+			continue
+		}
+		sum.Overridden++
+		sum.OverriddenRepos = append(sum.OverriddenRepos, repo)
+		for _, o := range r.options {
+			sum.ByOption[o]++
+		}
+	}
+	sort.Strings(sum.OverriddenRepos)
+	return sum
+}
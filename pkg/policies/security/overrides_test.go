@@ -0,0 +1,111 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config"
+)
+
+func TestOverrideStats(t *testing.T) {
+	s := &OverrideStats{}
+	ctx := WithOverrideStats(context.Background(), s)
+	oc := &OrgConfig{Action: "issue"}
+	issue := "issue"
+	log := "log"
+	stars := 10
+	merge := func(oc *OrgConfig, rc *RepoConfig, repo string) {
+		mc := mergeConfig(ctx, oc, rc, repo)
+		recordOverrides(ctx, repo, mc.overridden, mc.Action)
+	}
+	merge(oc, &RepoConfig{}, "default")
+	// Same as org value, not an override in effect.
+	merge(oc, &RepoConfig{Action: &issue}, "same")
+	merge(oc, &RepoConfig{Action: &log, MinStarsForIssue: &stars}, "override")
+	merge(oc, &RepoConfig{Action: &log}, "override")
+	merge(&OrgConfig{
+		Action:    "issue",
+		OptConfig: config.OrgOptConfig{DisableRepoOverride: true},
+	}, &RepoConfig{Action: &log}, "disabled")
+	want := OverrideSummary{
+		Repos:           4,
+		Overridden:      1,
+		ByOption:        map[string]int{"action": 1},
+		Actions:         map[string]int{"issue": 3, "log": 1},
+		OverriddenRepos: []string{"override"},
+	}
+	if diff := cmp.Diff(want, s.Summary()); diff != "" {
+		t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+	}
+	// No stats in context is a no-op.
+	recordOverrides(context.Background(), "none", []string{"action"}, "log")
+}
+
+func TestOverrideStatsOncePerCheck(t *testing.T) {
+	log := "log"
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner string, repo string, path string, out interface{}) error {
+		switch v := out.(type) {
+		case *OrgConfig:
+			v.OptConfig.OptOutStrategy = true
+			v.Action = "issue"
+		case *RepoConfig:
+			v.Action = &log
+		}
+		return nil
+	}
+	getContents = contentsMock(map[string]string{
+		"SECURITY.md": "To report a security issue, email security@example.com.",
+	})
+	query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+		qc := q.(*struct {
+			Repository struct {
+				SecurityPolicyUrl       string
+				IsSecurityPolicyEnabled bool
+				IsArchived              bool
+				IsDisabled              bool
+				DefaultBranchRef        struct {
+					Name string
+				}
+			} `graphql:"repository(owner: $owner, name: $name)"`
+		})
+		qc.Repository.IsSecurityPolicyEnabled = true
+		qc.Repository.DefaultBranchRef.Name = "main"
+		return nil
+	}
+	s := &OverrideStats{}
+	ctx := WithOverrideStats(context.Background(), s)
+	if _, err := check(ctx, mockRepos{}, nil, mockClient{}, "org", "thisrepo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Merging the config for the action or a simulation is not another check.
+	resolveConfig(ctx, nil, "org", "thisrepo")
+	simulate(ctx, mockRepos{}, nil, mockClient{}, "org", []string{"simulated"},
+		&OrgConfig{Action: "fix"}, nil)
+	want := OverrideSummary{
+		Repos:           1,
+		Overridden:      1,
+		ByOption:        map[string]int{"action": 1},
+		Actions:         map[string]int{"log": 1},
+		OverriddenRepos: []string{"thisrepo"},
+	}
+	if diff := cmp.Diff(want, s.Summary()); diff != "" {
+		t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+	}
+}
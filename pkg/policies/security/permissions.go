@@ -0,0 +1,123 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v39/github"
+)
+
+type apps interface {
+	GetInstallation(context.Context, int64) (*github.Installation,
+		*github.Response, error)
+}
+
+// permission is an App permission and the access level required.
+type permission struct {
+	Name  string
+	Level string
+}
+
+func (p permission) String() string {
+	return fmt.Sprintf("%v: %v", p.Name, p.Level)
+}
+
+// CheckPermissions returns the App permissions the SECURITY.md policy needs for
+// the repo, given its configured action, that the installation has not been
+// granted. An empty result means the installation is correctly configured. ac
+// must be the App client (installation 0 from ghclients), as installation
+// details are not visible to the installation itself.
+func CheckPermissions(ctx context.Context, ac *github.Client, c *github.Client,
+	instID int64, owner, repo string) ([]string, error) {
+	mc := resolveConfig(ctx, c, owner, repo)
+	return checkPermissions(ctx, ac.Apps, instID, mc.Action)
+}
+
+func checkPermissions(ctx context.Context, a apps, instID int64,
+	action string) ([]string, error) {
+	inst, _, err := a.GetInstallation(ctx, instID)
+	if err != nil {
+		return nil, err
+	}
+	return missingPermissions(inst.GetPermissions(), action), nil
+}
+
+// MissingPermissions returns the permissions the action needs that are not in
+// granted. Implementing enforce's permission check, so that write actions are
+// downgraded to log rather than failing mid-run.
+func (s Security) MissingPermissions(action string, granted *github.InstallationPermissions) []string {
+	return missingPermissions(granted, action)
+}
+
+func missingPermissions(granted *github.InstallationPermissions, action string) []string {
+	var missing []string
+	for _, p := range requiredPermissions(action) {
+		if !hasLevel(grantedLevel(granted, p.Name), p.Level) {
+			missing = append(missing, p.String())
+		}
+	}
+	return missing
+}
+
+func requiredPermissions(action string) []permission {
+	req := []permission{
+		{Name: "metadata", Level: "read"},
+		{Name: "contents", Level: "read"},
+	}
+	switch action {
+	case "issue":
+		req = append(req, permission{Name: "issues", Level: "write"})
+	case "dispatch":
+		req = []permission{
+			{Name: "metadata", Level: "read"},
+			{Name: "contents", Level: "write"},
+		}
+	case "fix":
+		req = []permission{
+			{Name: "metadata", Level: "read"},
+			{Name: "contents", Level: "write"},
+			{Name: "pull_requests", Level: "write"},
+		}
+	}
+	return req
+}
+
+func grantedLevel(p *github.InstallationPermissions, name string) string {
+	switch name {
+	case "metadata":
+		return p.GetMetadata()
+	case "contents":
+		return p.GetContents()
+	case "issues":
+		return p.GetIssues()
+	case "pull_requests":
+		return p.GetPullRequests()
+	}
+	return ""
+}
+
+// hasLevel returns true if the granted access level satisfies the required
+// level, "write" implies "read".
+func hasLevel(granted, required string) bool {
+	switch required {
+	case "read":
+		return granted == "read" || granted == "write"
+	case "write":
+		return granted == "write"
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+)
+
+type mockApps struct {
+	perms *github.InstallationPermissions
+}
+
+func (m mockApps) GetInstallation(ctx context.Context, id int64) (*github.Installation,
+	*github.Response, error) {
+	return &github.Installation{Permissions: m.perms}, nil, nil
+}
+
+func TestCheckPermissions(t *testing.T) {
+	read := "read"
+	write := "write"
+	tests := []struct {
+		Name   string
+		Action string
+		Perms  *github.InstallationPermissions
+		Exp    []string
+	}{
+		{
+			Name:   "LogOk",
+			Action: "log",
+			Perms: &github.InstallationPermissions{
+				Metadata: &read,
+				Contents: &read,
+			},
+		},
+		{
+			Name:   "IssueMissing",
+			Action: "issue",
+			Perms: &github.InstallationPermissions{
+				Metadata: &read,
+				Contents: &read,
+				Issues:   &read,
+			},
+			Exp: []string{"issues: write"},
+		},
+		{
+			Name:   "FixMissing",
+			Action: "fix",
+			Perms: &github.InstallationPermissions{
+				Metadata: &read,
+				Contents: &read,
+			},
+			Exp: []string{"contents: write", "pull_requests: write"},
+		},
+		{
+			Name:   "DispatchMissing",
+			Action: "dispatch",
+			Perms: &github.InstallationPermissions{
+				Metadata: &read,
+				Contents: &read,
+			},
+			Exp: []string{"contents: write"},
+		},
+		{
+			Name:   "WriteImpliesRead",
+			Action: "log",
+			Perms: &github.InstallationPermissions{
+				Metadata: &read,
+				Contents: &write,
+			},
+		},
+		{
+			Name:   "None",
+			Action: "log",
+			Perms:  nil,
+			Exp:    []string{"metadata: read", "contents: read"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			got, err := checkPermissions(context.Background(), mockApps{test.Perms},
+				1, test.Action)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.Exp, got); diff != "" {
+				t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// policyURLCacheDuration is how long a security policy URL status is cached.
+const policyURLCacheDuration = time.Hour
+
+const brokenPolicyURLText = `GitHub reports a security policy for this repository, but the security policy link does not resolve, so users can not read it.
+
+To fix this, check that the security policy file exists on the default branch, and re-commit it if needed. Go to the repository Security tab to confirm the policy is shown.`
+
+// policyURLClient returns the HTTP client used to check the policy URL. The
+// GitHub client's transport is used so that the request is authenticated,
+// allowing private repo policies to be checked.
+var policyURLClient func(*github.Client) *http.Client
+
+func init() {
+	policyURLClient = func(c *github.Client) *http.Client {
+		return c.Client()
+	}
+}
+
+type policyURLResult struct {
+	status  int
+	err     error
+	fetched time.Time
+}
+
+var policyURLCache = struct {
+	sync.Mutex
+	m map[string]policyURLResult
+}{m: make(map[string]policyURLResult)}
+
+// checkPolicyURL returns the HTTP status code of a HEAD request to url. Results
+// are cached for policyURLCacheDuration, unless a recheck is forced.
+func checkPolicyURL(ctx context.Context, c *github.Client, url string) (int, error) {
+	now := timeNow()
+	policyURLCache.Lock()
+	r, ok := policyURLCache.m[url]
+	policyURLCache.Unlock()
+	if ok && !policydef.IsForceRecheck(ctx) && now.Sub(r.fetched) < policyURLCacheDuration {
+		return r.status, r.err
+	}
+	status, err := fetchPolicyURL(ctx, policyURLClient(c), url)
+	if ctx.Err() != nil {
+		// Don't cache cancellation of this check.
+		return status, err
+	}
+	policyURLCache.Lock()
+	policyURLCache.m[url] = policyURLResult{status: status, err: err, fetched: now}
+	policyURLCache.Unlock()
+	return status, err
+}
+
+func fetchPolicyURL(ctx context.Context, hc *http.Client, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	rsp, err := hc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	rsp.Body.Close()
+	return rsp.StatusCode, nil
+}
+
+const rawURLText = `This repository requires that the security policy can be downloaded from its raw URL, which tools that collect security contacts use. The raw URL of the security policy file does not resolve.
+
+To fix this, check that the security policy file is committed as a regular file on the default branch, and re-commit it if needed.`
+
+// rawURLBase returns the base of raw file URLs, a var to allow testing.
+var rawURLBase = defaultRawURLBase
+
+// defaultRawURLBase returns https://raw.githubusercontent.com, or for a GitHub
+// Enterprise Server set in operator.RESTEndpoint, its /raw path.
+func defaultRawURLBase() string {
+	if operator.RESTEndpoint == "" {
+		return "https://raw.githubusercontent.com"
+	}
+	u, err := url.Parse(operator.RESTEndpoint)
+	if err != nil {
+		return "https://raw.githubusercontent.com"
+	}
+	return u.Scheme + "://" + u.Host + "/raw"
+}
+
+// rawURL returns the raw URL of the file at p on branch.
+func rawURL(owner, repo, branch, p string) string {
+	return fmt.Sprintf("%v/%v/%v/%v/%v", rawURLBase(), url.PathEscape(owner), url.PathEscape(repo),
+		url.PathEscape(branch), (&url.URL{Path: p}).EscapedPath())
+}
+
+// rawURLCache stores raw URL checks by URL and content hash, as the file
+// changing gives a new URL status.
+var rawURLCache = struct {
+	sync.Mutex
+	m map[string]policyURLResult
+}{m: make(map[string]policyURLResult)}
+
+// checkRawURL returns the HTTP status code of a HEAD request to the raw URL u
+// of a file with content. Successes are cached until the content changes,
+// other results for policyURLCacheDuration, unless a recheck is forced.
+func checkRawURL(ctx context.Context, c *github.Client, u, content string) (int, error) {
+	now := timeNow()
+	key := u + "@" + contentHash(content)
+	rawURLCache.Lock()
+	r, ok := rawURLCache.m[key]
+	rawURLCache.Unlock()
+	if ok && !policydef.IsForceRecheck(ctx) &&
+		((r.err == nil && r.status == http.StatusOK) || now.Sub(r.fetched) < policyURLCacheDuration) {
+		return r.status, r.err
+	}
+	status, err := fetchPolicyURL(ctx, policyURLClient(c), u)
+	if ctx.Err() != nil {
+		// Don't cache cancellation of this check.
+		return status, err
+	}
+	rawURLCache.Lock()
+	rawURLCache.m[key] = policyURLResult{status: status, err: err, fetched: now}
+	rawURLCache.Unlock()
+	return status, err
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config/operator"
+)
+
+func TestCheckPolicyURL(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/stale" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	policyURLClient = func(*github.Client) *http.Client { return srv.Client() }
+	defer func() { policyURLClient = func(c *github.Client) *http.Client { return c.Client() } }()
+
+	status, err := checkPolicyURL(context.Background(), nil, srv.URL+"/ok")
+	if err != nil || status != http.StatusOK {
+		t.Errorf("Unexpected result: %v %v", status, err)
+	}
+	status, err = checkPolicyURL(context.Background(), nil, srv.URL+"/stale")
+	if err != nil || status != http.StatusNotFound {
+		t.Errorf("Unexpected result: %v %v", status, err)
+	}
+	if _, err := checkPolicyURL(context.Background(), nil, srv.URL+"/stale"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected cached result, got %v requests", requests)
+	}
+}
+
+func TestCheckRawURL(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/org/repo/main/.github/SECURITY.md" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	policyURLClient = func(*github.Client) *http.Client { return srv.Client() }
+	defer func() { policyURLClient = func(c *github.Client) *http.Client { return c.Client() } }()
+	rawURLBase = func() string { return srv.URL }
+	defer func() { rawURLBase = defaultRawURLBase }()
+
+	u := rawURL("org", "repo", "main", ".github/SECURITY.md")
+	if u != srv.URL+"/org/repo/main/.github/SECURITY.md" {
+		t.Errorf("Unexpected raw URL: %v", u)
+	}
+	for i := 0; i < 2; i++ {
+		status, err := checkRawURL(context.Background(), nil, u, "v1")
+		if err != nil || status != http.StatusOK {
+			t.Errorf("Unexpected result: %v %v", status, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected cached result, got %v requests", requests)
+	}
+	if _, err := checkRawURL(context.Background(), nil, u, "v2"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected changed content to be rechecked, got %v requests", requests)
+	}
+	status, err := checkRawURL(context.Background(), nil, rawURL("org", "repo", "main", "SECURITY.md"), "v1")
+	if err != nil || status != http.StatusNotFound {
+		t.Errorf("Unexpected result: %v %v", status, err)
+	}
+}
+
+func TestDefaultRawURLBase(t *testing.T) {
+	old := operator.RESTEndpoint
+	defer func() { operator.RESTEndpoint = old }()
+	tests := map[string]string{
+		"":                                   "https://raw.githubusercontent.com",
+		"https://github.example.com/api/v3/": "https://github.example.com/raw",
+	}
+	for endpoint, exp := range tests {
+		operator.RESTEndpoint = endpoint
+		if got := defaultRawURLBase(); got != exp {
+			t.Errorf("Unexpected raw URL base for %q. Expected: %v Got: %v", endpoint, exp, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/runid"
+)
+
+// redactedText replaces each match of a redaction pattern.
+const redactedText = "[REDACTED]"
+
+var redactPatterns = operator.RedactPatterns
+
+// redactor masks matches of operator and org redaction patterns.
+type redactor []*regexp.Regexp
+
+// newRedactor compiles operator.RedactPatterns and the org patterns. Invalid
+// patterns are logged and skipped.
+func newRedactor(ctx context.Context, owner, repo string, patterns []string) redactor {
+	var r redactor
+	for _, p := range append(append([]string(nil), redactPatterns...), patterns...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			runid.Logger(ctx).Warn().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Str("pattern", p).
+				Err(err).
+				Msg("Invalid redaction pattern, skipping.")
+			continue
+		}
+		r = append(r, re)
+	}
+	return r
+}
+
+func (r redactor) redact(s string) string {
+	for _, re := range r {
+		s = re.ReplaceAllString(s, redactedText)
+	}
+	return s
+}
+
+// redactResult masks security policy content quoted in the notify text and
+// details of res, which are both logged and placed in issues.
+func redactResult(res *policydef.Result, r redactor) {
+	if len(r) == 0 {
+		return
+	}
+	res.NotifyText = r.redact(res.NotifyText)
+	d, ok := res.Details.(SecurityDetails)
+	if !ok {
+		return
+	}
+	d.PublicDisclosureLine = r.redact(d.PublicDisclosureLine)
+	d.Timeline = r.redact(d.Timeline)
+	if len(d.RulesetFindings) > 0 {
+		fs := make([]RulesetFinding, len(d.RulesetFindings))
+		for i, f := range d.RulesetFindings {
+			f.Message = r.redact(f.Message)
+			fs[i] = f
+		}
+		d.RulesetFindings = fs
+	}
+	for i, v := range d.SupportedVersions {
+		d.SupportedVersions[i] = r.redact(v)
+	}
+	res.Details = d
+}
+
+// redactJSON masks operator.RedactPatterns in logged JSON. If masking makes it
+// invalid JSON, the masked text is returned as a JSON string.
+func redactJSON(ctx context.Context, owner, repo, s string) string {
+	if len(redactPatterns) == 0 {
+		return s
+	}
+	rs := newRedactor(ctx, owner, repo, nil).redact(s)
+	if json.Valid([]byte(rs)) {
+		return rs
+	}
+	b, _ := json.Marshal(rs)
+	return string(b)
+}
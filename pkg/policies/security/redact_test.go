@@ -0,0 +1,69 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func TestRedactResult(t *testing.T) {
+	redactPatterns = []string{`[\w.+-]+@corp\.example\.com`}
+	defer func() { redactPatterns = nil }()
+	r := newRedactor(context.Background(), "org", "thisrepo",
+		[]string{`internal\.example\.net`, `(invalid`})
+	res := &policydef.Result{
+		NotifyText: "Security policy instructs public disclosure: \"mail jane@corp.example.com\"\n",
+		Details: SecurityDetails{
+			PublicDisclosureLine: "mail jane@corp.example.com",
+			RulesetFindings: []RulesetFinding{
+				{Rule: "contact", Message: "see https://internal.example.net/sec"},
+			},
+		},
+	}
+	redactResult(res, r)
+	want := &policydef.Result{
+		NotifyText: "Security policy instructs public disclosure: \"mail [REDACTED]\"\n",
+		Details: SecurityDetails{
+			PublicDisclosureLine: "mail [REDACTED]",
+			RulesetFindings: []RulesetFinding{
+				{Rule: "contact", Message: "see https://[REDACTED]/sec"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("Unexpected result. (-want +got):\n%s", diff)
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	redactPatterns = []string{`jane@corp\.example\.com`, `"a"`}
+	defer func() { redactPatterns = nil }()
+	tests := []struct {
+		In  string
+		Exp string
+	}{
+		{In: `{"email":"jane@corp.example.com"}`, Exp: `{"email":"[REDACTED]"}`},
+		{In: `{"a":1}`, Exp: `"{[REDACTED]:1}"`},
+	}
+	for _, test := range tests {
+		if got := redactJSON(context.Background(), "org", "thisrepo", test.In); got != test.Exp {
+			t.Errorf("Unexpected JSON. Expected: %v Got: %v", test.Exp, got)
+		}
+	}
+}
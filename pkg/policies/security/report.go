@@ -0,0 +1,253 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Compliance summarizes SECURITY.md policy coverage across an org.
+type Compliance struct {
+	// Enabled is the number of repos the policy is enabled on, excluding those
+	// that failed to check.
+	Enabled int
+
+	// Passing is the number of enabled repos that pass the policy.
+	Passing int
+
+	// Errors is the number of repos that could not be checked.
+	Errors int
+}
+
+// Percent returns the percentage of enabled repos that pass the policy. If no
+// repos are enabled, it is 100.
+func (c Compliance) Percent() float64 {
+	if c.Enabled == 0 {
+		return 100
+	}
+	return float64(c.Passing) * 100 / float64(c.Enabled)
+}
+
+// Summarize computes org compliance from results collected with Scan.
+func Summarize(results []*ScanResult) Compliance {
+	var c Compliance
+	for _, r := range results {
+		if r.Err != nil || r.Result == nil {
+			c.Errors++
+			continue
+		}
+		if !r.Result.Enabled {
+			continue
+		}
+		c.Enabled++
+		if r.Result.Pass {
+			c.Passing++
+		}
+	}
+	return c
+}
+
+// shieldsBadge is the shields.io endpoint badge schema, see
+// https://shields.io/endpoint
+type shieldsBadge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// Badge renders c as shields.io endpoint JSON, suitable to be served and
+// embedded with https://img.shields.io/endpoint?url=...
+func (c Compliance) Badge() ([]byte, error) {
+	p := c.Percent()
+	color := "red"
+	switch {
+	case p >= 90:
+		color = "brightgreen"
+	case p >= 75:
+		color = "yellow"
+	case p >= 50:
+		color = "orange"
+	}
+	return json.Marshal(shieldsBadge{
+		SchemaVersion: 1,
+		Label:         polName,
+		Message:       fmt.Sprintf("%.0f%%", p),
+		Color:         color,
+	})
+}
+
+// ReportEntry is a single repo in a scan report. Field order is fixed so that
+// serialized reports are stable between runs.
+type ReportEntry struct {
+	Owner   string   `json:"owner"`
+	Repo    string   `json:"repo"`
+	Enabled bool     `json:"enabled"`
+	Pass    bool     `json:"pass"`
+	Reasons []string `json:"reasons"`
+	Source  string   `json:"source,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Report builds report entries from results collected with Scan. Entries are
+// sorted by owner and repo, and reasons are sorted, so that reports from
+// different runs can be meaningfully diffed.
+func Report(results []*ScanResult) []ReportEntry {
+	entries := make([]ReportEntry, 0, len(results))
+	for _, r := range results {
+		e := ReportEntry{
+			Owner:   r.Owner,
+			Repo:    r.Repo,
+			Reasons: []string{},
+		}
+		if r.Err != nil {
+			e.Error = r.Err.Error()
+		}
+		if r.Result != nil {
+			e.Enabled = r.Result.Enabled
+			e.Pass = r.Result.Pass
+			e.Reasons = append(e.Reasons, r.Result.Reasons...)
+			sort.Strings(e.Reasons)
+			if d, ok := r.Result.Details.(SecurityDetails); ok {
+				e.Source = d.Source
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Owner != entries[j].Owner {
+			return entries[i].Owner < entries[j].Owner
+		}
+		if entries[i].Repo != entries[j].Repo {
+			return entries[i].Repo < entries[j].Repo
+		}
+		return strings.Join(entries[i].Reasons, ",") < strings.Join(entries[j].Reasons, ",")
+	})
+	return entries
+}
+
+// WriteJSON writes entries as an indented JSON array.
+func WriteJSON(w io.Writer, entries []ReportEntry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+var csvHeader = []string{"owner", "repo", "enabled", "pass", "reasons", "error", "source"}
+
+// WriteCSV writes entries as CSV with a header row. Multiple reasons are
+// separated by ";". The source column is last, so that existing column
+// positions are unchanged.
+func WriteCSV(w io.Writer, entries []ReportEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write([]string{
+			e.Owner,
+			e.Repo,
+			strconv.FormatBool(e.Enabled),
+			strconv.FormatBool(e.Pass),
+			strings.Join(e.Reasons, ";"),
+			e.Error,
+			e.Source,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Remediation is a failing repo in a remediation report, with the steps to
+// bring it into compliance.
+type Remediation struct {
+	Owner   string
+	Repo    string
+	Reasons []string
+	// Steps is the remediation part of the result's NotifyText.
+	Steps string
+}
+
+// Remediations lists the enabled repos that fail the policy, from results
+// collected with Scan, in the same order as Report.
+func Remediations(results []*ScanResult) []Remediation {
+	var rs []Remediation
+	for _, e := range Report(results) {
+		if e.Error != "" || !e.Enabled || e.Pass {
+			continue
+		}
+		rs = append(rs, Remediation{
+			Owner:   e.Owner,
+			Repo:    e.Repo,
+			Reasons: e.Reasons,
+		})
+	}
+	steps := make(map[string]string, len(results))
+	for _, r := range results {
+		if r.Result != nil {
+			steps[r.Owner+"/"+r.Repo] = remediationSteps(r.Result.NotifyText)
+		}
+	}
+	for i := range rs {
+		rs[i].Steps = steps[rs[i].Owner+"/"+rs[i].Repo]
+	}
+	return rs
+}
+
+// remediationSteps returns the "To fix this" part of notify text, or all of it
+// if there is none.
+func remediationSteps(text string) string {
+	if i := strings.Index(text, "To fix this"); i >= 0 {
+		text = text[i:]
+	}
+	return strings.TrimSpace(text)
+}
+
+// WriteMarkdown writes rs as a Markdown checklist, one item per repo with its
+// reason codes and remediation steps, suitable for a tracking document.
+func WriteMarkdown(w io.Writer, rs []Remediation) error {
+	for _, r := range rs {
+		reasons := make([]string, len(r.Reasons))
+		for i, c := range r.Reasons {
+			reasons[i] = "`" + c + "`"
+		}
+		if _, err := fmt.Fprintf(w, "- [ ] **%v/%v** (%v)\n", r.Owner, r.Repo, strings.Join(reasons, ", ")); err != nil {
+			return err
+		}
+		for _, l := range strings.Split(r.Steps, "\n") {
+			if l == "" {
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "  %v\n", l); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func TestSummarize(t *testing.T) {
+	results := []*ScanResult{
+		{Repo: "a", Result: &policydef.Result{Enabled: true, Pass: true}},
+		{Repo: "b", Result: &policydef.Result{Enabled: true, Pass: true}},
+		{Repo: "c", Result: &policydef.Result{Enabled: true, Pass: true}},
+		{Repo: "d", Result: &policydef.Result{Enabled: true, Pass: false}},
+		{Repo: "e", Result: &policydef.Result{Enabled: false, Pass: false}},
+		{Repo: "f", Err: errors.New("check failed")},
+	}
+	c := Summarize(results)
+	if c != (Compliance{Enabled: 4, Passing: 3, Errors: 1}) {
+		t.Errorf("Unexpected summary: %+v", c)
+	}
+	if c.Percent() != 75 {
+		t.Errorf("Unexpected percent: %v", c.Percent())
+	}
+	b, err := c.Badge()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `{"schemaVersion":1,"label":"SECURITY.md","message":"75%","color":"yellow"}`
+	if string(b) != want {
+		t.Errorf("Unexpected badge. Expected: %v Got: %v", want, string(b))
+	}
+	if p := (Compliance{}).Percent(); p != 100 {
+		t.Errorf("Expected 100 percent with no enabled repos, got: %v", p)
+	}
+}
+
+func TestReport(t *testing.T) {
+	results := []*ScanResult{
+		{Owner: "org", Repo: "b", Result: &policydef.Result{Enabled: true, Pass: false,
+			Reasons: []string{"z_reason", "a_reason"}}},
+		{Owner: "org", Repo: "a", Err: errors.New("check failed")},
+		{Owner: "another", Repo: "c", Result: &policydef.Result{Enabled: true, Pass: true,
+			Details: SecurityDetails{Source: SourceOrgDefault}}},
+	}
+	entries := Report(results)
+	var b bytes.Buffer
+	if err := WriteCSV(&b, entries); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `owner,repo,enabled,pass,reasons,error,source
+another,c,true,true,,,org_default
+org,a,false,false,,check failed,
+org,b,true,false,a_reason;z_reason,,
+`
+	if b.String() != want {
+		t.Errorf("Unexpected CSV. Expected:\n%v\nGot:\n%v", want, b.String())
+	}
+
+	// Input order must not affect output.
+	results[0], results[2] = results[2], results[0]
+	var j1, j2 bytes.Buffer
+	if err := WriteJSON(&j1, entries); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := WriteJSON(&j2, Report(results)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if j1.String() != j2.String() {
+		t.Errorf("JSON output not stable:\n%v\n%v", j1.String(), j2.String())
+	}
+}
+
+func TestRemediations(t *testing.T) {
+	results := []*ScanResult{
+		{Owner: "org", Repo: "b", Result: &policydef.Result{Enabled: true, Pass: false,
+			NotifyText: "Security policy missing.\nSome background.\n\nTo fix this, add a SECURITY.md.\n\nSee the docs.",
+			Reasons:    []string{ReasonMissing}}},
+		{Owner: "org", Repo: "a", Result: &policydef.Result{Enabled: true, Pass: false,
+			NotifyText: "Something is wrong.", Reasons: []string{"z_reason", "a_reason"}}},
+		{Owner: "org", Repo: "c", Result: &policydef.Result{Enabled: true, Pass: true}},
+		{Owner: "org", Repo: "d", Result: &policydef.Result{Enabled: false, Pass: false}},
+		{Owner: "org", Repo: "e", Err: errors.New("check failed")},
+	}
+	rs := Remediations(results)
+	if len(rs) != 2 {
+		t.Fatalf("Expected 2 failing repos, got: %+v", rs)
+	}
+	var b bytes.Buffer
+	if err := WriteMarkdown(&b, rs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "- [ ] **org/a** (`a_reason`, `z_reason`)\n" +
+		"  Something is wrong.\n" +
+		"\n" +
+		"- [ ] **org/b** (`security_policy_missing`)\n" +
+		"  To fix this, add a SECURITY.md.\n" +
+		"\n" +
+		"  See the docs.\n" +
+		"\n"
+	if b.String() != want {
+		t.Errorf("Unexpected Markdown. Expected:\n%v\nGot:\n%v", want, b.String())
+	}
+}
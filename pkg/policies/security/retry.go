@@ -0,0 +1,184 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+var retrySleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// retryDelay returns how long to wait before retrying after the given attempt,
+// starting at 1. retryAfter is the delay the response asked for, or 0. It
+// returns false if the wait would be longer than operator.RateLimitMaxWait.
+func retryDelay(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter > 0 {
+		return retryAfter, retryAfter <= operator.RateLimitMaxWait
+	}
+	return operator.CheckRetryBaseDelay << (attempt - 1), true
+}
+
+// parseRetryAfter returns the delay of a Retry-After header in seconds, or 0 if
+// it is missing or not in seconds.
+func parseRetryAfter(h http.Header) time.Duration {
+	s, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || s <= 0 {
+		return 0
+	}
+	return time.Duration(s) * time.Second
+}
+
+// isSecondaryRateLimit returns whether a 403 or 429 response body is GitHub's
+// secondary rate limit, rather than missing permissions.
+func isSecondaryRateLimit(body []byte) bool {
+	b := strings.ToLower(string(body))
+	return strings.Contains(b, "secondary rate limit") || strings.Contains(b, "abuse")
+}
+
+// retryTransport retries requests that fail with a transient 5xx or secondary
+// rate limit response, up to operator.CheckRetryAttempts attempts. It is used
+// for the GraphQL client, whose errors do not carry the response.
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for attempt := 1; ; attempt++ {
+		rsp, err := base.RoundTrip(req)
+		if err != nil || attempt >= operator.CheckRetryAttempts || (req.Body != nil && req.GetBody == nil) {
+			return rsp, err
+		}
+		retryable, err := retryableResponse(rsp)
+		if err != nil || !retryable {
+			return rsp, err
+		}
+		d, ok := retryDelay(attempt, parseRetryAfter(rsp.Header))
+		if !ok {
+			return rsp, nil
+		}
+		io.Copy(ioutil.Discard, rsp.Body)
+		rsp.Body.Close()
+		logRetry(req.Context(), req.URL.Path, rsp.Status, attempt, d)
+		if err := retrySleep(req.Context(), d); err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// retryableResponse returns whether rsp is a transient error. The body of a 403
+// or 429 response is read to tell a secondary rate limit from missing
+// permissions, and restored.
+func retryableResponse(rsp *http.Response) (bool, error) {
+	switch {
+	case rsp.StatusCode >= http.StatusInternalServerError:
+		return true, nil
+	case rsp.StatusCode == http.StatusForbidden || rsp.StatusCode == http.StatusTooManyRequests:
+		b, err := ioutil.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		rsp.Body = ioutil.NopCloser(bytes.NewReader(b))
+		if err != nil {
+			return false, err
+		}
+		return isSecondaryRateLimit(b), nil
+	}
+	return false, nil
+}
+
+// retryableError returns whether err from the REST API is a transient 5xx or
+// secondary rate limit error, and the delay the response asked for, if any.
+func retryableError(err error) (bool, time.Duration) {
+	var are *github.AbuseRateLimitError
+	if errors.As(err, &are) {
+		return true, are.GetRetryAfter()
+	}
+	var er *github.ErrorResponse
+	if errors.As(err, &er) && er.Response != nil && er.Response.StatusCode >= http.StatusInternalServerError {
+		return true, parseRetryAfter(er.Response.Header)
+	}
+	return false, 0
+}
+
+// fetchConfigRetry is config.FetchConfig, retried on transient errors.
+func fetchConfigRetry(ctx context.Context, c *github.Client, owner, repo, path string, out interface{}) error {
+	return withRetry(ctx, path, func() error {
+		return config.FetchConfig(ctx, c, owner, repo, path, out)
+	})
+}
+
+// withRetry calls f until it succeeds, fails with an error that is not
+// transient, or operator.CheckRetryAttempts attempts are made.
+func withRetry(ctx context.Context, what string, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= operator.CheckRetryAttempts {
+			return err
+		}
+		retryable, retryAfter := retryableError(err)
+		if !retryable {
+			return err
+		}
+		d, ok := retryDelay(attempt, retryAfter)
+		if !ok {
+			return err
+		}
+		logRetry(ctx, what, err.Error(), attempt, d)
+		if err := retrySleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+func logRetry(ctx context.Context, what, cause string, attempt int, d time.Duration) {
+	runid.Logger(ctx).Info().
+		Str("area", polName).
+		Str("request", what).
+		Str("cause", cause).
+		Int("attempt", attempt).
+		Dur("delay", d).
+		Msg("Transient GitHub API error, retrying.")
+}
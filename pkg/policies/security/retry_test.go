@@ -0,0 +1,263 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config/operator"
+)
+
+func stubRetrySleep(t *testing.T) *[]time.Duration {
+	var slept []time.Duration
+	old := retrySleep
+	retrySleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	t.Cleanup(func() { retrySleep = old })
+	return &slept
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Status []int
+		Header string
+		Body   string
+		Exp    int
+		Calls  int
+		Slept  []time.Duration
+	}{
+		{
+			Name:   "Success",
+			Status: []int{200},
+			Exp:    200,
+			Calls:  1,
+		},
+		{
+			Name:   "RetryServerError",
+			Status: []int{502, 200},
+			Exp:    200,
+			Calls:  2,
+			Slept:  []time.Duration{time.Second},
+		},
+		{
+			Name:   "Backoff",
+			Status: []int{500, 503, 200},
+			Exp:    200,
+			Calls:  3,
+			Slept:  []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			Name:   "AttemptsCapped",
+			Status: []int{500, 500, 500, 500},
+			Exp:    500,
+			Calls:  3,
+			Slept:  []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			Name:   "RetryAfter",
+			Status: []int{503, 200},
+			Header: "7",
+			Exp:    200,
+			Calls:  2,
+			Slept:  []time.Duration{7 * time.Second},
+		},
+		{
+			Name:   "RetryAfterTooLong",
+			Status: []int{503, 200},
+			Header: "100000",
+			Exp:    503,
+			Calls:  1,
+		},
+		{
+			Name:   "SecondaryRateLimit",
+			Status: []int{403, 200},
+			Header: "3",
+			Body:   "You have exceeded a secondary rate limit.",
+			Exp:    200,
+			Calls:  2,
+			Slept:  []time.Duration{3 * time.Second},
+		},
+		{
+			Name:   "Forbidden",
+			Status: []int{403, 200},
+			Body:   "Resource not accessible by integration",
+			Exp:    403,
+			Calls:  1,
+		},
+		{
+			Name:   "NotFound",
+			Status: []int{404, 200},
+			Exp:    404,
+			Calls:  1,
+		},
+		{
+			Name:   "Unauthorized",
+			Status: []int{401, 200},
+			Exp:    401,
+			Calls:  1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			slept := stubRetrySleep(t)
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				if string(b) != "query" {
+					t.Errorf("Unexpected request body: %q", b)
+				}
+				if test.Header != "" {
+					w.Header().Set("Retry-After", test.Header)
+				}
+				w.WriteHeader(test.Status[calls])
+				calls++
+				fmt.Fprint(w, test.Body)
+			}))
+			defer srv.Close()
+			c := &http.Client{Transport: retryTransport{}}
+			rsp, err := c.Post(srv.URL, "text/plain", strings.NewReader("query"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			b, _ := ioutil.ReadAll(rsp.Body)
+			rsp.Body.Close()
+			if rsp.StatusCode != test.Exp {
+				t.Errorf("Unexpected status: %v", rsp.StatusCode)
+			}
+			if string(b) != test.Body {
+				t.Errorf("Unexpected body: %q", b)
+			}
+			if calls != test.Calls {
+				t.Errorf("Unexpected calls: %v", calls)
+			}
+			if diff := cmp.Diff(test.Slept, *slept); diff != "" {
+				t.Errorf("Unexpected sleeps. (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRetryTransportDisabled(t *testing.T) {
+	stubRetrySleep(t)
+	old := operator.CheckRetryAttempts
+	operator.CheckRetryAttempts = 1
+	defer func() { operator.CheckRetryAttempts = old }()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(502)
+	}))
+	defer srv.Close()
+	c := &http.Client{Transport: retryTransport{}}
+	rsp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rsp.Body.Close()
+	if calls != 1 {
+		t.Errorf("Unexpected calls: %v", calls)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://api.github.com/repos/org/repo/contents/x", nil)
+	serverError := &github.ErrorResponse{
+		Response: &http.Response{StatusCode: 502, Header: http.Header{}, Request: req},
+	}
+	retryAfter := 5 * time.Second
+	tests := []struct {
+		Name  string
+		Errs  []error
+		Calls int
+		Slept []time.Duration
+	}{
+		{
+			Name:  "Success",
+			Errs:  []error{nil},
+			Calls: 1,
+		},
+		{
+			Name:  "ServerError",
+			Errs:  []error{serverError, nil},
+			Calls: 2,
+			Slept: []time.Duration{time.Second},
+		},
+		{
+			Name: "AbuseRateLimit",
+			Errs: []error{&github.AbuseRateLimitError{
+				Response:   &http.Response{StatusCode: 403, Request: req},
+				RetryAfter: &retryAfter,
+			}, nil},
+			Calls: 2,
+			Slept: []time.Duration{retryAfter},
+		},
+		{
+			Name:  "Wrapped",
+			Errs:  []error{fmt.Errorf("fetching: %w", serverError), nil},
+			Calls: 2,
+			Slept: []time.Duration{time.Second},
+		},
+		{
+			Name: "NotFound",
+			Errs: []error{&github.ErrorResponse{
+				Response: &http.Response{StatusCode: 404, Request: req},
+			}, nil},
+			Calls: 1,
+		},
+		{
+			Name:  "Other",
+			Errs:  []error{errors.New("bad yaml"), nil},
+			Calls: 1,
+		},
+		{
+			Name:  "AttemptsCapped",
+			Errs:  []error{serverError, serverError, serverError, nil},
+			Calls: 3,
+			Slept: []time.Duration{time.Second, 2 * time.Second},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			slept := stubRetrySleep(t)
+			calls := 0
+			err := withRetry(context.Background(), "test", func() error {
+				calls++
+				return test.Errs[calls-1]
+			})
+			if !errors.Is(err, test.Errs[calls-1]) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if calls != test.Calls {
+				t.Errorf("Unexpected calls: %v", calls)
+			}
+			if diff := cmp.Diff(test.Slept, *slept); diff != "" {
+				t.Errorf("Unexpected sleeps. (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
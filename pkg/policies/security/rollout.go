@@ -0,0 +1,238 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/runid"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// Values of RolloutResult.Skipped.
+const (
+	RolloutSkippedPassing = "passing"
+	RolloutSkippedOpenPR  = "open pull request"
+	RolloutSkippedCap     = "cap reached"
+	// The repo has a security policy, but it fails for reasons that adding
+	// SECURITY.md doesn't fix.
+	RolloutSkippedNotFixable = "not fixable"
+	// The repo has dryRun set in its config.
+	RolloutSkippedDryRun = "dry run"
+)
+
+// RolloutOptions configures Rollout.
+type RolloutOptions struct {
+	// DryRun, if true, only reports which repos would get a pull request and
+	// who would be requested to review it. Nothing is written.
+	DryRun bool
+
+	// Max is the maximum number of pull requests opened. Zero means no limit.
+	Max int
+}
+
+// RolloutResult is the outcome of Rollout for a single repo.
+type RolloutResult struct {
+	// PR is the number of the opened pull request, zero if none was opened.
+	PR int
+
+	// Planned is true if a pull request was, or in a dry run would be, opened.
+	Planned bool
+
+	// Reviewers are the code owners of the security policy requested, or in a
+	// dry run to be requested, to review the pull request.
+	Reviewers []string
+
+	// Skipped is why no pull request is planned, such as RolloutSkippedOpenPR.
+	// Empty if the repo is planned, or on error.
+	Skipped string
+
+	// Err is the error from checking or opening the pull request, if any.
+	Err error
+}
+
+type rolloutPulls interface {
+	pullLister
+	RequestReviewers(context.Context, string, string, int, github.ReviewersRequest) (
+		*github.PullRequest, *github.Response, error)
+}
+
+// rolloutClients holds the GitHub services used by Rollout, to allow mocking.
+type rolloutClients struct {
+	fix   fixClients
+	repos repositories
+	pulls rolloutPulls
+}
+
+var newRolloutClients func(*github.Client) rolloutClients
+
+func init() {
+	newRolloutClients = func(c *github.Client) rolloutClients {
+		return rolloutClients{
+			fix:   newFixClients(c),
+			repos: c.Repositories,
+			pulls: c.PullRequests,
+		}
+	}
+}
+
+// Rollout opens a pull request adding the default security policy on each of
+// the provided repos that is enabled and missing a security policy, regardless
+// of the configured fixMode, and requests review from the code owners of the
+// security policy. Repos that already have an open Allstar pull request, or
+// that are configured for dryRun, are skipped. At most opts.Max pull requests
+// are opened, in repo name order. With opts.DryRun, repos are checked but
+// nothing is written. Repos are checked as in Scan, and an error on one repo
+// does not stop the others. The outcome for every repo is returned keyed by
+// repo name.
+func Rollout(ctx context.Context, c *github.Client, owner string, repos []string,
+	opts RolloutOptions) map[string]*RolloutResult {
+	rc := newRolloutClients(c)
+	results := make(map[string]*RolloutResult, len(repos))
+	err := scan(ctx, c, owner, repos, operator.BatchConcurrency, func(sr *ScanResult) {
+		results[sr.Repo] = planRollout(ctx, rc, c, owner, sr)
+	})
+
+	sorted := append([]string(nil), repos...)
+	sort.Strings(sorted)
+	n := 0
+	for _, repo := range sorted {
+		rr, ok := results[repo]
+		if !ok {
+			// Not checked, as ctx was cancelled.
+			results[repo] = &RolloutResult{Err: err}
+			continue
+		}
+		if !rr.Planned {
+			continue
+		}
+		if opts.Max > 0 && n >= opts.Max {
+			rr.Planned = false
+			rr.Skipped = RolloutSkippedCap
+			continue
+		}
+		n++
+		if opts.DryRun {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			rr.Err = err
+			continue
+		}
+		rr.PR, rr.Err = rolloutPR(ctx, rc, c, owner, repo, rr.Reviewers)
+	}
+	runid.Logger(ctx).Info().
+		Str("org", owner).
+		Str("area", polName).
+		Bool("dryRun", opts.DryRun).
+		Int("planned", n).
+		Msg("Security policy rollout complete.")
+	return results
+}
+
+// planRollout determines from the check result of the repo whether it should
+// get a pull request, and who should review it.
+func planRollout(ctx context.Context, rc rolloutClients, c *github.Client, owner string,
+	sr *ScanResult) *RolloutResult {
+	if sr.Err != nil {
+		return &RolloutResult{Err: sr.Err}
+	}
+	repo, r := sr.Repo, sr.Result
+	if !r.Enabled || r.Pass {
+		return &RolloutResult{Skipped: RolloutSkippedPassing}
+	}
+	if !fixable(r.Reasons) {
+		return &RolloutResult{Skipped: RolloutSkippedNotFixable}
+	}
+	if resolveConfig(ctx, c, owner, repo).DryRun {
+		return &RolloutResult{Skipped: RolloutSkippedDryRun}
+	}
+	open, err := hasOpenFixPR(ctx, rc.pulls, owner, repo)
+	if err != nil {
+		return &RolloutResult{Err: err}
+	}
+	if open {
+		return &RolloutResult{Skipped: RolloutSkippedOpenPR}
+	}
+	_, co, err := getFirstFile(ctx, rc.repos, owner, repo, codeOwnersPaths)
+	if err != nil {
+		return &RolloutResult{Err: err}
+	}
+	var reviewers []string
+	for _, o := range codeOwnersFor(co, fixPath) {
+		// Email owners can't be requested to review.
+		if strings.HasPrefix(o, "@") {
+			reviewers = append(reviewers, o)
+		}
+	}
+	return &RolloutResult{Planned: true, Reviewers: reviewers}
+}
+
+// hasOpenFixPR reports whether the repo has an open pull request from the fix
+// action's branch.
+func hasOpenFixPR(ctx context.Context, p rolloutPulls, owner, repo string) (bool, error) {
+	pr, err := findOpenFixPR(ctx, p, owner, repo)
+	return pr != nil, err
+}
+
+// rolloutPR opens the pull request on the repo and requests review from
+// reviewers. A failure to request review is logged, as the pull request is
+// already open.
+func rolloutPR(ctx context.Context, rc rolloutClients, c *github.Client, owner, repo string,
+	reviewers []string) (int, error) {
+	if config.IsReadOnlyOrg(owner) {
+		return 0, errReadOnlyOrg
+	}
+	mc := resolveConfig(ctx, c, owner, repo)
+	r, _, err := rc.fix.repos.Get(ctx, owner, repo)
+	if err != nil {
+		return 0, err
+	}
+	p, err := planMode(ctx, rc.fix, mc, owner, repo, r.GetDefaultBranch(), fixModePR)
+	if err != nil {
+		return 0, err
+	}
+	pr, err := applyFix(ctx, rc.fix, owner, repo, p)
+	if err != nil {
+		return 0, err
+	}
+	if len(reviewers) == 0 {
+		return pr.GetNumber(), nil
+	}
+	var req github.ReviewersRequest
+	for _, o := range reviewers {
+		o = strings.TrimPrefix(o, "@")
+		if parts := strings.SplitN(o, "/", 2); len(parts) == 2 {
+			req.TeamReviewers = append(req.TeamReviewers, parts[1])
+		} else {
+			req.Reviewers = append(req.Reviewers, o)
+		}
+	}
+	if _, _, err := rc.pulls.RequestReviewers(ctx, owner, repo, pr.GetNumber(), req); err != nil {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Int("pr", pr.GetNumber()).
+			Err(err).
+			Msg("Unable to request review from code owners.")
+	}
+	return pr.GetNumber(), nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+type mockRolloutPulls struct {
+	mu       sync.Mutex
+	requests []github.ReviewersRequest
+}
+
+func (m *mockRolloutPulls) List(ctx context.Context, owner, repo string,
+	opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	if repo == "openpr" && opts.Head == owner+":"+fixBranch && opts.State == "open" {
+		return []*github.PullRequest{{Number: github.Int(3)}}, nil, nil
+	}
+	return nil, nil, nil
+}
+
+func (m *mockRolloutPulls) RequestReviewers(ctx context.Context, owner, repo string, number int,
+	req github.ReviewersRequest) (*github.PullRequest, *github.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, req)
+	return nil, nil, nil
+}
+
+func TestRollout(t *testing.T) {
+	securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+		if repo == "tooshort" {
+			return &policydef.Result{Enabled: true, Pass: false, Reasons: []string{ReasonTooShort}}, nil
+		}
+		return &policydef.Result{Enabled: true, Pass: repo == "passing", Reasons: []string{ReasonMissing}}, nil
+	}
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner, repo, path string, out interface{}) error {
+		if rc, ok := out.(*RepoConfig); ok && repo == "dryrun" {
+			rc.DryRun = github.Bool(true)
+		}
+		return nil
+	}
+	getContents = contentsMock(map[string]string{
+		".github/CODEOWNERS": "* @org/security @jane security@example.com\n",
+	})
+	repos := []string{"passing", "openpr", "tooshort", "dryrun", "c-fail", "a-fail", "b-fail"}
+	tests := []struct {
+		Name     string
+		Opts     RolloutOptions
+		Want     map[string]*RolloutResult
+		Requests int
+	}{
+		{
+			Name: "DryRun",
+			Opts: RolloutOptions{DryRun: true, Max: 2},
+			Want: map[string]*RolloutResult{
+				"passing":  {Skipped: RolloutSkippedPassing},
+				"openpr":   {Skipped: RolloutSkippedOpenPR},
+				"tooshort": {Skipped: RolloutSkippedNotFixable},
+				"dryrun":   {Skipped: RolloutSkippedDryRun},
+				"a-fail":   {Planned: true, Reviewers: []string{"@org/security", "@jane"}},
+				"b-fail":   {Planned: true, Reviewers: []string{"@org/security", "@jane"}},
+				"c-fail":   {Skipped: RolloutSkippedCap, Reviewers: []string{"@org/security", "@jane"}},
+			},
+		},
+		{
+			Name: "Apply",
+			Opts: RolloutOptions{Max: 2},
+			Want: map[string]*RolloutResult{
+				"passing":  {Skipped: RolloutSkippedPassing},
+				"openpr":   {Skipped: RolloutSkippedOpenPR},
+				"tooshort": {Skipped: RolloutSkippedNotFixable},
+				"dryrun":   {Skipped: RolloutSkippedDryRun},
+				"a-fail":   {PR: 7, Planned: true, Reviewers: []string{"@org/security", "@jane"}},
+				"b-fail":   {PR: 7, Planned: true, Reviewers: []string{"@org/security", "@jane"}},
+				"c-fail":   {Skipped: RolloutSkippedCap, Reviewers: []string{"@org/security", "@jane"}},
+			},
+			Requests: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			m := &mockFix{}
+			p := &mockRolloutPulls{}
+			newRolloutClients = func(c *github.Client) rolloutClients {
+				return rolloutClients{
+					fix:   fixClients{repos: m, git: m, pulls: m, issues: &mockFixIssues{}},
+					repos: mockRepos{},
+					pulls: p,
+				}
+			}
+			got := Rollout(context.Background(), nil, "org", repos, test.Opts)
+			if diff := cmp.Diff(test.Want, got); diff != "" {
+				t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+			}
+			if test.Opts.DryRun && m.pr != nil {
+				t.Errorf("Expected no pull request in dry run, got: %v", m.pr)
+			}
+			if len(p.requests) != test.Requests {
+				t.Fatalf("Expected %v review requests, got: %v", test.Requests, len(p.requests))
+			}
+			want := github.ReviewersRequest{
+				Reviewers:     []string{"jane"},
+				TeamReviewers: []string{"security"},
+			}
+			for _, r := range p.requests {
+				if diff := cmp.Diff(want, r); diff != "" {
+					t.Errorf("Unexpected review request. (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestRolloutCancelled(t *testing.T) {
+	securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+		return &policydef.Result{Enabled: true, Pass: false, Reasons: []string{ReasonMissing}}, nil
+	}
+	m := &mockFix{}
+	newRolloutClients = func(c *github.Client) rolloutClients {
+		return rolloutClients{
+			fix:   fixClients{repos: m, git: m, pulls: m, issues: &mockFixIssues{}},
+			repos: mockRepos{},
+			pulls: &mockRolloutPulls{},
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	repos := []string{"a", "b", "c"}
+	got := Rollout(ctx, nil, "org", repos, RolloutOptions{})
+	for _, repo := range repos {
+		if rr := got[repo]; rr == nil || rr.Err != context.Canceled {
+			t.Errorf("Expected %v to be cancelled, got: %+v", repo, rr)
+		}
+	}
+	if m.pr != nil {
+		t.Errorf("Expected no pull request, got: %v", m.pr)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ossf/allstar/pkg/runid"
+)
+
+// RulesetInput is the security policy and repo metadata passed to a
+// RulesetEvaluator.
+type RulesetInput struct {
+	Owner string
+	Repo  string
+
+	// URL is the security policy URL reported by GitHub.
+	URL string
+
+	// Path is the path of the security policy file, empty if not found.
+	Path string
+
+	// Content is the security policy file contents.
+	Content string
+}
+
+// RulesetFinding is a single violation reported by a RulesetEvaluator.
+type RulesetFinding struct {
+	// Rule identifies the rule that produced the finding.
+	Rule string
+
+	// Message describes the finding to repo maintainers.
+	Message string
+}
+
+// RulesetEvaluator evaluates a security policy against an org's ruleset, such
+// as an OPA/Rego or CUE policy. Allstar does not include an evaluator,
+// operators running Allstar register their own with RegisterEvaluator.
+type RulesetEvaluator interface {
+	// Evaluate evaluates in against ruleset, as configured in Ruleset, and
+	// returns any findings. An error means the evaluation could not be
+	// performed.
+	Evaluate(ctx context.Context, ruleset string, in *RulesetInput) ([]RulesetFinding, error)
+}
+
+var evaluatorsMu sync.RWMutex
+var evaluators = make(map[string]RulesetEvaluator)
+
+// RegisterEvaluator makes a RulesetEvaluator available to orgs by name, for use
+// in RulesetEvaluator config. It is intended to be called at startup.
+func RegisterEvaluator(name string, e RulesetEvaluator) {
+	evaluatorsMu.Lock()
+	defer evaluatorsMu.Unlock()
+	evaluators[name] = e
+}
+
+func evaluateRuleset(ctx context.Context, mc *mergedConfig, in *RulesetInput) ([]RulesetFinding, error) {
+	evaluatorsMu.RLock()
+	e, ok := evaluators[mc.RulesetEvaluator]
+	evaluatorsMu.RUnlock()
+	if !ok {
+		runid.Logger(ctx).Warn().
+			Str("org", in.Owner).
+			Str("repo", in.Repo).
+			Str("area", polName).
+			Str("evaluator", mc.RulesetEvaluator).
+			Msg("Unknown ruleset evaluator, skipping ruleset.")
+		return nil, nil
+	}
+	findings, err := e.Evaluate(ctx, mc.Ruleset, in)
+	if err != nil {
+		return nil, fmt.Errorf("ruleset evaluator %v: %w", mc.RulesetEvaluator, err)
+	}
+	return findings, nil
+}
+
+func rulesetNotifyText(findings []RulesetFinding) string {
+	var b strings.Builder
+	b.WriteString("Security policy does not meet this organization's requirements:\n")
+	for _, f := range findings {
+		fmt.Fprintf(&b, "- %v: %v\n", f.Rule, f.Message)
+	}
+	return b.String()
+}
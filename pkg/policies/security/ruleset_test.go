@@ -0,0 +1,129 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ossf/allstar/pkg/config"
+)
+
+type mockEvaluator struct {
+	in  *RulesetInput
+	err error
+}
+
+func (m *mockEvaluator) Evaluate(ctx context.Context, ruleset string, in *RulesetInput) ([]RulesetFinding, error) {
+	m.in = in
+	if m.err != nil {
+		return nil, m.err
+	}
+	if ruleset == "require-email" && !strings.Contains(in.Content, "@") {
+		return []RulesetFinding{{Rule: "email", Message: "No contact email."}}, nil
+	}
+	return nil, nil
+}
+
+func TestCheckRuleset(t *testing.T) {
+	query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+		qc := q.(*struct {
+			Repository struct {
+				SecurityPolicyUrl       string
+				IsSecurityPolicyEnabled bool
+				IsArchived              bool
+				IsDisabled              bool
+				DefaultBranchRef        struct {
+					Name string
+				}
+			} `graphql:"repository(owner: $owner, name: $name)"`
+		})
+		qc.Repository.IsSecurityPolicyEnabled = true
+		qc.Repository.DefaultBranchRef.Name = "main"
+		return nil
+	}
+	getContents = contentsMock(map[string]string{
+		"SECURITY.md": "Report issues on the issue tracker.",
+	})
+	m := &mockEvaluator{}
+	RegisterEvaluator("mock", m)
+	defer delete(evaluators, "mock")
+
+	tests := []struct {
+		Name      string
+		Evaluator string
+		Ruleset   string
+		Err       error
+		Pass      bool
+		Reasons   []string
+	}{
+		{
+			Name:      "Finding",
+			Evaluator: "mock",
+			Ruleset:   "require-email",
+			Pass:      false,
+			Reasons:   []string{ReasonRuleset},
+		},
+		{
+			Name:      "NoFinding",
+			Evaluator: "mock",
+			Ruleset:   "other",
+			Pass:      true,
+		},
+		{
+			Name:      "UnknownEvaluator",
+			Evaluator: "rego",
+			Pass:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			oc := &OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+				},
+				RulesetEvaluator: test.Evaluator,
+				Ruleset:          test.Ruleset,
+			}
+			res, err := checkConfig(context.Background(), mockRepos{}, nil, mockClient{},
+				"org", "thisrepo", oc, &RepoConfig{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Pass != test.Pass {
+				t.Errorf("Unexpected pass. Expected: %v Got: %v", test.Pass, res.Pass)
+			}
+			if diff := cmp.Diff(test.Reasons, res.Reasons); diff != "" {
+				t.Errorf("Unexpected reasons. (-want +got):\n%s", diff)
+			}
+		})
+	}
+	if m.in == nil || m.in.Path != "SECURITY.md" || m.in.Owner != "org" {
+		t.Errorf("Unexpected evaluator input: %+v", m.in)
+	}
+
+	m.err = errors.New("eval failed")
+	oc := &OrgConfig{
+		OptConfig:        config.OrgOptConfig{OptOutStrategy: true},
+		RulesetEvaluator: "mock",
+	}
+	if _, err := checkConfig(context.Background(), mockRepos{}, nil, mockClient{},
+		"org", "thisrepo", oc, &RepoConfig{}); err == nil {
+		t.Errorf("Expected evaluator error")
+	}
+}
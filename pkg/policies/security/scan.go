@@ -0,0 +1,219 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// ScanResult is the outcome of checking a single repo during Scan.
+type ScanResult struct {
+	// Owner is the org of the repo.
+	Owner string
+
+	// Repo is the repo name.
+	Repo string
+
+	// Result is the policy result, nil if Err is set.
+	Result *policydef.Result
+
+	// Err is the error from checking the repo, if any.
+	Err error
+}
+
+// Scan checks each of the provided repos and calls fn with each result as soon
+// as it is available, without buffering results for the whole org. Up to
+// operator.BatchConcurrency repos are checked at once. Calls to fn are
+// serialized, so fn does not need to be safe for concurrent use. If ctx is
+// cancelled, no further repos are checked and ctx.Err() is returned once
+// in-flight checks finish.
+func Scan(ctx context.Context, c *github.Client, owner string, repos []string,
+	fn func(*ScanResult)) error {
+	return scan(ctx, c, owner, repos, operator.BatchConcurrency, fn)
+}
+
+// CheckErrors is the error returned by CheckRepos when some repos failed to be
+// checked, keyed by repo name.
+type CheckErrors map[string]error
+
+func (e CheckErrors) Error() string {
+	repos := make([]string, 0, len(e))
+	for repo := range e {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	msgs := make([]string, len(repos))
+	for i, repo := range repos {
+		msgs[i] = fmt.Sprintf("%v: %v", repo, e[repo])
+	}
+	return fmt.Sprintf("checking %d repos failed: %v", len(e), strings.Join(msgs, "; "))
+}
+
+// CheckRepos checks each of the provided repos and returns the results keyed by
+// repo name. Up to concurrency repos are checked at once, or
+// operator.BatchConcurrency if concurrency is not positive. An error on one repo
+// does not stop the others: repos that failed are left out of the results and
+// returned in a CheckErrors. If ctx is cancelled, no further repos are checked
+// and the results so far are returned with ctx.Err().
+func CheckRepos(ctx context.Context, c *github.Client, owner string, repos []string,
+	concurrency int) (map[string]*policydef.Result, error) {
+	if concurrency <= 0 {
+		concurrency = operator.BatchConcurrency
+	}
+	results := make(map[string]*policydef.Result, len(repos))
+	errs := make(CheckErrors)
+	err := scan(ctx, c, owner, repos, concurrency, func(r *ScanResult) {
+		if r.Err != nil {
+			errs[r.Repo] = r.Err
+			return
+		}
+		results[r.Repo] = r.Result
+	})
+	if err != nil {
+		return results, err
+	}
+	if len(errs) > 0 {
+		return results, errs
+	}
+	return results, nil
+}
+
+func scan(ctx context.Context, c *github.Client, owner string, repos []string,
+	concurrency int, fn func(*ScanResult)) error {
+	work := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repo := range work {
+				r, err := securityCheck(ctx, c, owner, repo)
+				mu.Lock()
+				fn(&ScanResult{
+					Owner:  owner,
+					Repo:   repo,
+					Result: r,
+					Err:    err,
+				})
+				mu.Unlock()
+			}
+		}()
+	}
+	var err error
+dispatch:
+	for _, repo := range repos {
+		select {
+		case work <- repo:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break dispatch
+		}
+	}
+	close(work)
+	wg.Wait()
+	return err
+}
+
+type ownerUsers interface {
+	Get(context.Context, string) (*github.User, *github.Response, error)
+}
+
+type ownerRepositories interface {
+	ListByOrg(context.Context, string, *github.RepositoryListByOrgOptions) (
+		[]*github.Repository, *github.Response, error)
+	List(context.Context, string, *github.RepositoryListOptions) (
+		[]*github.Repository, *github.Response, error)
+}
+
+// ownerClients holds the GitHub services used to list an owner's repos, to
+// allow mocking.
+type ownerClients struct {
+	users ownerUsers
+	repos ownerRepositories
+}
+
+var newOwnerClients func(*github.Client) ownerClients
+
+func init() {
+	newOwnerClients = func(c *github.Client) ownerClients {
+		return ownerClients{
+			users: c.Users,
+			repos: c.Repositories,
+		}
+	}
+}
+
+// ScanOwner is Scan over all non-archived repos of owner, which may be an org
+// or a user account. For a user account, only the repos the user owns are
+// scanned, and config is read from operator.UserConfigRepo.
+func ScanOwner(ctx context.Context, c *github.Client, owner string, fn func(*ScanResult)) error {
+	oc := newOwnerClients(c)
+	u, _, err := oc.users.Get(ctx, owner)
+	if err != nil {
+		return err
+	}
+	repos, err := listOwnerRepos(ctx, oc.repos, owner, u.GetType())
+	if err != nil {
+		return err
+	}
+	return Scan(config.WithOwnerType(ctx, u.GetType()), c, owner, repos, fn)
+}
+
+// listOwnerRepos returns the names of the non-archived repos of owner, with the
+// API for its account type.
+func listOwnerRepos(ctx context.Context, rep ownerRepositories, owner, ownerType string) ([]string, error) {
+	var names []string
+	page := 1
+	for page != 0 {
+		var rs []*github.Repository
+		var resp *github.Response
+		var err error
+		lo := github.ListOptions{PerPage: 100, Page: page}
+		if ownerType == config.OwnerTypeUser {
+			rs, resp, err = rep.List(ctx, owner, &github.RepositoryListOptions{
+				Type:        "owner",
+				ListOptions: lo,
+			})
+		} else {
+			rs, resp, err = rep.ListByOrg(ctx, owner, &github.RepositoryListByOrgOptions{
+				ListOptions: lo,
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			if !r.GetArchived() {
+				names = append(names, r.GetName())
+			}
+		}
+		page = 0
+		if resp != nil {
+			page = resp.NextPage
+		}
+	}
+	return names, nil
+}
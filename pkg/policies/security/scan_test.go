@@ -0,0 +1,194 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config"
+	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+func TestScan(t *testing.T) {
+	securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+		return &policydef.Result{Enabled: true, Pass: repo != "failing"}, nil
+	}
+	var got []string
+	err := Scan(context.Background(), nil, "org", []string{"a", "failing", "b"},
+		func(r *ScanResult) {
+			if r.Result.Pass == (r.Repo == "failing") {
+				t.Errorf("Unexpected result for %v", r.Repo)
+			}
+			got = append(got, r.Repo)
+		})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sort.Strings(got)
+	if len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "failing" {
+		t.Errorf("Unexpected repos: %v", got)
+	}
+}
+
+func TestScanCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+		return &policydef.Result{}, nil
+	}
+	repos := make([]string, 1000)
+	n := 0
+	err := Scan(ctx, nil, "org", repos, func(r *ScanResult) { n++ })
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if n == len(repos) {
+		t.Errorf("Expected scan to stop early")
+	}
+}
+
+func TestCheckRepos(t *testing.T) {
+	checkErr := errors.New("check failed")
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if repo == "checkerr" {
+			return nil, checkErr
+		}
+		return &policydef.Result{Enabled: true, Pass: repo != "failing"}, nil
+	}
+	repos := []string{"checkerr", "failing"}
+	for i := 0; i < 20; i++ {
+		repos = append(repos, fmt.Sprintf("repo%d", i))
+	}
+	got, err := CheckRepos(context.Background(), nil, "org", repos, 3)
+	var errs CheckErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected CheckErrors, got: %v", err)
+	}
+	if len(errs) != 1 || errs["checkerr"] != checkErr {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+	if len(got) != len(repos)-1 {
+		t.Errorf("Unexpected number of results: %v", len(got))
+	}
+	if got["failing"].Pass || !got["repo0"].Pass {
+		t.Errorf("Unexpected results: %v %v", got["failing"], got["repo0"])
+	}
+	if maxRunning > 3 {
+		t.Errorf("Exceeded concurrency, %v checks running at once", maxRunning)
+	}
+}
+
+func TestCheckReposCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+		return &policydef.Result{}, nil
+	}
+	repos := make([]string, 1000)
+	for i := range repos {
+		repos[i] = fmt.Sprint(i)
+	}
+	got, err := CheckRepos(ctx, nil, "org", repos, 0)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if len(got) == len(repos) {
+		t.Errorf("Expected checks to stop early")
+	}
+}
+
+type mockOwner struct {
+	ownerType string
+}
+
+func (m mockOwner) Get(ctx context.Context, user string) (*github.User, *github.Response, error) {
+	return &github.User{Type: github.String(m.ownerType)}, nil, nil
+}
+
+func (m mockOwner) ListByOrg(ctx context.Context, org string,
+	opts *github.RepositoryListByOrgOptions) ([]*github.Repository, *github.Response, error) {
+	if m.ownerType == config.OwnerTypeUser {
+		return nil, nil, errors.New("not an org")
+	}
+	if opts.Page == 1 {
+		return []*github.Repository{{Name: github.String("a")}}, &github.Response{NextPage: 2}, nil
+	}
+	return []*github.Repository{{Name: github.String("b")}, {Name: github.String("old"), Archived: github.Bool(true)}},
+		&github.Response{}, nil
+}
+
+func (m mockOwner) List(ctx context.Context, user string,
+	opts *github.RepositoryListOptions) ([]*github.Repository, *github.Response, error) {
+	if m.ownerType != config.OwnerTypeUser || opts.Type != "owner" {
+		return nil, nil, errors.New("unexpected user repo listing")
+	}
+	return []*github.Repository{{Name: github.String("mine")}}, &github.Response{}, nil
+}
+
+func TestScanOwner(t *testing.T) {
+	tests := []struct {
+		Type       string
+		Repos      []string
+		ConfigRepo string
+	}{
+		{Type: "Organization", Repos: []string{"a", "b"}, ConfigRepo: operator.OrgConfigRepo},
+		{Type: config.OwnerTypeUser, Repos: []string{"mine"}, ConfigRepo: operator.UserConfigRepo},
+	}
+	for _, test := range tests {
+		t.Run(test.Type, func(t *testing.T) {
+			newOwnerClients = func(c *github.Client) ownerClients {
+				return ownerClients{users: mockOwner{test.Type}, repos: mockOwner{test.Type}}
+			}
+			securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+				if got := config.ConfigRepo(ctx); got != test.ConfigRepo {
+					t.Errorf("Unexpected config repo. Expected: %v Got: %v", test.ConfigRepo, got)
+				}
+				return &policydef.Result{Enabled: true, Pass: true}, nil
+			}
+			var got []string
+			err := ScanOwner(context.Background(), nil, "owner", func(r *ScanResult) {
+				got = append(got, r.Repo)
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(test.Repos, got); diff != "" {
+				t.Errorf("Unexpected repos. (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
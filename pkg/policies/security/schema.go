@@ -0,0 +1,86 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// OrgConfigSchema returns a JSON Schema describing the org-level security.yaml
+// config, generated from OrgConfig so that it stays in sync as options are
+// added. Non-zero defaults are included.
+func OrgConfigSchema() ([]byte, error) {
+	return configSchema("SECURITY.md policy org-level config", defaultOrgConfig())
+}
+
+// RepoConfigSchema returns a JSON Schema describing the repo-level
+// security.yaml config, generated from RepoConfig.
+func RepoConfigSchema() ([]byte, error) {
+	return configSchema("SECURITY.md policy repo-level config", &RepoConfig{})
+}
+
+func configSchema(title string, def interface{}) ([]byte, error) {
+	s := typeSchema(reflect.TypeOf(def).Elem(), reflect.ValueOf(def).Elem())
+	s["$schema"] = schemaDraft
+	s["title"] = title
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// typeSchema returns the schema of t. If def is valid and not the zero value,
+// it is included as the default.
+func typeSchema(t reflect.Type, def reflect.Value) map[string]interface{} {
+	s := make(map[string]interface{})
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if def.IsValid() {
+			def = def.Elem()
+		}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		s["type"] = "boolean"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		s["type"] = "integer"
+	case reflect.String:
+		s["type"] = "string"
+	case reflect.Slice:
+		s["type"] = "array"
+		s["items"] = typeSchema(t.Elem(), reflect.Value{})
+	case reflect.Map:
+		s["type"] = "object"
+		s["additionalProperties"] = typeSchema(t.Elem(), reflect.Value{})
+	case reflect.Struct:
+		s["type"] = "object"
+		s["additionalProperties"] = false
+		props := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			var fd reflect.Value
+			if def.IsValid() {
+				fd = def.Field(i)
+			}
+			props[yamlName(f)] = typeSchema(f.Type, fd)
+		}
+		s["properties"] = props
+		return s
+	}
+	if def.IsValid() && !def.IsZero() {
+		s["default"] = def.Interface()
+	}
+	return s
+}
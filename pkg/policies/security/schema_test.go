@@ -0,0 +1,72 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOrgConfigSchema(t *testing.T) {
+	b, err := OrgConfigSchema()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var s struct {
+		Type       string `json:"type"`
+		Properties map[string]struct {
+			Type    string      `json:"type"`
+			Default interface{} `json:"default"`
+			Items   struct {
+				Type string `json:"type"`
+			} `json:"items"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := reflect.TypeOf(OrgConfig{}).NumField(); len(s.Properties) != want {
+		t.Errorf("Expected %v properties, got: %v", want, len(s.Properties))
+	}
+	if p := s.Properties["action"]; p.Type != "string" || p.Default != "log" {
+		t.Errorf("Unexpected action schema: %+v", p)
+	}
+	if p := s.Properties["rollout"]; p.Type != "array" || p.Items.Type != "object" {
+		t.Errorf("Unexpected rollout schema: %+v", p)
+	}
+	if p := s.Properties["optConfig"]; p.Properties["optOutStrategy"] == nil {
+		t.Errorf("Unexpected optConfig schema: %+v", p)
+	}
+}
+
+func TestRepoConfigSchema(t *testing.T) {
+	b, err := RepoConfigSchema()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var s struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p := s.Properties["minStarsForIssue"]; p.Type != "integer" {
+		t.Errorf("Unexpected minStarsForIssue schema: %+v", p)
+	}
+}
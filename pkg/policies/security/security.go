@@ -17,15 +17,21 @@ package security
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/ossf/allstar/pkg/config"
 	"github.com/ossf/allstar/pkg/config/operator"
+	"github.com/ossf/allstar/pkg/ghclients"
+	"github.com/ossf/allstar/pkg/issue"
 	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/ossf/allstar/pkg/runid"
 
 	"github.com/google/go-github/v39/github"
-	"github.com/rs/zerolog/log"
 	"github.com/shurcooL/githubv4"
 )
 
@@ -38,6 +44,76 @@ To fix this, add a SECURITY.md file that explains how to handle vulnerabilities
 
 For more information, see https://docs.github.com/en/code-security/getting-started/adding-a-security-policy-to-your-repository.`
 
+// Reason codes reported in policydef.Result.Reasons.
+const (
+	// ReasonMissing : the repo has no security policy.
+	ReasonMissing = "security_policy_missing"
+
+	// ReasonPublicDisclosure : the security policy instructs public disclosure,
+	// see DisallowPublicDisclosure.
+	ReasonPublicDisclosure = "security_policy_public_disclosure"
+	// ReasonCodeOwnerReview : the last change to the security policy was not
+	// approved by a code owner, see RequireCodeOwnerReview.
+	ReasonCodeOwnerReview = "security_policy_codeowner_review"
+	// ReasonSupportedVersions : the security policy does not list supported
+	// versions, see RequireSupportedVersions.
+	ReasonSupportedVersions = "security_policy_supported_versions"
+	// ReasonTimeline : the security policy does not commit to a response or
+	// disclosure timeline, see RequireTimeline.
+	ReasonTimeline = "security_policy_timeline"
+	// ReasonReadmeLink : the README does not link to the security policy, see
+	// RequireReadmeLink.
+	ReasonReadmeLink = "security_policy_readme_link"
+	// ReasonContactChannels : the security policy lists too few contact
+	// channels, see MinContactChannels.
+	ReasonContactChannels = "security_policy_contact_channels"
+	// ReasonContactDomain : the security policy lists a contact outside the
+	// approved domains, see RequireContactDomains.
+	ReasonContactDomain = "security_policy_contact_domain"
+	// ReasonGroupDrift : the security policy differs from the canonical repo of
+	// its group, see RepoGroups.
+	ReasonGroupDrift = "security_policy_group_drift"
+	// ReasonBrokenPolicyURL : the security policy URL reported by GitHub does
+	// not resolve, see VerifyPolicyURL.
+	ReasonBrokenPolicyURL = "security_policy_broken_url"
+	// ReasonRawURL : the security policy file is not reachable at its raw URL,
+	// see VerifyRawURL.
+	ReasonRawURL = "security_policy_raw_url"
+	// ReasonRegression : the security policy was weakened compared to its
+	// baseline, see DetectRegression.
+	ReasonRegression = "security_policy_regression"
+	// ReasonSubPathMissing : a subdirectory listed in SubPaths does not have a
+	// security policy.
+	ReasonSubPathMissing = "security_policy_subpath_missing"
+	// ReasonBranchMissing : fewer than BranchQuorum of the branches matching
+	// Branches have a security policy.
+	ReasonBranchMissing = "security_policy_branch_missing"
+	// ReasonMissingWithAdvisories : in addition to ReasonMissing, the repo has
+	// open vulnerability alerts, see EscalateOnAdvisories.
+	ReasonMissingWithAdvisories = "security_policy_missing_with_advisories"
+	// ReasonRuleset : the security policy has findings from the configured
+	// ruleset, see Ruleset.
+	ReasonRuleset = "security_policy_ruleset"
+	// ReasonNotRegularFile : the security policy is a symlink, submodule, or
+	// empty file, see RequireRegularFile.
+	ReasonNotRegularFile = "security_policy_not_regular_file"
+	// ReasonBadEncoding : the security policy is binary or not valid UTF-8
+	// text, so content checks can't be applied.
+	ReasonBadEncoding = "security_policy_bad_encoding"
+	// ReasonPlaceholder : the security policy only contains template generator
+	// boilerplate, see DisallowPlaceholder.
+	ReasonPlaceholder = "security_policy_placeholder"
+	// ReasonRequiredContents : the security policy is missing content required
+	// by RequiredContents.
+	ReasonRequiredContents = "security_policy_required_contents"
+	// ReasonTooShort : the security policy is shorter than MinLength.
+	ReasonTooShort = "security_policy_too_short"
+	// ReasonNoAccess : the App could not access the repo's security policy or
+	// content, so compliance is unknown. Reported on a result that is not
+	// enabled, so no action is taken.
+	ReasonNoAccess = "security_policy_no_access"
+)
+
 // OrgConfig is the org-level config definition for Branch Protection.
 type OrgConfig struct {
 	// OptConfig is the standard org-level opt in/out config, RepoOverride applies to all
@@ -47,7 +123,332 @@ type OrgConfig struct {
 	// Action defines which action to take, default log, other: issue...
 	Action string `yaml:"action"`
 
-	//TODO add default contents for "fix" action
+	// RolloutStart is the date (YYYY-MM-DD) that the Rollout stages begin. Before
+	// this date Action is used.
+	RolloutStart string `yaml:"rolloutStart"`
+
+	// Rollout is a list of stages to advance the action through, starting at
+	// RolloutStart. Each stage lasts for its number of days, the last stage
+	// remains in effect once reached. When set, this replaces Action.
+	Rollout []RolloutStage `yaml:"rollout"`
+
+	// MinStarsForIssue is the minimum number of stars a repo must have for the
+	// issue action to be taken. Repos below this count use the log action
+	// instead. Default 0, issue action applies to all repos.
+	MinStarsForIssue int `yaml:"minStarsForIssue"`
+
+	// Actions maps failure reasons, such as "security_policy_missing", to the
+	// action to take for them, in place of Action. If a result has several
+	// mapped reasons, the first is used. Unmapped reasons use Action. Default
+	// empty.
+	Actions map[string]string `yaml:"actions"`
+
+	// DisallowPublicDisclosure : set to true to fail the policy if SECURITY.md
+	// instructs reporters to open a public issue for security
+	// vulnerabilities. This is a heuristic check, default false.
+	DisallowPublicDisclosure bool `yaml:"disallowPublicDisclosure"`
+
+	// PublicDisclosurePatterns are case-insensitive regular expressions that
+	// identify an instruction to open a public issue. A line matches if it also
+	// mentions security or vulnerabilities and is not negated. Defaults to a
+	// built-in set of patterns.
+	PublicDisclosurePatterns []string `yaml:"publicDisclosurePatterns"`
+
+	// DisallowPlaceholder : set to true to fail the policy if SECURITY.md only
+	// contains boilerplate left by a template generator, such as GitHub's "To
+	// set up a security policy" text or a link to the template chooser, rather
+	// than reporting instructions. Default false.
+	DisallowPlaceholder bool `yaml:"disallowPlaceholder"`
+
+	// PlaceholderPatterns are case-insensitive regular expressions that
+	// identify template generator boilerplate for DisallowPlaceholder. The file
+	// is a placeholder if every line other than headings matches. Defaults to a
+	// built-in set of patterns.
+	PlaceholderPatterns []string `yaml:"placeholderPatterns"`
+
+	// RequiredContents are case-insensitive regular expressions, or plain
+	// substrings if they are not valid regular expressions, that SECURITY.md
+	// must each contain, such as a reporting address or a response time.
+	// Default empty, no content is required.
+	RequiredContents []string `yaml:"requiredContents"`
+
+	// MinLength is the minimum length, in characters, of SECURITY.md ignoring
+	// leading and trailing whitespace, to reject trivially short files. Default
+	// 0, no minimum.
+	MinLength int `yaml:"minLength"`
+
+	// AcceptSecurityTxt is a URL pattern of a security.txt file (RFC 9116) that
+	// satisfies the policy when a SECURITY.md is not found, for projects that
+	// publish one with their website. The placeholders {owner} and {repo} are
+	// replaced. Ex: https://{repo}.example.com/.well-known/security.txt
+	// Default empty, not accepted.
+	AcceptSecurityTxt string `yaml:"acceptSecurityTxt"`
+
+	// AllowAttestation : set to true to allow repos to assert compliance with an
+	// attestation file, see Attestation. A valid attestation passes the policy
+	// without further checks. Default false.
+	AllowAttestation bool `yaml:"allowAttestation"`
+
+	// FixMode is how the fix action adds a SECURITY.md: "pr" to open a pull
+	// request, or "commit" to commit directly to the default branch. Defaults to
+	// the option of the operator's mapping of the action, see
+	// operator.ActionMap, otherwise "pr".
+	FixMode string `yaml:"fixMode"`
+
+	// CommitMessage is the commit message used by the fix action, as a
+	// text/template with fields .Owner, .Repo, and .Path. Defaults to a message
+	// explaining that Allstar added the file.
+	CommitMessage string `yaml:"commitMessage"`
+
+	// CommitAuthorName is the author name of the fix commit. Default empty, the
+	// GitHub App is the author.
+	CommitAuthorName string `yaml:"commitAuthorName"`
+
+	// CommitAuthorEmail is the author email of the fix commit, required if
+	// CommitAuthorName is set.
+	CommitAuthorEmail string `yaml:"commitAuthorEmail"`
+
+	// SignOff : set to true to add a Signed-off-by trailer for the commit author
+	// to the fix commit, for repos that require DCO. Requires CommitAuthorName
+	// and CommitAuthorEmail. Default false.
+	SignOff bool `yaml:"signOff"`
+
+	// PRTitle is the pull request title used when FixMode is "pr", as a
+	// text/template with the same fields as CommitMessage.
+	PRTitle string `yaml:"prTitle"`
+
+	// PRBody is the pull request body used when FixMode is "pr", as a
+	// text/template with the same fields as CommitMessage.
+	PRBody string `yaml:"prBody"`
+
+	// AuditComment, if set, is a comment the fix action adds to the fix commit,
+	// or to the pull request when FixMode is "pr", explaining where the file
+	// came from, such as with a link to the org's policy docs. It is a
+	// text/template with the same fields as CommitMessage. Default empty, no
+	// comment is added.
+	AuditComment string `yaml:"auditComment"`
+
+	// NotifyText replaces the built-in issue text for a missing security
+	// policy. It is a text/template with fields .Org, .Repo, and .PolicyURL,
+	// the repo's security policy page. An invalid template is logged and the
+	// built-in text is used. Default empty, the built-in text.
+	NotifyText string `yaml:"notifyText"`
+
+	// NotifyTextByLang is localized issue text for a missing security policy,
+	// keyed by locale, such as "ja" or "pt-BR". Each is a text/template with
+	// fields .Owner and .Repo, and those of NotifyText. Locales missing here
+	// fall back to NotifyText, or the built-in English text.
+	NotifyTextByLang map[string]string `yaml:"notifyTextByLang"`
+
+	// DefaultLang is the locale selected from NotifyTextByLang for repos that
+	// do not set a lang. Default empty, English.
+	DefaultLang string `yaml:"defaultLang"`
+
+	// OnlyIfHasReleases : set to true to only enforce on repos that have at
+	// least one published release or tag. Other repos are skipped. Default
+	// false.
+	OnlyIfHasReleases bool `yaml:"onlyIfHasReleases"`
+
+	// SkipTemplates : set to true to skip template repositories. Default false.
+	SkipTemplates bool `yaml:"skipTemplates"`
+
+	// BotAccounts is a list of bot account logins. Repos owned by one of these
+	// accounts are skipped.
+	BotAccounts []string `yaml:"botAccounts"`
+
+	// MinPushWithinDays, if set, skips repos that have not been pushed to in
+	// this many days, to focus enforcement on active projects. Default 0, no
+	// repos are skipped for inactivity.
+	MinPushWithinDays int `yaml:"minPushWithinDays"`
+
+	// EnforceForTeams, if set, is a list of team slugs. Only repos that one of
+	// these teams has access to are checked, others are skipped. Team access
+	// is cached for teamsCacheDuration. Default empty, all repos are checked.
+	EnforceForTeams []string `yaml:"enforceForTeams"`
+
+	// CheckEmptyRepos : set to true to check repos whose default branch has no
+	// commits yet. Default false, these repos are skipped.
+	CheckEmptyRepos bool `yaml:"checkEmptyRepos"`
+
+	// CheckArchived : set to true to check archived and disabled repos, which
+	// can't be changed to add a security policy. Default false, these repos
+	// are skipped.
+	CheckArchived bool `yaml:"checkArchived"`
+
+	// RulesetEvaluator is the name of a registered RulesetEvaluator used to
+	// evaluate the security policy contents against Ruleset. Default empty, no
+	// ruleset is evaluated. See RegisterEvaluator.
+	RulesetEvaluator string `yaml:"rulesetEvaluator"`
+
+	// Ruleset is the ruleset reference passed to RulesetEvaluator, such as an
+	// embedded policy or a URL. Its format is defined by the evaluator.
+	Ruleset string `yaml:"ruleset"`
+
+	// RequireCodeOwnerReview : set to true to require that the last commit
+	// changing the security policy file was merged through a pull request
+	// approved by one of the file's owners in CODEOWNERS. Default false.
+	RequireCodeOwnerReview bool `yaml:"requireCodeOwnerReview"`
+
+	// RequireSupportedVersions : set to true to require that the security policy
+	// has a "Supported Versions" section listing at least one version other
+	// than the examples in GitHub's template. Default false.
+	RequireSupportedVersions bool `yaml:"requireSupportedVersions"`
+
+	// RequireTimeline : set to true to require that the security policy commits
+	// to a response or disclosure timeline, such as "we aim to respond within 48
+	// hours". Default false.
+	RequireTimeline bool `yaml:"requireTimeline"`
+
+	// TimelinePatterns are case-insensitive regular expressions that identify a
+	// time commitment for RequireTimeline. A line matches if it also mentions
+	// responding, fixing, or disclosure. Defaults to a pattern matching a
+	// duration, such as "48 hours" or "90-day".
+	TimelinePatterns []string `yaml:"timelinePatterns"`
+
+	// RepoGroups are families of related repos, such as a main repo and its
+	// language bindings, that must share the same security policy. The policy
+	// of each repo in a group fails if its SECURITY.md content differs from
+	// that of the group's canonical repo. Default empty.
+	RepoGroups []RepoGroup `yaml:"repoGroups"`
+
+	// RequireReadmeLink : set to true to require that the repo README links to
+	// the security policy. Default false.
+	RequireReadmeLink bool `yaml:"requireReadmeLink"`
+
+	// ContactChannels is the list of contact channel types that count toward
+	// MinContactChannels: "email", "bugBounty", "privateReporting", and "pgp".
+	// Default empty, all types count.
+	ContactChannels []string `yaml:"contactChannels"`
+
+	// MinContactChannels is the minimum number of ContactChannels types the
+	// security policy must list. Default 0, not checked.
+	MinContactChannels int `yaml:"minContactChannels"`
+
+	// RequireContactDomains is a list of approved domains, such as
+	// "example.com", for the email addresses and URLs listed in the security
+	// policy. Subdomains of an approved domain are also approved. Links to the
+	// org on GitHub are always approved. Default empty, not checked.
+	RequireContactDomains []string `yaml:"requireContactDomains"`
+
+	// VerifyPolicyURL : set to true to check that the security policy URL
+	// reported by GitHub resolves. This makes an extra request per repo,
+	// results are cached. Default false.
+	VerifyPolicyURL bool `yaml:"verifyPolicyURL"`
+
+	// VerifyRawURL : set to true to check that the security policy file in the
+	// repo resolves at its raw URL, such as on raw.githubusercontent.com, for
+	// tooling that reads it from there. This makes an extra request per repo,
+	// results are cached per file content. Default false.
+	VerifyRawURL bool `yaml:"verifyRawURL"`
+
+	// DetectRegression : set to true to record a baseline of the security
+	// policy, and fail if a later version removes a contact channel or shrinks
+	// below MinLengthFraction of its baseline length. See SetBaselineStore.
+	// Default false.
+	DetectRegression bool `yaml:"detectRegression"`
+
+	// RequireRegularFile : set to true to fail if the repo's security policy is
+	// a symlink or submodule that does not resolve to a non-empty file, so that
+	// a placeholder entry does not count as a policy. Default false.
+	RequireRegularFile bool `yaml:"requireRegularFile"`
+
+	// MinLengthFraction is the fraction of the baseline length the security
+	// policy may shrink to with DetectRegression. Default 0.5.
+	MinLengthFraction float64 `yaml:"minLengthFraction"`
+
+	// Paths is a list of additional file paths, such as
+	// "docs/security/POLICY.md", where a non-empty file counts as the security
+	// policy even though GitHub does not recognize it. Default empty, only the
+	// security policy GitHub reports is accepted.
+	Paths []string `yaml:"paths"`
+
+	// SubPaths is a list of repo subdirectories, such as components of a
+	// monorepo, that must each contain a non-empty SECURITY.md. Default empty,
+	// only the repo level security policy is checked.
+	SubPaths []string `yaml:"subPaths"`
+
+	// SubPathsOnly : set to true to not require a repo level security policy
+	// when SubPaths is set. Default false, both are required.
+	SubPathsOnly bool `yaml:"subPathsOnly"`
+
+	// Branches is a list of branch names or globs, such as "release/*", for
+	// long-lived branches that must also contain a security policy. Default
+	// empty, only the default branch is checked.
+	Branches []string `yaml:"branches"`
+
+	// BranchQuorum is the number of branches matching Branches that must have
+	// a security policy. Default 0, all of them.
+	BranchQuorum int `yaml:"branchQuorum"`
+
+	// EscalateOnAdvisories : set to true to check a repo missing a security
+	// policy for open vulnerability alerts, and if there are any, raise the
+	// severity and urgency of the issue. Requires the vulnerability alerts
+	// permission. Default false.
+	EscalateOnAdvisories bool `yaml:"escalateOnAdvisories"`
+
+	// AcceptUpstreamPolicy : set to true to treat a fork without its own
+	// security policy as covered if its upstream parent has one. Default false,
+	// forks need their own security policy.
+	AcceptUpstreamPolicy bool `yaml:"acceptUpstreamPolicy"`
+
+	// RequireSecurityMD : set to true to require each repo to have its own
+	// security policy, rather than inheriting the org default from the org's
+	// .github repo. Default false, the org default satisfies the policy.
+	RequireSecurityMD bool `yaml:"requireSecurityMD"`
+
+	// UploadCodeScanning : set to true to upload the findings of each check as
+	// code scanning alerts on the repo, so that they appear in the Security tab.
+	// Requires the code scanning alerts write permission. Default false.
+	UploadCodeScanning bool `yaml:"uploadCodeScanning"`
+
+	// CaptureEvidence : set to true to record, on a pass by a SECURITY.md in
+	// the repo, the file's path, git SHA, content hash, and URL in the result
+	// details, as evidence of compliance for audits. This makes an extra
+	// request per passing repo. Default false.
+	CaptureEvidence bool `yaml:"captureEvidence"`
+
+	// MentionCodeOwners : set to true to @-mention the CODEOWNERS owners of
+	// SECURITY.md in the notify text of a failing result, so the issue reaches
+	// the people responsible. If the repo has no CODEOWNERS, or no owner of
+	// SECURITY.md, DefaultMentions are used, otherwise the repo admins.
+	// Default false.
+	MentionCodeOwners bool `yaml:"mentionCodeOwners"`
+
+	// DefaultMentions are users or teams, such as "@org/security", mentioned
+	// with MentionCodeOwners when there are no code owners. Default empty.
+	DefaultMentions []string `yaml:"defaultMentions"`
+
+	// RedactPatterns are regular expressions, such as internal email addresses,
+	// masked in security policy content quoted in the notify text and result
+	// details, in addition to operator.RedactPatterns. Default empty.
+	RedactPatterns []string `yaml:"redactPatterns"`
+
+	// RenotifyIntervalDays is the minimum number of days between re-pinging an
+	// open issue while the policy keeps failing, regardless of how often repos
+	// are checked. Default 0, the operator ping duration is used.
+	RenotifyIntervalDays int `yaml:"renotifyIntervalDays"`
+
+	// DryRun : set to true to log the issue or fix that would be made for a
+	// failing repo, including its title and body, instead of making it. The
+	// action is otherwise unchanged. Default false.
+	DryRun bool `yaml:"dryRun"`
+
+	// SecurityTemplate is the SECURITY.md added by the fix action, as a
+	// text/template. In addition to the CommitMessage fields, it has
+	// .DefaultBranch, .Releases (recent release tags, newest first), and
+	// .Branches, and the functions described in README.md. Defaults to a
+	// generic policy asking reporters to use the repo's private vulnerability
+	// reporting page.
+	SecurityTemplate string `yaml:"securityTemplate"`
+}
+
+// RolloutStage is a single stage of a staged rollout.
+type RolloutStage struct {
+	// Action is the action to take during this stage.
+	Action string `yaml:"action"`
+
+	// Days is the duration of this stage in days.
+	Days int `yaml:"days"`
 }
 
 // RepoConfig is the repo-level config for Branch Protection
@@ -57,27 +458,212 @@ type RepoConfig struct {
 
 	// Action overrides the same setting in org-level, only if present.
 	Action *string `yaml:"action"`
+
+	// MinStarsForIssue overrides the same setting in org-level, only if present.
+	MinStarsForIssue *int `yaml:"minStarsForIssue"`
+
+	// DisallowPublicDisclosure overrides the same setting in org-level, only if
+	// present.
+	DisallowPublicDisclosure *bool `yaml:"disallowPublicDisclosure"`
+
+	// AcceptSecurityTxt overrides the same setting in org-level, only if
+	// present.
+	AcceptSecurityTxt *string `yaml:"acceptSecurityTxt"`
+
+	// Lang is the locale used to select the org-level NotifyTextByLang, only
+	// if present.
+	Lang *string `yaml:"lang"`
+
+	// NotifyText overrides the same setting in org-level, only if present.
+	NotifyText *string `yaml:"notifyText"`
+
+	// OnlyIfHasReleases overrides the same setting in org-level, only if
+	// present.
+	OnlyIfHasReleases *bool `yaml:"onlyIfHasReleases"`
+
+	// Paths overrides the same setting in org-level, only if present.
+	Paths []string `yaml:"paths"`
+
+	// SubPaths overrides the same setting in org-level, only if present.
+	SubPaths []string `yaml:"subPaths"`
+
+	// SubPathsOnly overrides the same setting in org-level, only if present.
+	SubPathsOnly *bool `yaml:"subPathsOnly"`
+
+	// Branches overrides the same setting in org-level, only if present.
+	Branches []string `yaml:"branches"`
+
+	// RenotifyIntervalDays overrides the same setting in org-level, only if
+	// present.
+	RenotifyIntervalDays *int `yaml:"renotifyIntervalDays"`
+
+	// DryRun overrides the same setting in org-level, only if present.
+	DryRun *bool `yaml:"dryRun"`
+
+	// SecurityTemplate overrides the same setting in org-level, only if
+	// present.
+	SecurityTemplate *string `yaml:"securityTemplate"`
 }
 
 type mergedConfig struct {
-	Action string
+	AllowAttestation         bool
+	Action                   string
+	Actions                  map[string]string
+	MinStarsForIssue         int
+	DisallowPublicDisclosure bool
+	PublicDisclosurePatterns []string
+	DisallowPlaceholder      bool
+	PlaceholderPatterns      []string
+	RequiredContents         []string
+	MinLength                int
+	AcceptSecurityTxt        string
+	FixMode                  string
+	CommitMessage            string
+	CommitAuthorName         string
+	CommitAuthorEmail        string
+	SignOff                  bool
+	PRTitle                  string
+	PRBody                   string
+	SecurityTemplate         string
+	AuditComment             string
+	NotifyText               string
+	NotifyTextByLang         map[string]string
+	Lang                     string
+	OnlyIfHasReleases        bool
+	SkipTemplates            bool
+	BotAccounts              []string
+	MinPushWithinDays        int
+	EnforceForTeams          []string
+	CheckEmptyRepos          bool
+	CheckArchived            bool
+	RulesetEvaluator         string
+	Ruleset                  string
+	RequireCodeOwnerReview   bool
+	RequireSupportedVersions bool
+	RequireTimeline          bool
+	TimelinePatterns         []string
+	RepoGroups               []RepoGroup
+	RequireReadmeLink        bool
+	ContactChannels          []string
+	MinContactChannels       int
+	RequireContactDomains    []string
+	VerifyPolicyURL          bool
+	VerifyRawURL             bool
+	DetectRegression         bool
+	RequireRegularFile       bool
+	MinLengthFraction        float64
+	Paths                    []string
+	SubPaths                 []string
+	SubPathsOnly             bool
+	Branches                 []string
+	BranchQuorum             int
+	EscalateOnAdvisories     bool
+	AcceptUpstreamPolicy     bool
+	RequireSecurityMD        bool
+	RenotifyIntervalDays     int
+	DryRun                   bool
+
+	// overridden lists the repo-level options that took effect, by yaml name.
+	overridden []string
 }
 
-type details struct {
-	Enabled bool
-	URL     string
+// Mechanisms recorded in details, identifying what satisfied the policy.
+const (
+	mechanismSecurityMD  = "SECURITY.md"
+	mechanismSecurityTxt = "security.txt"
+)
+
+// Values of the Source detail, recording what produced a passing result.
+const (
+	SourceRepoFile    = "repo_file"
+	SourceOrgDefault  = "org_default"
+	SourceSecurityTxt = "security_txt"
+	SourceUpstream    = "upstream"
+	SourceAttestation = "attestation"
+	SourceSubPaths    = "subpaths"
+	SourcePath        = "path"
+	SourceSkipped     = "skipped"
+)
+
+// SecurityDetails are the details of a SECURITY.md policy result, in
+// policydef.Result.Details, for callers that read results as structured data.
+// Fields that don't apply to a result are left zero, and omitted from its JSON.
+type SecurityDetails struct {
+	Enabled                bool             `json:"Enabled"`
+	URL                    string           `json:"URL,omitempty"`
+	Mechanism              string           `json:"Mechanism,omitempty"`
+	PublicDisclosureLine   string           `json:"PublicDisclosureLine,omitempty"`
+	Attestation            *Attestation     `json:"Attestation,omitempty"`
+	NoReleases             bool             `json:"NoReleases,omitempty"`
+	Skipped                string           `json:"Skipped,omitempty"`
+	RulesetFindings        []RulesetFinding `json:"RulesetFindings,omitempty"`
+	CodeOwnerReview        string           `json:"CodeOwnerReview,omitempty"`
+	SupportedVersionsFound bool             `json:"SupportedVersionsFound,omitempty"`
+	SupportedVersions      []string         `json:"SupportedVersions,omitempty"`
+	Timeline               string           `json:"Timeline,omitempty"`
+	Placeholder            string           `json:"Placeholder,omitempty"`
+	ReadmeFound            bool             `json:"ReadmeFound,omitempty"`
+	ReadmeLinked           bool             `json:"ReadmeLinked,omitempty"`
+	ContactChannels        []string         `json:"ContactChannels,omitempty"`
+	ContactDomains         []ContactDomain  `json:"ContactDomains,omitempty"`
+	PolicyURLStatus        int              `json:"PolicyURLStatus,omitempty"`
+	RawURL                 string           `json:"RawURL,omitempty"`
+	RawURLStatus           int              `json:"RawURLStatus,omitempty"`
+	Regression             string           `json:"Regression,omitempty"`
+	SubPathsPassed         []string         `json:"SubPathsPassed,omitempty"`
+	SubPathsMissing        []string         `json:"SubPathsMissing,omitempty"`
+	BranchesPassed         []string         `json:"BranchesPassed,omitempty"`
+	BranchesMissing        []string         `json:"BranchesMissing,omitempty"`
+	OpenAdvisories         int              `json:"OpenAdvisories,omitempty"`
+	Severity               string           `json:"Severity,omitempty"`
+	FileType               string           `json:"FileType,omitempty"`
+	AccessError            string           `json:"AccessError,omitempty"`
+	IndexLag               string           `json:"IndexLag,omitempty"`
+	EncodingProblem        string           `json:"EncodingProblem,omitempty"`
+	Ref                    string           `json:"Ref,omitempty"`
+	Evidence               *Evidence        `json:"Evidence,omitempty"`
+	Upstream               string           `json:"Upstream,omitempty"`
+	GroupDrift             *GroupDrift      `json:"GroupDrift,omitempty"`
+	// Inherited is true if the security policy is the org default from the
+	// org's .github repo, rather than the repo's own.
+	Inherited       bool     `json:"Inherited,omitempty"`
+	Length          int      `json:"Length,omitempty"`
+	MissingContents []string `json:"MissingContents,omitempty"`
+	// SatisfiedPath is the path of the file that satisfied the policy, when
+	// it was read, such as for content checks or from Paths.
+	SatisfiedPath string `json:"SatisfiedPath,omitempty"`
+	// FailedRequirements are the reasons of a failing result.
+	FailedRequirements []string `json:"FailedRequirements,omitempty"`
+	// Exempt is the reason of the exemption, from optConfig exemptions, that
+	// skipped the repo.
+	Exempt string `json:"Exempt,omitempty"`
+	// Source is the provenance of a passing result, one of the Source values.
+	Source string `json:"Source,omitempty"`
 }
 
 var configFetchConfig func(context.Context, *github.Client, string, string, string, interface{}) error
+var timeNow func() time.Time
 
 func init() {
-	configFetchConfig = config.FetchConfig
+	configFetchConfig = fetchConfigRetry
+	timeNow = time.Now
+	newV4Client = defaultV4Client
 }
 
 type v4client interface {
 	Query(context.Context, interface{}, map[string]interface{}) error
 }
 
+// newV4Client returns the GraphQL client for c, see ghclients.NewV4Client.
+// Requests are retried on secondary rate limits.
+var newV4Client func(*github.Client) v4client
+
+func defaultV4Client(c *github.Client) v4client {
+	hc := *c.Client()
+	hc.Transport = retryTransport{hc.Transport}
+	return ghclients.NewV4Client(c, &hc)
+}
+
 // Security is the SECURITY.md policy object, implements policydef.Policy.
 type Security bool
 
@@ -93,28 +679,196 @@ func (s Security) Name() string {
 }
 
 // Check performs the polcy check for SECURITY.md policy based on the
-// configuration stored in the org/repo, implementing policydef.Policy.Check()
+// configuration stored in the org/repo, implementing policydef.Policy.Check().
+// Use policydef.WithForceRecheck to ignore cached config and results.
 func (s Security) Check(ctx context.Context, c *github.Client, owner,
 	repo string) (*policydef.Result, error) {
-	v4c := githubv4.NewClient(c.Client())
-	return check(ctx, c, v4c, owner, repo)
+	if r := backoffResult(ctx, owner, repo); r != nil {
+		return r, nil
+	}
+	v4c := newV4Client(c)
+	r, err := check(ctx, c.Repositories, c, v4c, owner, repo)
+	recordCheckError(ctx, owner, repo, err)
+	return r, err
 }
 
-func check(ctx context.Context, c *github.Client, v4c v4client, owner,
+func check(ctx context.Context, rep repositories, c *github.Client, v4c v4client, owner,
 	repo string) (*policydef.Result, error) {
+	if policydef.IsForceRecheck(ctx) {
+		runid.Logger(ctx).Info().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Msg("Forced recheck, ignoring cached config and results.")
+	}
 	oc, rc := getConfig(ctx, c, owner, repo)
+	res, err := checkConfig(ctx, rep, c, v4c, owner, repo, oc, rc)
+	if err == nil {
+		finishNotifyText(ctx, oc, owner, repo, res, func() string {
+			return mentionText(ctx, newMentionRepositories(c), owner, repo, oc.DefaultMentions)
+		})
+	}
+	if err == nil && !res.Pass {
+		if d, ok := res.Details.(SecurityDetails); ok {
+			d.FailedRequirements = res.Reasons
+			res.Details = d
+		}
+	}
+	if err == nil && res.Pass && oc.CaptureEvidence {
+		captureEvidence(ctx, rep, owner, repo, res)
+	}
+	if err == nil && res.Enabled && oc.UploadCodeScanning {
+		uploadCodeScanning(ctx, c, owner, repo, res)
+	}
+	return res, err
+}
+
+// finishNotifyText applies the redaction patterns of oc to res and, if
+// enabled, appends the mention line returned by mentions. It is the last step
+// of building the issue text, shared by Check and RenderNotifyText.
+func finishNotifyText(ctx context.Context, oc *OrgConfig, owner, repo string, res *policydef.Result, mentions func() string) {
+	redactResult(res, newRedactor(ctx, owner, repo, oc.RedactPatterns))
+	if res.Enabled && !res.Pass && oc.MentionCodeOwners {
+		res.NotifyText += mentions()
+	}
+}
+
+// checkConfig performs the policy check with the provided config rather than
+// fetching it.
+func checkConfig(ctx context.Context, rep repositories, c *github.Client, v4c v4client, owner,
+	repo string, oc *OrgConfig, rc *RepoConfig) (*policydef.Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, operator.CheckTimeout)
+	defer cancel()
 	enabled := config.IsEnabled(oc.OptConfig, rc.OptConfig, repo)
-	log.Info().
+	ex := findExemption(ctx, oc, owner, repo)
+	ev := runid.Logger(ctx).Info().
 		Str("org", owner).
 		Str("repo", repo).
 		Str("area", polName).
-		Bool("enabled", enabled).
-		Msg("Check repo enabled")
+		Bool("enabled", enabled)
+	if ex != nil {
+		ev = ev.Str("exempt", ex.Reason)
+	}
+	ev.Msg("Check repo enabled")
+	if ex != nil {
+		runid.Logger(ctx).Info().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("reason", ex.Reason).
+			Str("approvedBy", ex.ApprovedBy).
+			Str("until", ex.Until).
+			Msg("Repo exempt from policy.")
+		return &policydef.Result{
+			Enabled:    false,
+			Pass:       true,
+			NotifyText: "",
+			Details: SecurityDetails{
+				Skipped: "exempt",
+				Exempt:  ex.Reason,
+				Source:  SourceSkipped,
+			},
+		}, nil
+	}
+	logOptConflict(ctx, oc, rc, owner, repo)
+	mc := mergeConfig(ctx, oc, rc, repo)
+	recordOverrides(ctx, repo, mc.overridden, mc.Action)
+
+	if mc.AllowAttestation {
+		a := getAttestation(ctx, c, owner, repo)
+		if a != nil {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					Attestation: a,
+					Source:      SourceAttestation,
+				},
+			}, nil
+		}
+	}
+
+	if enabled && (mc.SkipTemplates || len(mc.BotAccounts) > 0 || mc.MinPushWithinDays > 0) {
+		reason, err := skipReason(ctx, v4c, mc, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			runid.Logger(ctx).Info().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Str("reason", reason).
+				Msg("Skipping repo.")
+			return &policydef.Result{
+				Enabled:    false,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					Skipped: reason,
+					Source:  SourceSkipped,
+				},
+			}, nil
+		}
+	}
+
+	if enabled && len(mc.EnforceForTeams) > 0 {
+		teams, err := repoTeams(ctx, newTeamRepositories(c), owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		if !anyTeam(teams, mc.EnforceForTeams) {
+			runid.Logger(ctx).Info().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Strs("teams", teams).
+				Msg("Repo not in an enforced team, skipping.")
+			return &policydef.Result{
+				Enabled:    false,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					Skipped: "not in an enforced team",
+					Source:  SourceSkipped,
+				},
+			}, nil
+		}
+	}
+
+	if enabled && mc.OnlyIfHasReleases {
+		has, err := hasReleases(ctx, v4c, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			runid.Logger(ctx).Info().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Msg("Repo has no releases, skipping.")
+			return &policydef.Result{
+				Enabled:    false,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					NoReleases: true,
+					Source:     SourceSkipped,
+				},
+			}, nil
+		}
+	}
 
 	var q struct {
 		Repository struct {
 			SecurityPolicyUrl       string
 			IsSecurityPolicyEnabled bool
+			IsArchived              bool
+			IsDisabled              bool
+			DefaultBranchRef        struct {
+				Name string
+			}
 		} `graphql:"repository(owner: $owner, name: $name)"`
 	}
 	variables := map[string]interface{}{
@@ -122,66 +876,799 @@ func check(ctx context.Context, c *github.Client, v4c v4client, owner,
 		"name":  githubv4.String(repo),
 	}
 	if err := v4c.Query(ctx, &q, variables); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("check timed out after %v: %w", operator.CheckTimeout, err)
+		}
+		if isAccessError(err) {
+			return noAccessResult(ctx, owner, repo, err), nil
+		}
 		return nil, err
 	}
+	debugResponse(ctx, owner, repo, "graphql", q)
+	if (q.Repository.IsArchived || q.Repository.IsDisabled) && !mc.CheckArchived {
+		reason := "archived"
+		if q.Repository.IsDisabled {
+			reason = "disabled"
+		}
+		runid.Logger(ctx).Info().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("reason", reason).
+			Msg("Skipping repo.")
+		return &policydef.Result{
+			Enabled:    false,
+			Pass:       true,
+			NotifyText: "",
+			Details: SecurityDetails{
+				Skipped: reason,
+				Source:  SourceSkipped,
+			},
+		}, nil
+	}
+	if q.Repository.DefaultBranchRef.Name == "" && !mc.CheckEmptyRepos {
+		runid.Logger(ctx).Info().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Msg("Repo default branch has no commits, skipping.")
+		return &policydef.Result{
+			Enabled:    false,
+			Pass:       true,
+			NotifyText: "",
+			Details: SecurityDetails{
+				Skipped: "default branch has no commits",
+				Source:  SourceSkipped,
+			},
+		}, nil
+	}
+	ref := checkRef(ctx)
+	inherited := q.Repository.IsSecurityPolicyEnabled &&
+		policySource(owner, repo, q.Repository.SecurityPolicyUrl) == SourceOrgDefault
+	if ref != "" {
+		// GitHub only reports the security policy of the default branch, look
+		// for the file at the ref instead. An org default still applies.
+		rp, _, _, err := getPolicyEntry(ctx, rep, owner, repo, ref)
+		if isAccessError(err) {
+			return noAccessResult(ctx, owner, repo, err), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		orgDefault := q.Repository.IsSecurityPolicyEnabled &&
+			policySource(owner, repo, q.Repository.SecurityPolicyUrl) == SourceOrgDefault
+		q.Repository.IsSecurityPolicyEnabled = rp != "" || orgDefault
+		inherited = rp == "" && orgDefault
+	}
+	lagPath := ""
+	if !q.Repository.IsSecurityPolicyEnabled && ref == "" {
+		lagPath = confirmPolicyFile(ctx, rep, owner, repo)
+		q.Repository.IsSecurityPolicyEnabled = lagPath != ""
+		if lagPath != "" && measureDetectionLag {
+			recordDetectionLag(ctx, newCommitRepositories(c), owner, repo, lagPath)
+		}
+	}
+	if !q.Repository.IsSecurityPolicyEnabled && ref == "" && !strings.EqualFold(repo, orgDefaultRepo) {
+		// GitHub may not yet show the org default on the repo.
+		u, err := orgDefaultPolicy(ctx, v4c, owner)
+		if err != nil {
+			runid.Logger(ctx).Warn().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Err(err).
+				Msg("Unable to check org default security policy.")
+		}
+		if u != "" {
+			q.Repository.IsSecurityPolicyEnabled = true
+			q.Repository.SecurityPolicyUrl = u
+			inherited = true
+		}
+	}
+	if inherited && mc.RequireSecurityMD {
+		q.Repository.IsSecurityPolicyEnabled = false
+	}
+	if !q.Repository.IsSecurityPolicyEnabled && len(mc.Paths) > 0 {
+		p, err := findPathPolicy(ctx, rep, owner, repo, ref, mc.Paths)
+		if isAccessError(err) {
+			return noAccessResult(ctx, owner, repo, err), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p != "" {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					Enabled:       false,
+					Mechanism:     mechanismSecurityMD,
+					Ref:           ref,
+					SatisfiedPath: p,
+					Source:        SourcePath,
+				},
+			}, nil
+		}
+	}
+	if !q.Repository.IsSecurityPolicyEnabled && mc.AcceptSecurityTxt != "" {
+		u := securityTxtURL(mc.AcceptSecurityTxt, owner, repo)
+		err := checkSecurityTxt(ctx, u)
+		if err == nil {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					Enabled:   false,
+					URL:       u,
+					Mechanism: mechanismSecurityTxt,
+					Source:    SourceSecurityTxt,
+				},
+			}, nil
+		}
+		runid.Logger(ctx).Info().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("url", u).
+			Err(err).
+			Msg("security.txt not accepted.")
+	}
+	if !q.Repository.IsSecurityPolicyEnabled && mc.AcceptUpstreamPolicy && ref == "" {
+		parent, u, err := upstreamPolicy(ctx, v4c, owner, repo)
+		if err != nil {
+			runid.Logger(ctx).Warn().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Err(err).
+				Msg("Unable to check fork upstream security policy.")
+		}
+		if u != "" {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					Enabled:   false,
+					URL:       u,
+					Mechanism: mechanismSecurityMD,
+					Upstream:  fmt.Sprintf("fork of %v, covered by its security policy", parent),
+					Source:    SourceUpstream,
+				},
+			}, nil
+		}
+	}
+	d := SecurityDetails{
+		Enabled:   q.Repository.IsSecurityPolicyEnabled,
+		URL:       q.Repository.SecurityPolicyUrl,
+		Mechanism: mechanismSecurityMD,
+		Ref:       ref,
+		Inherited: inherited,
+	}
+	if lagPath != "" {
+		d.Enabled = false
+		d.IndexLag = fmt.Sprintf("%v found, GitHub does not yet show a security policy", lagPath)
+	}
+	if len(mc.SubPaths) > 0 {
+		var err error
+		d.SubPathsPassed, d.SubPathsMissing, err = checkSubPaths(ctx, rep, owner, repo, mc.SubPaths)
+		if isAccessError(err) {
+			return noAccessResult(ctx, owner, repo, err), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	rootRequired := len(mc.SubPaths) == 0 || !mc.SubPathsOnly
+	if !q.Repository.IsSecurityPolicyEnabled && rootRequired {
+		d.Mechanism = ""
+		reasons := []string{ReasonMissing}
+		if len(d.SubPathsMissing) > 0 {
+			reasons = append(reasons, ReasonSubPathMissing)
+		}
+		if enabled && mc.EscalateOnAdvisories {
+			n, err := openAdvisories(ctx, v4c, owner, repo)
+			if err != nil {
+				runid.Logger(ctx).Warn().
+					Str("org", owner).
+					Str("repo", repo).
+					Str("area", polName).
+					Err(err).
+					Msg("Unable to get vulnerability alerts, not escalating.")
+			}
+			if n > 0 {
+				d.OpenAdvisories = n
+				d.Severity = severityHigh
+				reasons = append(reasons, ReasonMissingWithAdvisories)
+			}
+		}
+		return &policydef.Result{
+			Enabled:    enabled,
+			Pass:       false,
+			NotifyText: missingNotifyText(ctx, mc, owner, repo, d),
+			Reasons:    reasons,
+			Details:    d,
+		}, nil
+	}
+	if len(d.SubPathsMissing) > 0 {
+		return &policydef.Result{
+			Enabled:    enabled,
+			Pass:       false,
+			NotifyText: fmt.Sprintf("Security policy missing in: %v\n", strings.Join(d.SubPathsMissing, ", ")) + subPathsText,
+			Details:    d,
+			Reasons:    []string{ReasonSubPathMissing},
+		}, nil
+	}
+	if len(mc.Branches) > 0 {
+		var err error
+		d.BranchesPassed, d.BranchesMissing, err = checkBranches(ctx, newBranchRepositories(c), owner, repo, mc.Branches)
+		if isAccessError(err) {
+			return noAccessResult(ctx, owner, repo, err), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if need := branchQuorum(mc.BranchQuorum, len(d.BranchesPassed)+len(d.BranchesMissing)); len(d.BranchesPassed) < need {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: fmt.Sprintf("Security policy missing on branches: %v (%v of %v required branches have one)\n", strings.Join(d.BranchesMissing, ", "), len(d.BranchesPassed), need) + branchesText,
+				Details:    d,
+				Reasons:    []string{ReasonBranchMissing},
+			}, nil
+		}
+	}
 	if !q.Repository.IsSecurityPolicyEnabled {
+		// Only subpath policies are required, and they are all present.
+		d.Mechanism = ""
+		d.Source = SourceSubPaths
+		return &policydef.Result{
+			Enabled:    enabled,
+			Pass:       true,
+			NotifyText: "",
+			Details:    d,
+		}, nil
+	}
+	if mc.VerifyPolicyURL && q.Repository.SecurityPolicyUrl != "" {
+		status, err := checkPolicyURL(ctx, c, q.Repository.SecurityPolicyUrl)
+		if err != nil {
+			return nil, err
+		}
+		d.PolicyURLStatus = status
+		if status != http.StatusOK {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: fmt.Sprintf("Security policy link %v returned status %v.\n", q.Repository.SecurityPolicyUrl, status) + brokenPolicyURLText,
+				Details:    d,
+				Reasons:    []string{ReasonBrokenPolicyURL},
+			}, nil
+		}
+	}
+	if mc.RequireReadmeLink {
+		found, linked, err := checkReadmeLink(ctx, rep, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		d.ReadmeFound = found
+		d.ReadmeLinked = linked
+		if !linked {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: "README does not link to the security policy.\n" + readmeLinkText,
+				Details:    d,
+				Reasons:    []string{ReasonReadmeLink},
+			}, nil
+		}
+	}
+	needContent := mc.DisallowPublicDisclosure || mc.DisallowPlaceholder || mc.RulesetEvaluator != "" ||
+		len(mc.RequiredContents) > 0 || mc.MinLength > 0 ||
+		mc.RequireCodeOwnerReview || mc.RequireSupportedVersions || mc.RequireTimeline ||
+		mc.MinContactChannels > 0 || len(mc.RequireContactDomains) > 0 ||
+		canonicalRepo(mc.RepoGroups, repo) != "" || mc.VerifyRawURL || mc.DetectRegression || mc.RequireRegularFile
+	if !needContent {
+		d.Source = policySource(owner, repo, q.Repository.SecurityPolicyUrl)
+		return &policydef.Result{
+			Enabled:    enabled,
+			Pass:       true,
+			NotifyText: "",
+			Details:    d,
+		}, nil
+	}
+	p, content, typ, err := getPolicyEntry(ctx, rep, owner, repo, ref)
+	if isAccessError(err) {
+		return noAccessResult(ctx, owner, repo, err), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.FileType = typ
+	if mc.RequireRegularFile && p != "" && (typ != "file" || strings.TrimSpace(content) == "") {
+		what := typ
+		if typ == "file" {
+			what = "empty file"
+		}
 		return &policydef.Result{
 			Enabled:    enabled,
 			Pass:       false,
-			NotifyText: "Security policy not enabled.\n" + fmt.Sprintf(notifyText, owner, repo),
-			Details: details{
-				Enabled: false,
-				URL:     q.Repository.SecurityPolicyUrl,
-			},
+			NotifyText: fmt.Sprintf("Security policy %v is a %v.\n", p, what) + notRegularFileText,
+			Details:    d,
+			Reasons:    []string{ReasonNotRegularFile},
 		}, nil
 	}
+	if problem := encodingProblem(content); p != "" && typ == "file" && problem != "" {
+		d.EncodingProblem = problem
+		return &policydef.Result{
+			Enabled:    enabled,
+			Pass:       false,
+			NotifyText: fmt.Sprintf("Security policy %v is not readable text: %v.\n", p, problem) + badEncodingText,
+			Details:    d,
+			Reasons:    []string{ReasonBadEncoding},
+		}, nil
+	}
+	if mc.VerifyRawURL && p != "" && typ == "file" {
+		branch := ref
+		if branch == "" {
+			branch = q.Repository.DefaultBranchRef.Name
+		}
+		d.RawURL = rawURL(owner, repo, branch, p)
+		status, err := checkRawURL(ctx, c, d.RawURL, content)
+		if err != nil {
+			return nil, err
+		}
+		d.RawURLStatus = status
+		if status != http.StatusOK {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: fmt.Sprintf("Security policy raw URL %v returned status %v.\n", d.RawURL, status) + rawURLText,
+				Details:    d,
+				Reasons:    []string{ReasonRawURL},
+			}, nil
+		}
+	}
+	if mc.DisallowPlaceholder && p != "" && typ == "file" {
+		line, err := findPlaceholder(content, mc.PlaceholderPatterns)
+		if err != nil {
+			runid.Logger(ctx).Warn().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Err(err).
+				Msg("Invalid placeholder pattern, skipping check.")
+		}
+		if line != "" {
+			d.Placeholder = line
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: fmt.Sprintf("Security policy %v is only template boilerplate: %q\n", p, line) + placeholderText,
+				Details:    d,
+				Reasons:    []string{ReasonPlaceholder},
+			}, nil
+		}
+	}
+	if mc.MinLength > 0 && p != "" && typ == "file" {
+		d.Length = contentLength(content)
+		if d.Length < mc.MinLength {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: fmt.Sprintf("Security policy %v is %v characters, shorter than the required %v.\n", p, d.Length, mc.MinLength) + tooShortText,
+				Details:    d,
+				Reasons:    []string{ReasonTooShort},
+			}, nil
+		}
+	}
+	if len(mc.RequiredContents) > 0 && p != "" && typ == "file" {
+		d.MissingContents = missingContents(content, mc.RequiredContents)
+		if len(d.MissingContents) > 0 {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: requiredContentsNotifyText(p, d.MissingContents),
+				Details:    d,
+				Reasons:    []string{ReasonRequiredContents},
+			}, nil
+		}
+	}
+	if mc.DisallowPublicDisclosure {
+		line, err := findPublicDisclosure(content, mc.PublicDisclosurePatterns)
+		if err != nil {
+			runid.Logger(ctx).Warn().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Err(err).
+				Msg("Invalid public disclosure pattern, skipping check.")
+		}
+		if line != "" {
+			d.PublicDisclosureLine = line
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: fmt.Sprintf("Security policy instructs public disclosure: %q\n", line) + publicDisclosureText,
+				Details:    d,
+				Reasons:    []string{ReasonPublicDisclosure},
+			}, nil
+		}
+	}
+	if mc.MinContactChannels > 0 {
+		d.ContactChannels = findContactChannels(content)
+		if n := countAccepted(d.ContactChannels, mc.ContactChannels); n < mc.MinContactChannels {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: fmt.Sprintf("Security policy lists %v of the required %v contact channels.\n", n, mc.MinContactChannels) + contactChannelsText,
+				Details:    d,
+				Reasons:    []string{ReasonContactChannels},
+			}, nil
+		}
+	}
+	if len(mc.RequireContactDomains) > 0 {
+		d.ContactDomains = checkContactDomains(content, owner, mc.RequireContactDomains)
+		if off := offDomainContacts(d.ContactDomains); len(off) > 0 {
+			return &policydef.Result{
+				Enabled: enabled,
+				Pass:    false,
+				NotifyText: fmt.Sprintf("Security policy lists contacts outside the approved domains (%v): %v\n",
+					strings.Join(mc.RequireContactDomains, ", "), strings.Join(off, ", ")) + contactDomainText,
+				Details: d,
+				Reasons: []string{ReasonContactDomain},
+			}, nil
+		}
+	}
+	if mc.DetectRegression && p != "" {
+		lost, err := checkRegression(ctx, owner, repo, content, mc.MinLengthFraction)
+		if err != nil {
+			return nil, err
+		}
+		if lost != "" {
+			d.Regression = lost
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: fmt.Sprintf("Security policy was weakened: %v.\n", lost) + regressionText,
+				Details:    d,
+				Reasons:    []string{ReasonRegression},
+			}, nil
+		}
+	}
+	if mc.RequireSupportedVersions {
+		d.SupportedVersionsFound, d.SupportedVersions = findSupportedVersions(content)
+		if len(d.SupportedVersions) == 0 {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: "Security policy does not list supported versions.\n" + supportedVersionsText,
+				Details:    d,
+				Reasons:    []string{ReasonSupportedVersions},
+			}, nil
+		}
+	}
+	if mc.RequireTimeline {
+		line, err := findTimeline(content, mc.TimelinePatterns)
+		if err != nil {
+			runid.Logger(ctx).Warn().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Err(err).
+				Msg("Invalid timeline pattern, skipping check.")
+		}
+		d.Timeline = line
+		if line == "" && err == nil {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: "Security policy does not state a response timeline.\n" + timelineText,
+				Details:    d,
+				Reasons:    []string{ReasonTimeline},
+			}, nil
+		}
+	}
+	if canon := canonicalRepo(mc.RepoGroups, repo); canon != "" && p != "" {
+		drift, err := checkGroupDrift(ctx, rep, owner, canon, content)
+		if isAccessError(err) {
+			return noAccessResult(ctx, owner, canon, err), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if drift != nil {
+			d.GroupDrift = drift
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: fmt.Sprintf("Security policy of %v/%v differs from the canonical policy of %v/%v.\n", owner, repo, owner, canon) + groupDriftText,
+				Details:    d,
+				Reasons:    []string{ReasonGroupDrift},
+			}, nil
+		}
+	}
+	if mc.RequireCodeOwnerReview && p != "" {
+		found, ok, err := checkCodeOwnerReview(ctx, newReviewClients(c), owner, repo, p)
+		if err != nil {
+			return nil, err
+		}
+		d.CodeOwnerReview = found
+		if !ok {
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: fmt.Sprintf("Security policy change not approved by a code owner: %v\n", found) + codeOwnerReviewText,
+				Details:    d,
+				Reasons:    []string{ReasonCodeOwnerReview},
+			}, nil
+		}
+	}
+	if mc.RulesetEvaluator != "" {
+		findings, err := evaluateRuleset(ctx, mc, &RulesetInput{
+			Owner:   owner,
+			Repo:    repo,
+			URL:     q.Repository.SecurityPolicyUrl,
+			Path:    p,
+			Content: content,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(findings) > 0 {
+			d.RulesetFindings = findings
+			return &policydef.Result{
+				Enabled:    enabled,
+				Pass:       false,
+				NotifyText: rulesetNotifyText(findings),
+				Details:    d,
+				Reasons:    []string{ReasonRuleset},
+			}, nil
+		}
+	}
+	d.Source = policySource(owner, repo, q.Repository.SecurityPolicyUrl)
+	d.SatisfiedPath = p
 	return &policydef.Result{
 		Enabled:    enabled,
 		Pass:       true,
 		NotifyText: "",
-		Details: details{
-			Enabled: true,
-			URL:     q.Repository.SecurityPolicyUrl,
-		},
+		Details:    d,
 	}, nil
 }
 
-// Fix implementing policydef.Policy.Fix(). Currently not supported. Plan
-// to support this TODO.
-func (s Security) Fix(ctx context.Context, c *github.Client, owner, repo string) error {
-	log.Warn().
+// isAccessError returns whether err is GitHub denying the App access to the
+// repo or its contents, such as a missing scope or a protected ref, rather than
+// a transient error. Rate limit errors are not access errors.
+func isAccessError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var er *github.ErrorResponse
+	if errors.As(err, &er) {
+		return er.Response != nil && (er.Response.StatusCode == http.StatusUnauthorized ||
+			er.Response.StatusCode == http.StatusForbidden)
+	}
+	// GraphQL errors are only available as messages.
+	return strings.Contains(err.Error(), "Resource not accessible by integration")
+}
+
+// noAccessResult is the result for a repo whose content could not be accessed.
+// It is not enabled, so that an access problem is not reported as
+// non-compliance.
+func noAccessResult(ctx context.Context, owner, repo string, err error) *policydef.Result {
+	runid.Logger(ctx).Warn().
 		Str("org", owner).
 		Str("repo", repo).
 		Str("area", polName).
-		Msg("Action fix is configured, but not implemented.")
-	return nil
+		Err(err).
+		Msg("Cannot access repo content, skipping.")
+	return &policydef.Result{
+		Enabled:    false,
+		Pass:       false,
+		NotifyText: "",
+		Details: SecurityDetails{
+			Skipped:     "cannot access content",
+			AccessError: err.Error(),
+		},
+		Reasons: []string{ReasonNoAccess},
+	}
+}
+
+// policySource returns whether the security policy GitHub found at url is the
+// repo's own file or the org default from the org's .github repo.
+func policySource(owner, repo, url string) string {
+	if !strings.EqualFold(repo, ".github") &&
+		strings.Contains(strings.ToLower(url), "/"+strings.ToLower(owner)+"/.github/") {
+		return SourceOrgDefault
+	}
+	return SourceRepoFile
+}
+
+// Fix adds a SECURITY.md to the repo, either by pull request or by direct
+// commit to the default branch, according to FixMode. If ctx has reasons from
+// policydef.WithReasons, nothing is done unless the policy is missing.
+// Implementing policydef.Policy.Fix()
+func (s Security) Fix(ctx context.Context, c *github.Client, owner, repo string) error {
+	return fix(ctx, newFixClients(c), c, owner, repo)
+}
+
+// FixPending implements the enforce fixPender interface, returning whether Fix
+// would change the repo, so that safe mode does not count a fix pull request
+// that is already open.
+func (s Security) FixPending(ctx context.Context, c *github.Client, owner, repo string) (bool, error) {
+	return fixPending(ctx, newFixClients(c), c, owner, repo)
+}
+
+// Plan returns the changes Fix would make to the repo, without making them.
+// If the policy is passing or not enabled, the plan is empty.
+func (s Security) Plan(ctx context.Context, c *github.Client, owner, repo string) (*FixPlan, error) {
+	return plan(ctx, newFixClients(c), c, owner, repo)
+}
+
+// PreviewIssue returns the issue the issue action would create for the repo,
+// without creating it. If the policy is passing or not enabled, there is no
+// issue and nil is returned.
+func (s Security) PreviewIssue(ctx context.Context, c *github.Client, owner, repo string) (*issue.Payload, error) {
+	res, err := securityCheck(ctx, c, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	if res.Pass || !res.Enabled {
+		return nil, nil
+	}
+	return issue.Render(polName, res.NotifyText), nil
+}
+
+// CheckRef is Check, with the repo's security policy file looked up at ref,
+// such as the head SHA of a pull request, rather than the default branch, so
+// that adding a security policy in a pull request makes the check pass. Other
+// files, such as in SubPaths, are still read from the default branch.
+func (s Security) CheckRef(ctx context.Context, c *github.Client, owner,
+	repo, ref string) (*policydef.Result, error) {
+	return s.Check(withCheckRef(ctx, ref), c, owner, repo)
+}
+
+type checkRefKey struct{}
+
+func withCheckRef(ctx context.Context, ref string) context.Context {
+	return context.WithValue(ctx, checkRefKey{}, ref)
+}
+
+// checkRef returns the ref set by CheckRef, or an empty string for the default
+// branch.
+func checkRef(ctx context.Context) string {
+	r, _ := ctx.Value(checkRefKey{}).(string)
+	return r
 }
 
 // GetAction returns the configured action from SECURITY.md policy's
 // configuration stored in the org-level repo, default log. Implementing
 // policydef.Policy.GetAction()
 func (s Security) GetAction(ctx context.Context, c *github.Client, owner, repo string) string {
+	v4c := newV4Client(c)
+	return getAction(ctx, c, v4c, owner, repo)
+}
+
+// RenotifyInterval implements the enforce renotifier interface, returning the
+// configured minimum duration between issue pings, or 0 if not configured.
+func (s Security) RenotifyInterval(ctx context.Context, c *github.Client, owner, repo string) time.Duration {
+	mc := resolveConfig(ctx, c, owner, repo)
+	if mc.RenotifyIntervalDays <= 0 {
+		return 0
+	}
+	return time.Duration(mc.RenotifyIntervalDays) * 24 * time.Hour
+}
+
+// DryRun implements the enforce dryRunner interface, returning whether the
+// issue action should only log the issue it would create.
+func (s Security) DryRun(ctx context.Context, c *github.Client, owner, repo string) bool {
+	return dryRun(ctx, c, owner, repo)
+}
+
+func dryRun(ctx context.Context, c *github.Client, owner, repo string) bool {
+	return resolveConfig(ctx, c, owner, repo).DryRun
+}
+
+// resolveConfig returns the merged config of the policy for repo.
+func resolveConfig(ctx context.Context, c *github.Client, owner, repo string) *mergedConfig {
 	oc, rc := getConfig(ctx, c, owner, repo)
-	mc := mergeConfig(oc, rc, repo)
-	return mc.Action
+	return mergeConfig(ctx, oc, rc, repo)
 }
 
-func getConfig(ctx context.Context, c *github.Client, owner, repo string) (*OrgConfig, *RepoConfig) {
-	oc := &OrgConfig{ // Fill out non-zero defaults
-		Action: "log",
+func getAction(ctx context.Context, c *github.Client, v4c v4client, owner, repo string) string {
+	mc := resolveConfig(ctx, c, owner, repo)
+	a := mc.Action
+	for _, r := range policydef.Reasons(ctx) {
+		if ra, ok := mc.Actions[r]; ok {
+			a = ra
+			break
+		}
+	}
+	if a == "issue" && mc.MinStarsForIssue > 0 {
+		var q struct {
+			Repository struct {
+				StargazerCount int
+			} `graphql:"repository(owner: $owner, name: $name)"`
+		}
+		variables := map[string]interface{}{
+			"owner": githubv4.String(owner),
+			"name":  githubv4.String(repo),
+		}
+		if err := v4c.Query(ctx, &q, variables); err != nil {
+			runid.Logger(ctx).Error().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Err(err).
+				Msg("Unexpected error getting star count, using log action.")
+			return "log"
+		}
+		if q.Repository.StargazerCount < mc.MinStarsForIssue {
+			runid.Logger(ctx).Info().
+				Str("org", owner).
+				Str("repo", repo).
+				Str("area", polName).
+				Int("stars", q.Repository.StargazerCount).
+				Int("minStarsForIssue", mc.MinStarsForIssue).
+				Msg("Repo below star threshold, using log action.")
+			return "log"
+		}
+	}
+	return a
+}
+
+func logOptConflict(ctx context.Context, oc *OrgConfig, rc *RepoConfig, owner, repo string) {
+	if c := config.OptConflict(oc.OptConfig, rc.OptConfig, repo); c != "" {
+		runid.Logger(ctx).Warn().
+			Str("org", owner).
+			Str("repo", repo).
+			Str("area", polName).
+			Str("conflict", c).
+			Msg("Conflicting opt in/out config, repo is opted out.")
 	}
-	if err := configFetchConfig(ctx, c, owner, operator.OrgConfigRepo, configFile, oc); err != nil {
-		log.Error().
+}
+
+func defaultOrgConfig() *OrgConfig {
+	return &OrgConfig{ // Fill out non-zero defaults
+		Action:        "log",
+		CommitMessage: defaultCommitMessage,
+		PRTitle:       defaultPRTitle,
+		PRBody:        defaultPRBody,
+	}
+}
+
+type resolvedConfig struct {
+	oc *OrgConfig
+	rc *RepoConfig
+}
+
+// getConfig returns the org and repo config of the policy for repo. Within
+// one run, see policydef.WithRunCache, the config is fetched once and shared
+// between Check, GetAction, Fix, and the optional enforce interfaces. Callers
+// must not modify the returned config.
+func getConfig(ctx context.Context, c *github.Client, owner, repo string) (*OrgConfig, *RepoConfig) {
+	r := policydef.RunCached(ctx, polName+"/config/"+owner+"/"+repo, func() interface{} {
+		oc, rc := fetchConfigs(ctx, c, owner, repo)
+		return resolvedConfig{oc: oc, rc: rc}
+	}).(resolvedConfig)
+	return r.oc, r.rc
+}
+
+func fetchConfigs(ctx context.Context, c *github.Client, owner, repo string) (*OrgConfig, *RepoConfig) {
+	oc := defaultOrgConfig()
+	if err := fetchConfigCached(ctx, c, owner, config.ConfigRepo(ctx), configFile, oc); err != nil {
+		runid.Logger(ctx).Error().
 			Str("org", owner).
-			Str("repo", operator.OrgConfigRepo).
+			Str("repo", config.ConfigRepo(ctx)).
 			Str("area", polName).
 			Str("file", configFile).
 			Err(err).
 			Msg("Unexpected config error, using defaults.")
 	}
 	rc := &RepoConfig{}
-	if err := configFetchConfig(ctx, c, owner, repo, path.Join(operator.RepoConfigDir, configFile), rc); err != nil {
-		log.Error().
+	if err := fetchConfigCached(ctx, c, owner, repo, path.Join(operator.RepoConfigDir, configFile), rc); err != nil {
+		runid.Logger(ctx).Error().
 			Str("org", owner).
 			Str("repo", repo).
 			Str("area", polName).
@@ -189,18 +1676,329 @@ func getConfig(ctx context.Context, c *github.Client, owner, repo string) (*OrgC
 			Err(err).
 			Msg("Unexpected config error, using defaults.")
 	}
+	stripDisallowed(ctx, owner, config.ConfigRepo(ctx), oc, defaultOrgConfig())
+	stripDisallowed(ctx, owner, repo, rc, &RepoConfig{})
 	return oc, rc
 }
 
-func mergeConfig(oc *OrgConfig, rc *RepoConfig, repo string) *mergedConfig {
+func mergeConfig(ctx context.Context, oc *OrgConfig, rc *RepoConfig, repo string) *mergedConfig {
 	mc := &mergedConfig{
-		Action: oc.Action,
+		AllowAttestation:         oc.AllowAttestation,
+		Action:                   rolloutAction(ctx, oc, repo),
+		Actions:                  oc.Actions,
+		MinStarsForIssue:         oc.MinStarsForIssue,
+		DisallowPublicDisclosure: oc.DisallowPublicDisclosure,
+		PublicDisclosurePatterns: oc.PublicDisclosurePatterns,
+		DisallowPlaceholder:      oc.DisallowPlaceholder,
+		PlaceholderPatterns:      oc.PlaceholderPatterns,
+		RequiredContents:         oc.RequiredContents,
+		MinLength:                oc.MinLength,
+		AcceptSecurityTxt:        oc.AcceptSecurityTxt,
+		FixMode:                  oc.FixMode,
+		CommitMessage:            oc.CommitMessage,
+		CommitAuthorName:         oc.CommitAuthorName,
+		CommitAuthorEmail:        oc.CommitAuthorEmail,
+		SignOff:                  oc.SignOff,
+		PRTitle:                  oc.PRTitle,
+		PRBody:                   oc.PRBody,
+		SecurityTemplate:         oc.SecurityTemplate,
+		AuditComment:             oc.AuditComment,
+		NotifyText:               oc.NotifyText,
+		NotifyTextByLang:         oc.NotifyTextByLang,
+		Lang:                     oc.DefaultLang,
+		OnlyIfHasReleases:        oc.OnlyIfHasReleases,
+		SkipTemplates:            oc.SkipTemplates,
+		BotAccounts:              oc.BotAccounts,
+		MinPushWithinDays:        oc.MinPushWithinDays,
+		EnforceForTeams:          oc.EnforceForTeams,
+		CheckEmptyRepos:          oc.CheckEmptyRepos,
+		CheckArchived:            oc.CheckArchived,
+		RulesetEvaluator:         oc.RulesetEvaluator,
+		Ruleset:                  oc.Ruleset,
+		RequireCodeOwnerReview:   oc.RequireCodeOwnerReview,
+		RequireSupportedVersions: oc.RequireSupportedVersions,
+		RequireTimeline:          oc.RequireTimeline,
+		TimelinePatterns:         oc.TimelinePatterns,
+		RepoGroups:               oc.RepoGroups,
+		RequireReadmeLink:        oc.RequireReadmeLink,
+		ContactChannels:          oc.ContactChannels,
+		MinContactChannels:       oc.MinContactChannels,
+		RequireContactDomains:    oc.RequireContactDomains,
+		VerifyPolicyURL:          oc.VerifyPolicyURL,
+		VerifyRawURL:             oc.VerifyRawURL,
+		DetectRegression:         oc.DetectRegression,
+		RequireRegularFile:       oc.RequireRegularFile,
+		MinLengthFraction:        oc.MinLengthFraction,
+		Paths:                    oc.Paths,
+		SubPaths:                 oc.SubPaths,
+		SubPathsOnly:             oc.SubPathsOnly,
+		Branches:                 oc.Branches,
+		BranchQuorum:             oc.BranchQuorum,
+		EscalateOnAdvisories:     oc.EscalateOnAdvisories,
+		AcceptUpstreamPolicy:     oc.AcceptUpstreamPolicy,
+		RequireSecurityMD:        oc.RequireSecurityMD,
+		RenotifyIntervalDays:     oc.RenotifyIntervalDays,
+		DryRun:                   oc.DryRun,
 	}
 
+	var overridden []string
 	if !oc.OptConfig.DisableRepoOverride {
 		if rc.Action != nil {
+			if *rc.Action != mc.Action {
+				overridden = append(overridden, "action")
+			}
 			mc.Action = *rc.Action
 		}
+		if rc.MinStarsForIssue != nil {
+			if *rc.MinStarsForIssue != mc.MinStarsForIssue {
+				overridden = append(overridden, "minStarsForIssue")
+			}
+			mc.MinStarsForIssue = *rc.MinStarsForIssue
+		}
+		if rc.DisallowPublicDisclosure != nil {
+			if *rc.DisallowPublicDisclosure != mc.DisallowPublicDisclosure {
+				overridden = append(overridden, "disallowPublicDisclosure")
+			}
+			mc.DisallowPublicDisclosure = *rc.DisallowPublicDisclosure
+		}
+		if rc.AcceptSecurityTxt != nil {
+			if *rc.AcceptSecurityTxt != mc.AcceptSecurityTxt {
+				overridden = append(overridden, "acceptSecurityTxt")
+			}
+			mc.AcceptSecurityTxt = *rc.AcceptSecurityTxt
+		}
+		if rc.NotifyText != nil {
+			if *rc.NotifyText != mc.NotifyText {
+				overridden = append(overridden, "notifyText")
+			}
+			mc.NotifyText = *rc.NotifyText
+		}
+		if rc.Lang != nil {
+			if *rc.Lang != mc.Lang {
+				overridden = append(overridden, "lang")
+			}
+			mc.Lang = *rc.Lang
+		}
+		if rc.OnlyIfHasReleases != nil {
+			if *rc.OnlyIfHasReleases != mc.OnlyIfHasReleases {
+				overridden = append(overridden, "onlyIfHasReleases")
+			}
+			mc.OnlyIfHasReleases = *rc.OnlyIfHasReleases
+		}
+		if rc.Paths != nil {
+			if strings.Join(rc.Paths, "\n") != strings.Join(mc.Paths, "\n") {
+				overridden = append(overridden, "paths")
+			}
+			mc.Paths = rc.Paths
+		}
+		if rc.SubPaths != nil {
+			if strings.Join(rc.SubPaths, "\n") != strings.Join(mc.SubPaths, "\n") {
+				overridden = append(overridden, "subPaths")
+			}
+			mc.SubPaths = rc.SubPaths
+		}
+		if rc.SubPathsOnly != nil {
+			if *rc.SubPathsOnly != mc.SubPathsOnly {
+				overridden = append(overridden, "subPathsOnly")
+			}
+			mc.SubPathsOnly = *rc.SubPathsOnly
+		}
+		if rc.Branches != nil {
+			if strings.Join(rc.Branches, "\n") != strings.Join(mc.Branches, "\n") {
+				overridden = append(overridden, "branches")
+			}
+			mc.Branches = rc.Branches
+		}
+		if rc.RenotifyIntervalDays != nil {
+			if *rc.RenotifyIntervalDays != mc.RenotifyIntervalDays {
+				overridden = append(overridden, "renotifyIntervalDays")
+			}
+			mc.RenotifyIntervalDays = *rc.RenotifyIntervalDays
+		}
+		if rc.DryRun != nil {
+			if *rc.DryRun != mc.DryRun {
+				overridden = append(overridden, "dryRun")
+			}
+			mc.DryRun = *rc.DryRun
+		}
+		if rc.SecurityTemplate != nil {
+			if *rc.SecurityTemplate != mc.SecurityTemplate {
+				overridden = append(overridden, "securityTemplate")
+			}
+			mc.SecurityTemplate = *rc.SecurityTemplate
+		}
 	}
+	mc.overridden = overridden
 	return mc
 }
+
+// skipReason returns why the repo should be skipped as a template, inactive,
+// or bot-owned repo, or empty if it should be checked.
+func skipReason(ctx context.Context, v4c v4client, mc *mergedConfig, owner, repo string) (string, error) {
+	var q struct {
+		Repository struct {
+			IsTemplate bool
+			PushedAt   githubv4.DateTime
+			Owner      struct {
+				Login string
+			}
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	variables := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+	}
+	if err := v4c.Query(ctx, &q, variables); err != nil {
+		return "", err
+	}
+	if mc.SkipTemplates && q.Repository.IsTemplate {
+		return "template repository", nil
+	}
+	if mc.MinPushWithinDays > 0 {
+		cutoff := timeNow().AddDate(0, 0, -mc.MinPushWithinDays)
+		if q.Repository.PushedAt.Before(cutoff) {
+			return fmt.Sprintf("no pushes in the last %v days", mc.MinPushWithinDays), nil
+		}
+	}
+	for _, b := range mc.BotAccounts {
+		if strings.EqualFold(b, q.Repository.Owner.Login) {
+			return "owned by bot account " + q.Repository.Owner.Login, nil
+		}
+	}
+	return "", nil
+}
+
+// hasReleases returns true if the repo has any published release or tag.
+func hasReleases(ctx context.Context, v4c v4client, owner, repo string) (bool, error) {
+	var q struct {
+		Repository struct {
+			Releases struct {
+				TotalCount int
+			}
+			Refs struct {
+				TotalCount int
+			} `graphql:"refs(refPrefix: \"refs/tags/\")"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	variables := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+	}
+	if err := v4c.Query(ctx, &q, variables); err != nil {
+		return false, err
+	}
+	return q.Repository.Releases.TotalCount > 0 || q.Repository.Refs.TotalCount > 0, nil
+}
+
+// RenderNotifyText returns the issue text the issue action would use for res
+// on owner/repo under the org config cfg, without contacting GitHub. Unlike
+// the action, which logs and falls back to the default text, it returns an
+// error if a configured notifyText template is invalid. This lets config
+// authors test their templates, such as in CI. As CODEOWNERS can't be read
+// without GitHub, mentions are of cfg.DefaultMentions.
+func RenderNotifyText(cfg *OrgConfig, owner, repo string, res *policydef.Result) (string, error) {
+	if res.Pass {
+		return "", nil
+	}
+	// Only the text is returned, so leave the details of res unredacted.
+	r := policydef.Result{Enabled: res.Enabled, NotifyText: res.NotifyText}
+	for _, reason := range res.Reasons {
+		if reason == ReasonMissing {
+			mc := mergeConfig(context.Background(), cfg, &RepoConfig{}, repo)
+			d, _ := res.Details.(SecurityDetails)
+			t, err := renderMissingNotifyText(mc, owner, repo, d)
+			if err != nil {
+				return "", err
+			}
+			r.NotifyText = t
+			break
+		}
+	}
+	finishNotifyText(context.Background(), cfg, owner, repo, &r, func() string {
+		return mentionLine(cfg.DefaultMentions)
+	})
+	return r.NotifyText, nil
+}
+
+// missingNotifyText returns the issue text for a missing security policy, in
+// the configured locale if NotifyTextByLang has it, otherwise NotifyText or the
+// built-in English text.
+func missingNotifyText(ctx context.Context, mc *mergedConfig, owner, repo string, d SecurityDetails) string {
+	t, err := renderMissingNotifyText(mc, owner, repo, d)
+	if err == nil {
+		return t
+	}
+	runid.Logger(ctx).Warn().
+		Str("org", owner).
+		Str("repo", repo).
+		Str("area", polName).
+		Str("lang", mc.Lang).
+		Err(err).
+		Msg("Invalid notifyText template, using default.")
+	return decorateMissingNotifyText(defaultMissingNotifyText(owner, repo), d)
+}
+
+func renderMissingNotifyText(mc *mergedConfig, owner, repo string, d SecurityDetails) (string, error) {
+	text, ok := mc.NotifyTextByLang[mc.Lang]
+	if !ok || mc.Lang == "" {
+		text = mc.NotifyText
+	}
+	if text == "" {
+		return decorateMissingNotifyText(defaultMissingNotifyText(owner, repo), d), nil
+	}
+	t, err := renderTemplate("notifyText", text, templateData{
+		Owner:     owner,
+		Repo:      repo,
+		Path:      fixPath,
+		Org:       owner,
+		PolicyURL: fmt.Sprintf("https://github.com/%v/%v/security/policy", owner, repo),
+	})
+	if err != nil {
+		return "", err
+	}
+	return decorateMissingNotifyText(t, d), nil
+}
+
+// decorateMissingNotifyText adds the requireSecurityMD and open advisories
+// text to the missing policy text, as d calls for.
+func decorateMissingNotifyText(text string, d SecurityDetails) string {
+	if d.Inherited {
+		text = requireSecurityMDText + text
+	}
+	if d.OpenAdvisories > 0 {
+		text = advisoriesNotifyText(d.OpenAdvisories, text)
+	}
+	return text
+}
+
+func defaultMissingNotifyText(owner, repo string) string {
+	return "Security policy not enabled.\n" + fmt.Sprintf(notifyText, owner, repo)
+}
+
+func rolloutAction(ctx context.Context, oc *OrgConfig, repo string) string {
+	if len(oc.Rollout) == 0 {
+		return oc.Action
+	}
+	start, err := time.Parse("2006-01-02", oc.RolloutStart)
+	if err != nil {
+		runid.Logger(ctx).Warn().
+			Str("repo", repo).
+			Str("area", polName).
+			Str("rolloutStart", oc.RolloutStart).
+			Err(err).
+			Msg("Invalid rollout start date, using configured action.")
+		return oc.Action
+	}
+	now := timeNow()
+	if now.Before(start) {
+		return oc.Action
+	}
+	end := start
+	for _, st := range oc.Rollout {
+		end = end.AddDate(0, 0, st.Days)
+		if now.Before(end) {
+			return st.Action
+		}
+	}
+	return oc.Rollout[len(oc.Rollout)-1].Action
+}
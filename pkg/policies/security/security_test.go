@@ -16,29 +16,91 @@ package security
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-github/v39/github"
 	"github.com/ossf/allstar/pkg/config"
 	"github.com/ossf/allstar/pkg/policydef"
+	"github.com/shurcooL/githubv4"
 )
 
 var query func(context.Context, interface{}, map[string]interface{}) error
 
 type mockClient struct{}
 
+// orgDefaultURL is the security policy URL of the org's .github repo served by
+// mockClient, empty for none.
+var orgDefaultURL string
+
+type orgDefaultQuery = struct {
+	Repository struct {
+		IsSecurityPolicyEnabled bool
+		SecurityPolicyUrl       string
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
 func (m mockClient) Query(ctx context.Context, q interface{}, v map[string]interface{}) error {
+	if qc, ok := q.(*orgDefaultQuery); ok && v["name"] == githubv4.String(orgDefaultRepo) {
+		qc.Repository.IsSecurityPolicyEnabled = orgDefaultURL != ""
+		qc.Repository.SecurityPolicyUrl = orgDefaultURL
+		return nil
+	}
 	return query(ctx, q, v)
 }
 
+var getContents func(context.Context, string, string, string,
+	*github.RepositoryContentGetOptions) (*github.RepositoryContent,
+	[]*github.RepositoryContent, *github.Response, error)
+
+type mockRepos struct{}
+
+func (m mockRepos) GetContents(ctx context.Context, owner, repo, path string,
+	opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+	[]*github.RepositoryContent, *github.Response, error) {
+	return getContents(ctx, owner, repo, path, opts)
+}
+
+// contentsMock returns a GetContents mock which serves the provided files,
+// keyed by path, and 404 for any other path.
+func contentsMock(files map[string]string) func(context.Context, string, string, string,
+	*github.RepositoryContentGetOptions) (*github.RepositoryContent,
+	[]*github.RepositoryContent, *github.Response, error) {
+	return func(ctx context.Context, owner, repo, path string,
+		opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+		[]*github.RepositoryContent, *github.Response, error) {
+		f, ok := files[path]
+		if !ok {
+			return nil, nil, &github.Response{
+				Response: &http.Response{StatusCode: http.StatusNotFound},
+			}, &github.ErrorResponse{}
+		}
+		e := "base64"
+		c := base64.StdEncoding.EncodeToString([]byte(f))
+		return &github.RepositoryContent{
+			Encoding: &e,
+			Content:  &c,
+		}, nil, nil, nil
+	}
+}
+
 func TestCheck(t *testing.T) {
+	expires := time.Now().AddDate(0, 1, 0).Format("2006-01-02")
 	tests := []struct {
-		Name       string
-		Org        OrgConfig
-		Repo       RepoConfig
-		SecEnabled bool
-		Exp        policydef.Result
+		Name        string
+		Org         OrgConfig
+		Repo        RepoConfig
+		SecEnabled  bool
+		Files       map[string]string
+		Attestation *Attestation
+		Exp         policydef.Result
 	}{
 		{
 			Name:       "NotEnabled",
@@ -49,9 +111,11 @@ func TestCheck(t *testing.T) {
 				Enabled:    false,
 				Pass:       true,
 				NotifyText: "",
-				Details: details{
-					Enabled: true,
-					URL:     "",
+				Details: SecurityDetails{
+					Enabled:   true,
+					URL:       "",
+					Mechanism: mechanismSecurityMD,
+					Source:    SourceRepoFile,
 				},
 			},
 		},
@@ -68,9 +132,173 @@ func TestCheck(t *testing.T) {
 				Enabled:    true,
 				Pass:       true,
 				NotifyText: "",
-				Details: details{
-					Enabled: true,
-					URL:     "",
+				Details: SecurityDetails{
+					Enabled:   true,
+					URL:       "",
+					Mechanism: mechanismSecurityMD,
+					Source:    SourceRepoFile,
+				},
+			},
+		},
+		{
+			Name: "IndexLag",
+			Org: OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+				},
+			},
+			Repo:       RepoConfig{},
+			SecEnabled: false,
+			Files: map[string]string{
+				"SECURITY.md": "# Security\nEmail security@example.com.\n",
+			},
+			Exp: policydef.Result{
+				Enabled:    true,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					Enabled:   false,
+					URL:       "",
+					Mechanism: mechanismSecurityMD,
+					IndexLag:  "SECURITY.md found, GitHub does not yet show a security policy",
+					Source:    SourceRepoFile,
+				},
+			},
+		},
+		{
+			Name: "PublicDisclosure",
+			Org: OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+				},
+				DisallowPublicDisclosure: true,
+			},
+			Repo:       RepoConfig{},
+			SecEnabled: true,
+			Files: map[string]string{
+				"SECURITY.md": "# Security\nTo report a vulnerability open an issue.\n",
+			},
+			Exp: policydef.Result{
+				Enabled:    true,
+				Pass:       false,
+				NotifyText: "Security policy instructs public disclosure",
+				Reasons:    []string{ReasonPublicDisclosure},
+				Details: SecurityDetails{
+					FailedRequirements:   []string{ReasonPublicDisclosure},
+					Enabled:              true,
+					URL:                  "",
+					Mechanism:            mechanismSecurityMD,
+					PublicDisclosureLine: "To report a vulnerability open an issue.",
+					FileType:             "file",
+				},
+			},
+		},
+		{
+			Name: "PrivateDisclosure",
+			Org: OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+				},
+				DisallowPublicDisclosure: true,
+			},
+			Repo:       RepoConfig{},
+			SecEnabled: true,
+			Files: map[string]string{
+				".github/SECURITY.md": "# Security\nDo not open an issue for a vulnerability, email us.\n",
+			},
+			Exp: policydef.Result{
+				Enabled:    true,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					Enabled:       true,
+					URL:           "",
+					Mechanism:     mechanismSecurityMD,
+					FileType:      "file",
+					SatisfiedPath: ".github/SECURITY.md",
+					Source:        SourceRepoFile,
+				},
+			},
+		},
+		{
+			Name: "Attested",
+			Org: OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+				},
+				AllowAttestation: true,
+			},
+			Repo:       RepoConfig{},
+			SecEnabled: false,
+			Attestation: &Attestation{
+				AttestedBy: "maintainer",
+				Reason:     "Reports handled via internal tracker",
+				Expires:    expires,
+			},
+			Exp: policydef.Result{
+				Enabled:    true,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					Attestation: &Attestation{
+						AttestedBy: "maintainer",
+						Reason:     "Reports handled via internal tracker",
+						Expires:    expires,
+					},
+					Source: SourceAttestation,
+				},
+			},
+		},
+		{
+			Name: "AttestationNotAllowed",
+			Org: OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+				},
+			},
+			Repo:       RepoConfig{},
+			SecEnabled: false,
+			Attestation: &Attestation{
+				AttestedBy: "maintainer",
+				Reason:     "Reports handled via internal tracker",
+				Expires:    expires,
+			},
+			Exp: policydef.Result{
+				Enabled:    true,
+				Pass:       false,
+				NotifyText: "Security policy not enabled.\nA SECURITY.md file can give users information about what constitutes a vulnerability",
+				Reasons:    []string{ReasonMissing},
+				Details: SecurityDetails{
+					FailedRequirements: []string{ReasonMissing},
+					Enabled:            false,
+					URL:                "",
+				},
+			},
+		},
+		{
+			Name: "Exempt",
+			Org: OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+					Exemptions: []config.Exemption{
+						{
+							Repo:       "thisrepo",
+							Reason:     "Archived mirror",
+							ApprovedBy: "secteam",
+						},
+					},
+				},
+			},
+			Repo:       RepoConfig{},
+			SecEnabled: false,
+			Exp: policydef.Result{
+				Enabled:    false,
+				Pass:       true,
+				NotifyText: "",
+				Details: SecurityDetails{
+					Skipped: "exempt",
+					Exempt:  "Archived mirror",
+					Source:  SourceSkipped,
 				},
 			},
 		},
@@ -87,9 +315,11 @@ func TestCheck(t *testing.T) {
 				Enabled:    true,
 				Pass:       false,
 				NotifyText: "Security policy not enabled.\nA SECURITY.md file can give users information about what constitutes a vulnerability",
-				Details: details{
-					Enabled: false,
-					URL:     "",
+				Reasons:    []string{ReasonMissing},
+				Details: SecurityDetails{
+					FailedRequirements: []string{ReasonMissing},
+					Enabled:            false,
+					URL:                "",
 				},
 			},
 		},
@@ -99,29 +329,39 @@ func TestCheck(t *testing.T) {
 		t.Run(test.Name, func(t *testing.T) {
 			configFetchConfig = func(ctx context.Context, c *github.Client,
 				owner string, repo string, path string, out interface{}) error {
-				if repo == "thisrepo" {
-					rc := out.(*RepoConfig)
-					*rc = test.Repo
-				} else {
-					oc := out.(*OrgConfig)
-					*oc = test.Org
+				switch v := out.(type) {
+				case *OrgConfig:
+					*v = test.Org
+				case *RepoConfig:
+					*v = test.Repo
+				case *Attestation:
+					if test.Attestation != nil {
+						*v = *test.Attestation
+					}
 				}
 				return nil
 			}
+			getContents = contentsMock(test.Files)
 			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
 				qc, ok := q.(*struct {
 					Repository struct {
 						SecurityPolicyUrl       string
 						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
 					} `graphql:"repository(owner: $owner, name: $name)"`
 				})
 				if !ok {
 					t.Errorf("Query() called with unexpected query structure.")
 				}
 				qc.Repository.IsSecurityPolicyEnabled = test.SecEnabled
+				qc.Repository.DefaultBranchRef.Name = "main"
 				return nil
 			}
-			res, err := check(context.Background(), nil, mockClient{}, "", "thisrepo")
+			res, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "", "thisrepo")
 			if err != nil {
 				t.Fatalf("Unexpected error: %v", err)
 			}
@@ -133,9 +373,1022 @@ func TestCheck(t *testing.T) {
 	}
 }
 
-func trunc(s string, n int) string {
-	if n >= len(s) {
-		return s
+func TestCheckRef(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Ref        string
+		SecEnabled bool
+		Pass       bool
+	}{
+		{Name: "AddedInRef", Ref: "abc123", SecEnabled: false, Pass: true},
+		{Name: "RemovedInRef", Ref: "def456", SecEnabled: true, Pass: false},
+		{Name: "NoRef", SecEnabled: false, Pass: false},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner string, repo string, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					oc.OptConfig.OptOutStrategy = true
+				}
+				return nil
+			}
+			getContents = func(ctx context.Context, owner, repo, path string,
+				opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+				[]*github.RepositoryContent, *github.Response, error) {
+				if path != "SECURITY.md" || opts == nil || opts.Ref != "abc123" {
+					return nil, nil, &github.Response{
+						Response: &http.Response{StatusCode: http.StatusNotFound},
+					}, &github.ErrorResponse{}
+				}
+				return &github.RepositoryContent{
+					Content: github.String("Email security@example.com"),
+				}, nil, nil, nil
+			}
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				qc := q.(*struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				})
+				qc.Repository.IsSecurityPolicyEnabled = test.SecEnabled
+				qc.Repository.DefaultBranchRef.Name = "main"
+				return nil
+			}
+			ctx := withCheckRef(context.Background(), test.Ref)
+			res, err := check(ctx, mockRepos{}, nil, mockClient{}, "org", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Pass != test.Pass {
+				t.Errorf("Unexpected pass. Expected: %v Got: %v", test.Pass, res.Pass)
+			}
+			if got := res.Details.(SecurityDetails).Ref; got != test.Ref {
+				t.Errorf("Unexpected ref. Expected: %v Got: %v", test.Ref, got)
+			}
+		})
+	}
+}
+
+func TestCheckRequireRegularFile(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Type    string
+		Content string
+		Pass    bool
+	}{
+		{Name: "File", Type: "file", Content: "Email security@example.com", Pass: true},
+		{Name: "EmptyFile", Type: "file", Content: "", Pass: false},
+		{Name: "Symlink", Type: "symlink", Pass: false},
+		{Name: "Submodule", Type: "submodule", Pass: false},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner string, repo string, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					oc.OptConfig.OptOutStrategy = true
+					oc.RequireRegularFile = true
+				}
+				return nil
+			}
+			getContents = func(ctx context.Context, owner, repo, path string,
+				opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+				[]*github.RepositoryContent, *github.Response, error) {
+				if path != "SECURITY.md" {
+					return nil, nil, &github.Response{
+						Response: &http.Response{StatusCode: http.StatusNotFound},
+					}, &github.ErrorResponse{}
+				}
+				return &github.RepositoryContent{
+					Type:    github.String(test.Type),
+					Content: github.String(test.Content),
+				}, nil, nil, nil
+			}
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				qc := q.(*struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				})
+				qc.Repository.IsSecurityPolicyEnabled = true
+				qc.Repository.DefaultBranchRef.Name = "main"
+				return nil
+			}
+			res, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Pass != test.Pass {
+				t.Errorf("Unexpected pass. Expected: %v Got: %v", test.Pass, res.Pass)
+			}
+			if ft := res.Details.(SecurityDetails).FileType; ft != test.Type {
+				t.Errorf("Unexpected file type. Expected: %v Got: %v", test.Type, ft)
+			}
+			if !test.Pass && (len(res.Reasons) != 1 || res.Reasons[0] != ReasonNotRegularFile) {
+				t.Errorf("Unexpected reasons: %v", res.Reasons)
+			}
+		})
+	}
+}
+
+func TestCheckNoAccess(t *testing.T) {
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner string, repo string, path string, out interface{}) error {
+		if oc, ok := out.(*OrgConfig); ok {
+			oc.OptConfig.OptOutStrategy = true
+			oc.DisallowPublicDisclosure = true
+		}
+		return nil
+	}
+	req, _ := http.NewRequest("GET", "https://api.github.com/repos/org/thisrepo/contents/SECURITY.md", nil)
+	forbidden := &github.ErrorResponse{
+		Response: &http.Response{StatusCode: http.StatusForbidden, Request: req},
+		Message:  "Resource not accessible by integration",
+	}
+	t.Run("GraphQL", func(t *testing.T) {
+		query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+			return errors.New("Resource not accessible by integration")
+		}
+		res, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "", "thisrepo")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if res.Enabled || len(res.Reasons) != 1 || res.Reasons[0] != ReasonNoAccess {
+			t.Errorf("Unexpected result: %+v", res)
+		}
+	})
+	t.Run("Contents", func(t *testing.T) {
+		query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+			qc := q.(*struct {
+				Repository struct {
+					SecurityPolicyUrl       string
+					IsSecurityPolicyEnabled bool
+					IsArchived              bool
+					IsDisabled              bool
+					DefaultBranchRef        struct {
+						Name string
+					}
+				} `graphql:"repository(owner: $owner, name: $name)"`
+			})
+			qc.Repository.IsSecurityPolicyEnabled = true
+			qc.Repository.DefaultBranchRef.Name = "main"
+			return nil
+		}
+		getContents = func(ctx context.Context, owner, repo, path string,
+			opts *github.RepositoryContentGetOptions) (*github.RepositoryContent,
+			[]*github.RepositoryContent, *github.Response, error) {
+			return nil, nil, &github.Response{Response: forbidden.Response}, forbidden
+		}
+		res, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "", "thisrepo")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if res.Enabled || len(res.Reasons) != 1 || res.Reasons[0] != ReasonNoAccess {
+			t.Errorf("Unexpected result: %+v", res)
+		}
+	})
+	t.Run("Other", func(t *testing.T) {
+		query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+			return errors.New("something went wrong")
+		}
+		if _, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "", "thisrepo"); err == nil {
+			t.Errorf("Expected error")
+		}
+	})
+}
+
+func TestCheckTimeout(t *testing.T) {
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner string, repo string, path string, out interface{}) error {
+		return nil
+	}
+	query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("Expected query context to have a deadline")
+		}
+		return context.DeadlineExceeded
+	}
+	if _, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "", "thisrepo"); err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+func TestMergeConfigRollout(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2021, 9, 10, 12, 0, 0, 0, time.UTC)
+	}
+	defer func() { timeNow = time.Now }()
+	issue := "issue"
+	rollout := []RolloutStage{
+		{Action: "log", Days: 7},
+		{Action: "issue", Days: 7},
+		{Action: "fix"},
+	}
+	tests := []struct {
+		Name   string
+		Org    OrgConfig
+		Repo   RepoConfig
+		Expect string
+	}{
+		{
+			Name:   "NoRollout",
+			Org:    OrgConfig{Action: "issue"},
+			Expect: "issue",
+		},
+		{
+			Name: "BeforeStart",
+			Org: OrgConfig{
+				Action:       "log",
+				RolloutStart: "2021-09-20",
+				Rollout:      rollout,
+			},
+			Expect: "log",
+		},
+		{
+			Name: "FirstStage",
+			Org: OrgConfig{
+				RolloutStart: "2021-09-05",
+				Rollout:      rollout,
+			},
+			Expect: "log",
+		},
+		{
+			Name: "SecondStage",
+			Org: OrgConfig{
+				RolloutStart: "2021-09-01",
+				Rollout:      rollout,
+			},
+			Expect: "issue",
+		},
+		{
+			Name: "LastStage",
+			Org: OrgConfig{
+				RolloutStart: "2021-08-01",
+				Rollout:      rollout,
+			},
+			Expect: "fix",
+		},
+		{
+			Name: "BadDate",
+			Org: OrgConfig{
+				Action:       "log",
+				RolloutStart: "September",
+				Rollout:      rollout,
+			},
+			Expect: "log",
+		},
+		{
+			Name: "RepoOverride",
+			Org: OrgConfig{
+				RolloutStart: "2021-08-01",
+				Rollout:      rollout,
+			},
+			Repo:   RepoConfig{Action: &issue},
+			Expect: "issue",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mc := mergeConfig(context.Background(), &test.Org, &test.Repo, "thisrepo")
+			if mc.Action != test.Expect {
+				t.Errorf("Unexpected action. Expected: %v Got: %v", test.Expect, mc.Action)
+			}
+		})
+	}
+}
+
+func TestGetActionMinStars(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Org    OrgConfig
+		Stars  int
+		Expect string
+	}{
+		{
+			Name:   "NoThreshold",
+			Org:    OrgConfig{Action: "issue"},
+			Stars:  0,
+			Expect: "issue",
+		},
+		{
+			Name:   "AboveThreshold",
+			Org:    OrgConfig{Action: "issue", MinStarsForIssue: 100},
+			Stars:  150,
+			Expect: "issue",
+		},
+		{
+			Name:   "BelowThreshold",
+			Org:    OrgConfig{Action: "issue", MinStarsForIssue: 100},
+			Stars:  50,
+			Expect: "log",
+		},
+		{
+			Name:   "NotIssueAction",
+			Org:    OrgConfig{Action: "fix", MinStarsForIssue: 100},
+			Stars:  50,
+			Expect: "fix",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner string, repo string, path string, out interface{}) error {
+				if repo != "thisrepo" {
+					oc := out.(*OrgConfig)
+					*oc = test.Org
+				}
+				return nil
+			}
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				qc, ok := q.(*struct {
+					Repository struct {
+						StargazerCount int
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				})
+				if !ok {
+					t.Fatalf("Query() called with unexpected query structure.")
+				}
+				qc.Repository.StargazerCount = test.Stars
+				return nil
+			}
+			got := getAction(context.Background(), nil, mockClient{}, "", "thisrepo")
+			if got != test.Expect {
+				t.Errorf("Unexpected action. Expected: %v Got: %v", test.Expect, got)
+			}
+		})
+	}
+}
+
+func TestGetActionByReason(t *testing.T) {
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner string, repo string, path string, out interface{}) error {
+		if oc, ok := out.(*OrgConfig); ok {
+			oc.Action = "log"
+			oc.Actions = map[string]string{
+				ReasonMissing:         "issue",
+				ReasonContactChannels: "pr",
+			}
+		}
+		return nil
+	}
+	tests := []struct {
+		Name    string
+		Reasons []string
+		Expect  string
+	}{
+		{Name: "Mapped", Reasons: []string{ReasonMissing}, Expect: "issue"},
+		{Name: "FirstMapped", Reasons: []string{ReasonRegression, ReasonContactChannels, ReasonMissing}, Expect: "pr"},
+		{Name: "Unmapped", Reasons: []string{ReasonRegression}, Expect: "log"},
+		{Name: "NoReasons", Expect: "log"},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ctx := policydef.WithReasons(context.Background(), test.Reasons)
+			got := getAction(ctx, nil, mockClient{}, "", "thisrepo")
+			if got != test.Expect {
+				t.Errorf("Unexpected action. Expected: %v Got: %v", test.Expect, got)
+			}
+		})
+	}
+}
+
+func TestMissingNotifyText(t *testing.T) {
+	byLang := map[string]string{
+		"es":  "Falta la política de seguridad en {{.Owner}}/{{.Repo}}.",
+		"bad": "{{.Owner",
+	}
+	english := "Security policy not enabled.\n" + fmt.Sprintf(notifyText, "org", "thisrepo")
+	tests := []struct {
+		Name   string
+		Lang   string
+		Expect string
+	}{
+		{
+			Name:   "Localized",
+			Lang:   "es",
+			Expect: "Falta la política de seguridad en org/thisrepo.",
+		},
+		{
+			Name:   "Default",
+			Lang:   "",
+			Expect: english,
+		},
+		{
+			Name:   "MissingLang",
+			Lang:   "fr",
+			Expect: english,
+		},
+		{
+			Name:   "BadTemplate",
+			Lang:   "bad",
+			Expect: english,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mc := &mergedConfig{
+				NotifyTextByLang: byLang,
+				Lang:             test.Lang,
+			}
+			got := missingNotifyText(context.Background(), mc, "org", "thisrepo", SecurityDetails{})
+			if got != test.Expect {
+				t.Errorf("Unexpected text. Expected: %q Got: %q", test.Expect, got)
+			}
+		})
+	}
+}
+
+func TestMissingNotifyTextCustom(t *testing.T) {
+	english := defaultMissingNotifyText("org", "thisrepo")
+	custom := "Add SECURITY.md to {{.Org}}/{{.Repo}}, see {{.PolicyURL}}."
+	tests := []struct {
+		Name   string
+		Org    string
+		Repo   *string
+		Lang   string
+		Expect string
+	}{
+		{
+			Name:   "Default",
+			Expect: english,
+		},
+		{
+			Name:   "Org",
+			Org:    custom,
+			Expect: "Add SECURITY.md to org/thisrepo, see https://github.com/org/thisrepo/security/policy.",
+		},
+		{
+			Name:   "RepoOverride",
+			Org:    custom,
+			Repo:   github.String("Repo text for {{.Repo}}."),
+			Expect: "Repo text for thisrepo.",
+		},
+		{
+			Name:   "Localized",
+			Org:    custom,
+			Lang:   "es",
+			Expect: "Falta en thisrepo.",
+		},
+		{
+			Name:   "BadTemplate",
+			Org:    "{{.Org",
+			Expect: english,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			oc := &OrgConfig{
+				NotifyText:       test.Org,
+				NotifyTextByLang: map[string]string{"es": "Falta en {{.Repo}}."},
+				DefaultLang:      test.Lang,
+			}
+			mc := mergeConfig(context.Background(), oc, &RepoConfig{NotifyText: test.Repo}, "thisrepo")
+			got := missingNotifyText(context.Background(), mc, "org", "thisrepo", SecurityDetails{})
+			if got != test.Expect {
+				t.Errorf("Unexpected text. Expected: %q Got: %q", test.Expect, got)
+			}
+		})
+	}
+}
+
+func TestRenderNotifyText(t *testing.T) {
+	oc := &OrgConfig{
+		DefaultLang: "es",
+		NotifyTextByLang: map[string]string{
+			"es": "Falta la política de seguridad en {{.Owner}}/{{.Repo}}.",
+		},
+	}
+	missing := &policydef.Result{Pass: false, Reasons: []string{ReasonMissing}}
+	got, err := RenderNotifyText(oc, "org", "thisrepo", missing)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "Falta la política de seguridad en org/thisrepo." {
+		t.Errorf("Unexpected text: %q", got)
+	}
+	other := &policydef.Result{Pass: false, NotifyText: "other", Reasons: []string{ReasonRuleset}}
+	if got, err := RenderNotifyText(oc, "org", "thisrepo", other); err != nil || got != "other" {
+		t.Errorf("Unexpected text: %q, err: %v", got, err)
+	}
+	if got, err := RenderNotifyText(oc, "org", "thisrepo", &policydef.Result{Pass: true}); err != nil || got != "" {
+		t.Errorf("Expected no text for passing result, got: %q, err: %v", got, err)
+	}
+	oc.NotifyTextByLang["es"] = "{{.Owner"
+	if _, err := RenderNotifyText(oc, "org", "thisrepo", missing); err == nil {
+		t.Errorf("Expected error for invalid template")
+	}
+}
+
+func TestRenderNotifyTextMatchesAction(t *testing.T) {
+	oc := &OrgConfig{
+		NotifyText:        "Add a policy to {{.Repo}}, token=abc123.",
+		RedactPatterns:    []string{"token=[a-z0-9]+"},
+		MentionCodeOwners: true,
+		DefaultMentions:   []string{"@org/security"},
+	}
+	res := &policydef.Result{
+		Enabled: true,
+		Pass:    false,
+		Reasons: []string{ReasonMissing, ReasonMissingWithAdvisories},
+		Details: SecurityDetails{Inherited: true, OpenAdvisories: 2},
+	}
+	got, err := RenderNotifyText(oc, "org", "thisrepo", res)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "Add a policy to thisrepo, " + redactedText + "."
+	want = advisoriesNotifyText(2, requireSecurityMDText+want) + "\n\ncc @org/security"
+	if got != want {
+		t.Errorf("Unexpected text. Expected: %q Got: %q", want, got)
+	}
+}
+
+func TestMergeConfigLang(t *testing.T) {
+	lang := "ja"
+	oc := &OrgConfig{DefaultLang: "es"}
+	if mc := mergeConfig(context.Background(), oc, &RepoConfig{}, "thisrepo"); mc.Lang != "es" {
+		t.Errorf("Expected org default lang, got: %v", mc.Lang)
+	}
+	if mc := mergeConfig(context.Background(), oc, &RepoConfig{Lang: &lang}, "thisrepo"); mc.Lang != "ja" {
+		t.Errorf("Expected repo lang, got: %v", mc.Lang)
+	}
+}
+
+func TestMergeConfigRenotifyInterval(t *testing.T) {
+	days := 14
+	oc := &OrgConfig{RenotifyIntervalDays: 7}
+	if mc := mergeConfig(context.Background(), oc, &RepoConfig{}, "thisrepo"); mc.RenotifyIntervalDays != 7 {
+		t.Errorf("Expected org renotify interval, got: %v", mc.RenotifyIntervalDays)
+	}
+	if mc := mergeConfig(context.Background(), oc, &RepoConfig{RenotifyIntervalDays: &days}, "thisrepo"); mc.RenotifyIntervalDays != 14 {
+		t.Errorf("Expected repo renotify interval, got: %v", mc.RenotifyIntervalDays)
+	}
+}
+
+func TestCheckOnlyIfHasReleases(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Releases int
+		Tags     int
+		Enabled  bool
+	}{
+		{
+			Name:    "NoReleases",
+			Enabled: false,
+		},
+		{
+			Name:     "Release",
+			Releases: 1,
+			Enabled:  true,
+		},
+		{
+			Name:    "Tag",
+			Tags:    2,
+			Enabled: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				switch qc := q.(type) {
+				case *struct {
+					Repository struct {
+						Releases struct {
+							TotalCount int
+						}
+						Refs struct {
+							TotalCount int
+						} `graphql:"refs(refPrefix: \"refs/tags/\")"`
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				}:
+					qc.Repository.Releases.TotalCount = test.Releases
+					qc.Repository.Refs.TotalCount = test.Tags
+				case *struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				}:
+					qc.Repository.IsSecurityPolicyEnabled = true
+					qc.Repository.DefaultBranchRef.Name = "main"
+				default:
+					t.Fatalf("Query() called with unexpected query structure.")
+				}
+				return nil
+			}
+			oc := &OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+				},
+				OnlyIfHasReleases: true,
+			}
+			res, err := checkConfig(context.Background(), mockRepos{}, nil, mockClient{},
+				"", "thisrepo", oc, &RepoConfig{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Enabled != test.Enabled {
+				t.Errorf("Unexpected enabled. Expected: %v Got: %v", test.Enabled, res.Enabled)
+			}
+			if !res.Pass {
+				t.Errorf("Expected pass")
+			}
+		})
+	}
+}
+
+func TestCheckSkip(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Org        OrgConfig
+		IsTemplate bool
+		PushedAt   time.Time
+		Owner      string
+		Enabled    bool
+	}{
+		{
+			Name:       "TemplateNotConfigured",
+			Org:        OrgConfig{BotAccounts: []string{"other-bot"}},
+			IsTemplate: true,
+			Owner:      "org",
+			Enabled:    true,
+		},
+		{
+			Name:       "Template",
+			Org:        OrgConfig{SkipTemplates: true},
+			IsTemplate: true,
+			Owner:      "org",
+			Enabled:    false,
+		},
+		{
+			Name:    "BotOwner",
+			Org:     OrgConfig{BotAccounts: []string{"Deploy-Bot"}},
+			Owner:   "deploy-bot",
+			Enabled: false,
+		},
+		{
+			Name:     "Inactive",
+			Org:      OrgConfig{MinPushWithinDays: 30},
+			PushedAt: time.Now().AddDate(0, 0, -31),
+			Owner:    "org",
+			Enabled:  false,
+		},
+		{
+			Name:     "Active",
+			Org:      OrgConfig{MinPushWithinDays: 30},
+			PushedAt: time.Now().AddDate(0, 0, -29),
+			Owner:    "org",
+			Enabled:  true,
+		},
+		{
+			Name:    "NotSkipped",
+			Org:     OrgConfig{SkipTemplates: true, BotAccounts: []string{"deploy-bot"}},
+			Owner:   "org",
+			Enabled: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				switch qc := q.(type) {
+				case *struct {
+					Repository struct {
+						IsTemplate bool
+						PushedAt   githubv4.DateTime
+						Owner      struct {
+							Login string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				}:
+					qc.Repository.IsTemplate = test.IsTemplate
+					qc.Repository.PushedAt = githubv4.DateTime{Time: test.PushedAt}
+					qc.Repository.Owner.Login = test.Owner
+				case *struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				}:
+					qc.Repository.IsSecurityPolicyEnabled = true
+					qc.Repository.DefaultBranchRef.Name = "main"
+				default:
+					t.Fatalf("Query() called with unexpected query structure.")
+				}
+				return nil
+			}
+			oc := test.Org
+			oc.OptConfig = config.OrgOptConfig{
+				OptOutStrategy: true,
+			}
+			res, err := checkConfig(context.Background(), mockRepos{}, nil, mockClient{},
+				"", "thisrepo", &oc, &RepoConfig{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Enabled != test.Enabled {
+				t.Errorf("Unexpected enabled. Expected: %v Got: %v", test.Enabled, res.Enabled)
+			}
+		})
+	}
+}
+
+func trunc(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	return s[:n]
+}
+
+func TestCheckEmptyRepo(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Check   bool
+		Enabled bool
+		Pass    bool
+	}{
+		{
+			Name:    "Skipped",
+			Check:   false,
+			Enabled: false,
+			Pass:    true,
+		},
+		{
+			Name:    "Checked",
+			Check:   true,
+			Enabled: true,
+			Pass:    false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				if _, ok := q.(*struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				}); !ok {
+					t.Fatalf("Query() called with unexpected query structure.")
+				}
+				return nil
+			}
+			oc := &OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+				},
+				CheckEmptyRepos: test.Check,
+			}
+			res, err := checkConfig(context.Background(), mockRepos{}, nil, mockClient{},
+				"", "thisrepo", oc, &RepoConfig{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Enabled != test.Enabled || res.Pass != test.Pass {
+				t.Errorf("Unexpected result. Expected enabled %v pass %v, got: %v %v",
+					test.Enabled, test.Pass, res.Enabled, res.Pass)
+			}
+		})
+	}
+}
+
+func TestCheckSubPaths(t *testing.T) {
+	tests := []struct {
+		Name       string
+		SecEnabled bool
+		SubPaths   []string
+		Only       bool
+		Pass       bool
+		Reasons    []string
+		Passed     []string
+		Missing    []string
+	}{
+		{
+			Name:       "AllPresent",
+			SecEnabled: true,
+			SubPaths:   []string{"components/api", "/components/web/"},
+			Pass:       true,
+			Passed:     []string{"components/api", "components/web"},
+		},
+		{
+			Name:       "RootMissing",
+			SecEnabled: false,
+			SubPaths:   []string{"components/api", "components/web", "components/cli"},
+			Pass:       false,
+			Reasons:    []string{ReasonMissing, ReasonSubPathMissing},
+			Passed:     []string{"components/api", "components/web"},
+			Missing:    []string{"components/cli"},
+		},
+		{
+			Name:       "SubPathsOnly",
+			SecEnabled: false,
+			SubPaths:   []string{"components/api", "components/web", "components/cli"},
+			Only:       true,
+			Pass:       false,
+			Reasons:    []string{ReasonSubPathMissing},
+			Passed:     []string{"components/api", "components/web"},
+			Missing:    []string{"components/cli"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				qc := q.(*struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				})
+				qc.Repository.IsSecurityPolicyEnabled = test.SecEnabled
+				qc.Repository.DefaultBranchRef.Name = "main"
+				return nil
+			}
+			getContents = contentsMock(map[string]string{
+				"components/api/SECURITY.md": "Email security@example.com",
+				"components/web/SECURITY.md": "Email security@example.com",
+				"components/cli/SECURITY.md": "  \n",
+			})
+			oc := &OrgConfig{
+				OptConfig: config.OrgOptConfig{
+					OptOutStrategy: true,
+				},
+				SubPaths:     test.SubPaths,
+				SubPathsOnly: test.Only,
+			}
+			res, err := checkConfig(context.Background(), mockRepos{}, nil, mockClient{},
+				"", "thisrepo", oc, &RepoConfig{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Pass != test.Pass {
+				t.Errorf("Unexpected pass. Expected: %v Got: %v", test.Pass, res.Pass)
+			}
+			if diff := cmp.Diff(test.Reasons, res.Reasons); diff != "" {
+				t.Errorf("Unexpected reasons. (-want +got):\n%s", diff)
+			}
+			d := res.Details.(SecurityDetails)
+			if diff := cmp.Diff(test.Passed, d.SubPathsPassed); diff != "" {
+				t.Errorf("Unexpected passed subpaths. (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.Missing, d.SubPathsMissing); diff != "" {
+				t.Errorf("Unexpected missing subpaths. (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPolicySource(t *testing.T) {
+	tests := []struct {
+		Repo string
+		URL  string
+		Exp  string
+	}{
+		{"thisrepo", "https://github.com/org/thisrepo/security/policy", SourceRepoFile},
+		{"thisrepo", "https://github.com/org/.github/blob/main/SECURITY.md", SourceOrgDefault},
+		{"thisrepo", "https://github.com/Org/.GitHub/blob/main/SECURITY.md", SourceOrgDefault},
+		{".github", "https://github.com/org/.github/blob/main/SECURITY.md", SourceRepoFile},
+		{"thisrepo", "", SourceRepoFile},
+	}
+	for _, test := range tests {
+		if got := policySource("org", test.Repo, test.URL); got != test.Exp {
+			t.Errorf("Unexpected source for %v %v. Expected: %v Got: %v", test.Repo, test.URL, test.Exp, got)
+		}
+	}
+}
+
+func TestPreviewIssue(t *testing.T) {
+	var s Security
+	defer func() { securityCheck = s.Check }()
+	res := &policydef.Result{Enabled: true, Pass: false, NotifyText: "Security policy not enabled."}
+	securityCheck = func(ctx context.Context, c *github.Client, owner, repo string) (*policydef.Result, error) {
+		return res, nil
+	}
+	p, err := s.PreviewIssue(context.Background(), nil, "org", "repo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p == nil || p.Title != "Security Policy violation SECURITY.md" ||
+		!strings.Contains(p.Body, "Security policy not enabled.") ||
+		!strings.Contains(p.Body, "<!-- allstar-policy: SECURITY.md -->") {
+		t.Errorf("Unexpected preview: %+v", p)
+	}
+	res = &policydef.Result{Enabled: true, Pass: true}
+	if p, err := s.PreviewIssue(context.Background(), nil, "org", "repo"); err != nil || p != nil {
+		t.Errorf("Expected no preview for passing result, got: %+v %v", p, err)
+	}
+}
+
+func TestCheckArchived(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Archived      bool
+		Disabled      bool
+		CheckArchived bool
+		ExpSkipped    string
+		ExpPass       bool
+	}{
+		{Name: "Active"},
+		{Name: "Archived", Archived: true, ExpSkipped: "archived", ExpPass: true},
+		{Name: "Disabled", Disabled: true, ExpSkipped: "disabled", ExpPass: true},
+		{Name: "CheckArchived", Archived: true, CheckArchived: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFetchConfig = func(ctx context.Context, c *github.Client,
+				owner, repo, path string, out interface{}) error {
+				if oc, ok := out.(*OrgConfig); ok {
+					oc.CheckArchived = test.CheckArchived
+				}
+				return nil
+			}
+			getContents = contentsMock(nil)
+			query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+				qc, ok := q.(*struct {
+					Repository struct {
+						SecurityPolicyUrl       string
+						IsSecurityPolicyEnabled bool
+						IsArchived              bool
+						IsDisabled              bool
+						DefaultBranchRef        struct {
+							Name string
+						}
+					} `graphql:"repository(owner: $owner, name: $name)"`
+				})
+				if !ok {
+					t.Fatalf("Query() called with unexpected query structure.")
+				}
+				qc.Repository.IsArchived = test.Archived
+				qc.Repository.IsDisabled = test.Disabled
+				qc.Repository.DefaultBranchRef.Name = "main"
+				return nil
+			}
+			res, err := check(context.Background(), mockRepos{}, nil, mockClient{}, "org", "thisrepo")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if res.Pass != test.ExpPass {
+				t.Errorf("Unexpected pass. Expected: %v Got: %v", test.ExpPass, res.Pass)
+			}
+			if d := res.Details.(SecurityDetails); d.Skipped != test.ExpSkipped {
+				t.Errorf("Unexpected skipped. Expected: %q Got: %q", test.ExpSkipped, d.Skipped)
+			}
+			if test.ExpPass && res.NotifyText != "" {
+				t.Errorf("Expected no notify text, got: %q", res.NotifyText)
+			}
+		})
+	}
+}
+
+func TestSecurityDetailsJSON(t *testing.T) {
+	res := &policydef.Result{
+		Details: SecurityDetails{
+			Inherited:          true,
+			FailedRequirements: []string{ReasonMissing},
+			Source:             "",
+		},
+	}
+	b, err := json.Marshal(res.Details)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `{"Enabled":false,"Inherited":true,"FailedRequirements":["security_policy_missing"]}`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Errorf("Unexpected JSON. (-want +got):\n%s", diff)
+	}
+	d, ok := res.Details.(SecurityDetails)
+	if !ok || !d.Inherited {
+		t.Errorf("Expected SecurityDetails, got: %T", res.Details)
 	}
-	return s[:n]
 }
@@ -0,0 +1,148 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// securityTxtCacheDuration is how long a security.txt fetch result is cached.
+const securityTxtCacheDuration = time.Hour
+
+// securityTxtMaxSize is the maximum size of security.txt to read.
+const securityTxtMaxSize = 64 * 1024
+
+var securityTxtClient = &http.Client{Timeout: 10 * time.Second}
+
+type securityTxtResult struct {
+	err     error
+	fetched time.Time
+}
+
+var securityTxtCache = struct {
+	sync.Mutex
+	m map[string]securityTxtResult
+}{m: make(map[string]securityTxtResult)}
+
+// securityTxtURL expands the {owner} and {repo} placeholders in pattern.
+func securityTxtURL(pattern, owner, repo string) string {
+	return strings.NewReplacer("{owner}", owner, "{repo}", repo).Replace(pattern)
+}
+
+// checkSecurityTxt fetches the security.txt at url and returns nil if it is
+// valid per RFC 9116. Results are cached for securityTxtCacheDuration.
+func checkSecurityTxt(ctx context.Context, url string) error {
+	now := timeNow()
+	securityTxtCache.Lock()
+	r, ok := securityTxtCache.m[url]
+	securityTxtCache.Unlock()
+	if ok && now.Sub(r.fetched) < securityTxtCacheDuration {
+		return r.err
+	}
+	err := fetchSecurityTxt(ctx, url, now)
+	if ctx.Err() != nil {
+		// Don't cache cancellation of this check.
+		return err
+	}
+	securityTxtCache.Lock()
+	securityTxtCache.m[url] = securityTxtResult{err: err, fetched: now}
+	securityTxtCache.Unlock()
+	return err
+}
+
+func fetchSecurityTxt(ctx context.Context, url string, now time.Time) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	rsp, err := securityTxtClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("security.txt fetch returned status %v", rsp.StatusCode)
+	}
+	b, err := io.ReadAll(io.LimitReader(rsp.Body, securityTxtMaxSize))
+	if err != nil {
+		return err
+	}
+	return parseSecurityTxt(string(b), now)
+}
+
+// parseSecurityTxt validates the required fields of a security.txt file per
+// RFC 9116: at least one Contact, and exactly one Expires that has not
+// passed. An OpenPGP cleartext signature wrapper is allowed, but not
+// verified.
+func parseSecurityTxt(content string, now time.Time) error {
+	var contacts int
+	var expires []string
+	signed := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "-----BEGIN PGP SIGNED MESSAGE-----") {
+			signed = true
+			continue
+		}
+		if strings.HasPrefix(line, "-----BEGIN PGP SIGNATURE-----") {
+			break
+		}
+		if signed {
+			if strings.HasPrefix(line, "Hash:") {
+				continue
+			}
+			line = strings.TrimPrefix(line, "- ")
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return fmt.Errorf("security.txt has malformed line: %q", line)
+		}
+		field := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		switch field {
+		case "contact":
+			if value != "" {
+				contacts++
+			}
+		case "expires":
+			expires = append(expires, value)
+		}
+	}
+	if contacts == 0 {
+		return errors.New("security.txt has no Contact field")
+	}
+	if len(expires) != 1 {
+		return fmt.Errorf("security.txt must have exactly one Expires field, found %v", len(expires))
+	}
+	e, err := time.Parse(time.RFC3339, expires[0])
+	if err != nil {
+		return fmt.Errorf("security.txt has invalid Expires: %w", err)
+	}
+	if !now.Before(e) {
+		return fmt.Errorf("security.txt expired on %v", expires[0])
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseSecurityTxt(t *testing.T) {
+	now := time.Date(2021, 9, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		Name    string
+		Content string
+		Valid   bool
+	}{
+		{
+			Name:    "Valid",
+			Content: "# Our policy\nContact: mailto:security@example.com\nExpires: 2022-01-01T00:00:00Z\n",
+			Valid:   true,
+		},
+		{
+			Name: "Signed",
+			Content: "-----BEGIN PGP SIGNED MESSAGE-----\nHash: SHA256\n\n" +
+				"Contact: https://example.com/report\r\nExpires: 2022-01-01T00:00:00Z\r\n" +
+				"-----BEGIN PGP SIGNATURE-----\nabc\n-----END PGP SIGNATURE-----\n",
+			Valid: true,
+		},
+		{
+			Name:    "NoContact",
+			Content: "Expires: 2022-01-01T00:00:00Z\n",
+		},
+		{
+			Name:    "NoExpires",
+			Content: "Contact: mailto:security@example.com\n",
+		},
+		{
+			Name:    "TwoExpires",
+			Content: "Contact: mailto:security@example.com\nExpires: 2022-01-01T00:00:00Z\nExpires: 2023-01-01T00:00:00Z\n",
+		},
+		{
+			Name:    "Expired",
+			Content: "Contact: mailto:security@example.com\nExpires: 2021-01-01T00:00:00Z\n",
+		},
+		{
+			Name:    "HTML",
+			Content: "<html><body>Not found</body></html>",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := parseSecurityTxt(test.Content, now)
+			if test.Valid && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !test.Valid && err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
+
+func TestCheckSecurityTxt(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/thisrepo/.well-known/security.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "Contact: mailto:security@example.com\nExpires: %v\n",
+			time.Now().Add(24*time.Hour).Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	u := securityTxtURL(srv.URL+"/{repo}/.well-known/security.txt", "org", "thisrepo")
+	if err := checkSecurityTxt(context.Background(), u); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := checkSecurityTxt(context.Background(), u); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected cached result, got %v calls", calls)
+	}
+	if err := checkSecurityTxt(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Errorf("Expected error for missing security.txt")
+	}
+}
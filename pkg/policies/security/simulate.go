@@ -0,0 +1,102 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// SimulationChange describes how a repo's result would change under a
+// proposed config.
+type SimulationChange struct {
+	Repo          string
+	EnabledBefore bool
+	EnabledAfter  bool
+	PassBefore    bool
+	PassAfter     bool
+	ActionBefore  string
+	ActionAfter   string
+	Err           error
+}
+
+// String returns a one line summary of the change.
+func (s SimulationChange) String() string {
+	if s.Err != nil {
+		return fmt.Sprintf("%v: error: %v", s.Repo, s.Err)
+	}
+	return fmt.Sprintf("%v: enabled %v -> %v, pass %v -> %v, action %v -> %v",
+		s.Repo, s.EnabledBefore, s.EnabledAfter, s.PassBefore, s.PassAfter,
+		s.ActionBefore, s.ActionAfter)
+}
+
+// Simulate checks each of the provided repos with both the current config and
+// the proposed org-level config, and returns the repos whose enabled, pass, or
+// action would change. Pass and action changes are ignored for repos disabled
+// under both configs. Proposed repo-level configs may be provided keyed by
+// repo name, otherwise the current repo-level config is used. Nothing is
+// modified. Repos which error are included with Err set. Results are sorted by
+// repo name.
+func Simulate(ctx context.Context, c *github.Client, owner string, repos []string,
+	proposed *OrgConfig, proposedRepos map[string]*RepoConfig) []SimulationChange {
+	v4c := newV4Client(c)
+	return simulate(ctx, c.Repositories, c, v4c, owner, repos, proposed, proposedRepos)
+}
+
+func simulate(ctx context.Context, rep repositories, c *github.Client, v4c v4client,
+	owner string, repos []string, proposed *OrgConfig,
+	proposedRepos map[string]*RepoConfig) []SimulationChange {
+	var changes []SimulationChange
+	ctx = withoutOverrideStats(ctx)
+	for _, repo := range repos {
+		oc, rc := getConfig(ctx, c, owner, repo)
+		prc := rc
+		if r, ok := proposedRepos[repo]; ok {
+			prc = r
+		}
+		before, err := checkConfig(ctx, rep, c, v4c, owner, repo, oc, rc)
+		if err != nil {
+			changes = append(changes, SimulationChange{Repo: repo, Err: err})
+			continue
+		}
+		after, err := checkConfig(ctx, rep, c, v4c, owner, repo, proposed, prc)
+		if err != nil {
+			changes = append(changes, SimulationChange{Repo: repo, Err: err})
+			continue
+		}
+		sc := SimulationChange{
+			Repo:          repo,
+			EnabledBefore: before.Enabled,
+			EnabledAfter:  after.Enabled,
+			PassBefore:    before.Pass,
+			PassAfter:     after.Pass,
+			ActionBefore:  mergeConfig(ctx, oc, rc, repo).Action,
+			ActionAfter:   mergeConfig(ctx, proposed, prc, repo).Action,
+		}
+		// A pass or action change only matters if enabled in either config.
+		active := sc.EnabledBefore || sc.EnabledAfter
+		if sc.EnabledBefore != sc.EnabledAfter ||
+			(active && (sc.PassBefore != sc.PassAfter || sc.ActionBefore != sc.ActionAfter)) {
+			changes = append(changes, sc)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Repo < changes[j].Repo
+	})
+	return changes
+}
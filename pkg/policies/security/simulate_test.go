@@ -0,0 +1,93 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v39/github"
+	"github.com/ossf/allstar/pkg/config"
+)
+
+func TestSimulate(t *testing.T) {
+	current := OrgConfig{
+		OptConfig: config.OrgOptConfig{
+			OptInRepos: []string{"enabled", "bad"},
+		},
+		Action: "log",
+	}
+	configFetchConfig = func(ctx context.Context, c *github.Client,
+		owner string, repo string, path string, out interface{}) error {
+		if oc, ok := out.(*OrgConfig); ok {
+			*oc = current
+		}
+		return nil
+	}
+	getContents = contentsMock(map[string]string{
+		"SECURITY.md": "To report a security issue, open an issue.",
+	})
+	query = func(ctx context.Context, q interface{}, v map[string]interface{}) error {
+		qc := q.(*struct {
+			Repository struct {
+				SecurityPolicyUrl       string
+				IsSecurityPolicyEnabled bool
+				IsArchived              bool
+				IsDisabled              bool
+				DefaultBranchRef        struct {
+					Name string
+				}
+			} `graphql:"repository(owner: $owner, name: $name)"`
+		})
+		qc.Repository.IsSecurityPolicyEnabled = true
+		qc.Repository.DefaultBranchRef.Name = "main"
+		return nil
+	}
+	proposed := &OrgConfig{
+		OptConfig: config.OrgOptConfig{
+			OptInRepos: []string{"enabled", "new", "bad"},
+		},
+		Action:                   "log",
+		DisallowPublicDisclosure: true,
+	}
+	issue := "issue"
+	got := simulate(context.Background(), mockRepos{}, nil, mockClient{}, "org",
+		[]string{"unchanged", "new", "enabled"}, proposed,
+		map[string]*RepoConfig{"enabled": {Action: &issue}})
+	want := []SimulationChange{
+		{
+			Repo:          "enabled",
+			EnabledBefore: true,
+			EnabledAfter:  true,
+			PassBefore:    true,
+			PassAfter:     false,
+			ActionBefore:  "log",
+			ActionAfter:   "issue",
+		},
+		{
+			Repo:          "new",
+			EnabledBefore: false,
+			EnabledAfter:  true,
+			PassBefore:    true,
+			PassAfter:     false,
+			ActionBefore:  "log",
+			ActionAfter:   "log",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected results. (-want +got):\n%s", diff)
+	}
+}
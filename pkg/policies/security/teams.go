@@ -0,0 +1,98 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ossf/allstar/pkg/policydef"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// teamsCacheDuration is how long the teams with access to a repo are cached.
+const teamsCacheDuration = time.Hour
+
+type teamRepositories interface {
+	ListTeams(context.Context, string, string, *github.ListOptions) (
+		[]*github.Team, *github.Response, error)
+}
+
+var newTeamRepositories func(*github.Client) teamRepositories
+
+func init() {
+	newTeamRepositories = func(c *github.Client) teamRepositories {
+		return c.Repositories
+	}
+}
+
+type cachedTeams struct {
+	teams   []string
+	fetched time.Time
+}
+
+var teamsCache = struct {
+	sync.Mutex
+	m map[string]cachedTeams
+}{m: make(map[string]cachedTeams)}
+
+// repoTeams returns the slugs of the teams with access to the repo. Results are
+// cached for teamsCacheDuration, unless a recheck is forced.
+func repoTeams(ctx context.Context, rep teamRepositories, owner, repo string) ([]string, error) {
+	key := strings.ToLower(owner) + "/" + strings.ToLower(repo)
+	now := timeNow()
+	teamsCache.Lock()
+	e, ok := teamsCache.m[key]
+	teamsCache.Unlock()
+	if ok && !policydef.IsForceRecheck(ctx) && now.Sub(e.fetched) < teamsCacheDuration {
+		return e.teams, nil
+	}
+	opt := &github.ListOptions{
+		PerPage: 100,
+	}
+	var teams []string
+	for {
+		ts, resp, err := rep.ListTeams(ctx, owner, repo, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range ts {
+			teams = append(teams, t.GetSlug())
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	teamsCache.Lock()
+	teamsCache.m[key] = cachedTeams{teams: teams, fetched: now}
+	teamsCache.Unlock()
+	return teams, nil
+}
+
+// anyTeam returns whether any of teams is in enforced, ignoring case.
+func anyTeam(teams, enforced []string) bool {
+	for _, t := range teams {
+		for _, e := range enforced {
+			if strings.EqualFold(t, e) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ossf/allstar/pkg/policydef"
+
+	"github.com/google/go-github/v39/github"
+)
+
+type mockTeamRepos struct {
+	teams []string
+	calls *int
+}
+
+func (m mockTeamRepos) ListTeams(ctx context.Context, owner, repo string,
+	opts *github.ListOptions) ([]*github.Team, *github.Response, error) {
+	*m.calls++
+	var ts []*github.Team
+	for _, t := range m.teams {
+		ts = append(ts, &github.Team{Slug: github.String(t)})
+	}
+	return ts, &github.Response{}, nil
+}
+
+func TestRepoTeams(t *testing.T) {
+	now := time.Date(2021, 9, 10, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	calls := 0
+	rep := mockTeamRepos{teams: []string{"products", "infra"}, calls: &calls}
+	for i := 0; i < 2; i++ {
+		teams, err := repoTeams(context.Background(), rep, "org", "teamsrepo")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !anyTeam(teams, []string{"Products"}) {
+			t.Errorf("Expected repo to be in enforced team, teams: %v", teams)
+		}
+		if anyTeam(teams, []string{"docs"}) {
+			t.Errorf("Expected repo not to be in team docs, teams: %v", teams)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected teams to be cached, got %v calls", calls)
+	}
+	if _, err := repoTeams(policydef.WithForceRecheck(context.Background()), rep, "org", "teamsrepo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now = now.Add(2 * teamsCacheDuration)
+	if _, err := repoTeams(context.Background(), rep, "org", "teamsrepo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected cache bypass on forced recheck and expiry, got %v calls", calls)
+	}
+}
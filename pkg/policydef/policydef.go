@@ -25,6 +25,8 @@ package policydef
 
 import (
 	"context"
+	"sort"
+	"sync"
 
 	"github.com/google/go-github/v39/github"
 )
@@ -45,6 +47,123 @@ type Result struct {
 	// Details are logged on failure. it should be serailizable to json and allow
 	// useful log querying.
 	Details interface{}
+
+	// Reasons are stable, machine readable codes identifying each finding that
+	// caused the policy to fail, ex: "security_policy_missing". Unlike
+	// NotifyText, they should not change between runs unless the findings
+	// change. Optional.
+	Reasons []string
+}
+
+// ResultChanged returns true if the new result is meaningfully different from
+// the old one: old is nil, enabled or pass differs, or the set of reason codes
+// differs. NotifyText and Details are not compared.
+func ResultChanged(old, new *Result) bool {
+	if old == nil || new == nil {
+		return old != new
+	}
+	if old.Enabled != new.Enabled || old.Pass != new.Pass {
+		return true
+	}
+	if len(old.Reasons) != len(new.Reasons) {
+		return true
+	}
+	o := append([]string(nil), old.Reasons...)
+	n := append([]string(nil), new.Reasons...)
+	sort.Strings(o)
+	sort.Strings(n)
+	for i := range o {
+		if o[i] != n[i] {
+			return true
+		}
+	}
+	return false
+}
+
+type forceRecheckKey struct{}
+
+// WithForceRecheck returns a copy of ctx that asks policy checks to ignore any
+// cached config or results and fetch fresh data, such as when debugging or
+// after a known change. Fresh data is still stored in the caches.
+func WithForceRecheck(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRecheckKey{}, true)
+}
+
+// IsForceRecheck returns true if ctx was created with WithForceRecheck.
+// Policies that cache data should check it before using a cached value.
+func IsForceRecheck(ctx context.Context) bool {
+	f, _ := ctx.Value(forceRecheckKey{}).(bool)
+	return f
+}
+
+type actionOptionKey struct{}
+
+// WithActionOption returns a copy of ctx with the policy specific option of
+// the action being taken, such as "commit" from an operator mapped action of
+// "fix:commit".
+func WithActionOption(ctx context.Context, opt string) context.Context {
+	return context.WithValue(ctx, actionOptionKey{}, opt)
+}
+
+// ActionOption returns the action option stored in ctx, or an empty string if
+// there is none. Policies may use it to choose how to take the action, such as
+// how to Fix.
+func ActionOption(ctx context.Context) string {
+	o, _ := ctx.Value(actionOptionKey{}).(string)
+	return o
+}
+
+type reasonsKey struct{}
+
+// WithReasons returns a copy of ctx with the reasons of the result an action is
+// being chosen for, so that GetAction may choose the action by reason.
+func WithReasons(ctx context.Context, reasons []string) context.Context {
+	return context.WithValue(ctx, reasonsKey{}, reasons)
+}
+
+// Reasons returns the result reasons stored in ctx, or nil if there are none.
+func Reasons(ctx context.Context) []string {
+	r, _ := ctx.Value(reasonsKey{}).([]string)
+	return r
+}
+
+type runCacheKey struct{}
+
+type runCache struct {
+	mu sync.Mutex
+	m  map[string]interface{}
+}
+
+// WithRunCache returns a copy of ctx with a cache that lets a policy share
+// values, such as its resolved config, between the Check, GetAction, and Fix
+// calls made for a repo in one run, see RunCached. enforce.RunPolicies adds one
+// for each repo.
+func WithRunCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, runCacheKey{}, &runCache{m: make(map[string]interface{})})
+}
+
+// RunCached returns the value stored under key in the run cache of ctx, calling
+// f to compute and store it on first use. If ctx has no run cache, f is called
+// every time. Keys should be prefixed with the policy name.
+func RunCached(ctx context.Context, key string, f func() interface{}) interface{} {
+	rc, ok := ctx.Value(runCacheKey{}).(*runCache)
+	if !ok {
+		return f()
+	}
+	rc.mu.Lock()
+	v, ok := rc.m[key]
+	rc.mu.Unlock()
+	if ok {
+		return v
+	}
+	v = f()
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if cached, ok := rc.m[key]; ok {
+		return cached
+	}
+	rc.m[key] = v
+	return v
 }
 
 // Policy is the interface that policies must implement to be included in
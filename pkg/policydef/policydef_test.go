@@ -0,0 +1,123 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policydef
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResultChanged(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Old    *Result
+		New    *Result
+		Expect bool
+	}{
+		{
+			Name:   "NoOld",
+			Old:    nil,
+			New:    &Result{Enabled: true, Pass: false},
+			Expect: true,
+		},
+		{
+			Name:   "Same",
+			Old:    &Result{Enabled: true, Pass: false, Reasons: []string{"a", "b"}},
+			New:    &Result{Enabled: true, Pass: false, Reasons: []string{"b", "a"}},
+			Expect: false,
+		},
+		{
+			Name:   "OnlyTextChanged",
+			Old:    &Result{Enabled: true, Pass: false, NotifyText: "one"},
+			New:    &Result{Enabled: true, Pass: false, NotifyText: "two"},
+			Expect: false,
+		},
+		{
+			Name:   "PassChanged",
+			Old:    &Result{Enabled: true, Pass: false},
+			New:    &Result{Enabled: true, Pass: true},
+			Expect: true,
+		},
+		{
+			Name:   "EnabledChanged",
+			Old:    &Result{Enabled: false, Pass: false},
+			New:    &Result{Enabled: true, Pass: false},
+			Expect: true,
+		},
+		{
+			Name:   "NewReason",
+			Old:    &Result{Enabled: true, Pass: false, Reasons: []string{"a"}},
+			New:    &Result{Enabled: true, Pass: false, Reasons: []string{"a", "b"}},
+			Expect: true,
+		},
+		{
+			Name:   "DifferentReason",
+			Old:    &Result{Enabled: true, Pass: false, Reasons: []string{"a"}},
+			New:    &Result{Enabled: true, Pass: false, Reasons: []string{"b"}},
+			Expect: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if got := ResultChanged(test.Old, test.New); got != test.Expect {
+				t.Errorf("Unexpected result. Expected: %v Got: %v", test.Expect, got)
+			}
+		})
+	}
+}
+
+func TestForceRecheck(t *testing.T) {
+	ctx := context.Background()
+	if IsForceRecheck(ctx) {
+		t.Error("Expected no forced recheck")
+	}
+	if !IsForceRecheck(WithForceRecheck(ctx)) {
+		t.Error("Expected forced recheck")
+	}
+}
+
+func TestReasons(t *testing.T) {
+	ctx := context.Background()
+	if r := Reasons(ctx); r != nil {
+		t.Errorf("Expected no reasons, got: %v", r)
+	}
+	if r := Reasons(WithReasons(ctx, []string{"a", "b"})); len(r) != 2 || r[0] != "a" {
+		t.Errorf("Unexpected reasons: %v", r)
+	}
+}
+
+func TestRunCached(t *testing.T) {
+	calls := 0
+	f := func() interface{} {
+		calls++
+		return calls
+	}
+	if v := RunCached(context.Background(), "k", f); v != 1 {
+		t.Errorf("Unexpected value: %v", v)
+	}
+	if v := RunCached(context.Background(), "k", f); v != 2 {
+		t.Errorf("Expected no caching without a run cache, got: %v", v)
+	}
+	ctx := WithRunCache(context.Background())
+	calls = 0
+	for i := 0; i < 2; i++ {
+		if v := RunCached(ctx, "k", f); v != 1 {
+			t.Errorf("Expected cached value, got: %v", v)
+		}
+	}
+	if v := RunCached(ctx, "other", f); v != 2 {
+		t.Errorf("Unexpected value for other key: %v", v)
+	}
+}
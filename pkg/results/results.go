@@ -0,0 +1,71 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package results records policy results to an external store, such as to
+// build compliance trends over time.
+package results
+
+import (
+	"context"
+	"time"
+
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+// Record is a single policy result for a repo.
+type Record struct {
+	Owner  string
+	Repo   string
+	Policy string
+	Time   time.Time
+	Result *policydef.Result
+}
+
+// ResultSink persists policy results.
+type ResultSink interface {
+	// Write persists the record.
+	Write(ctx context.Context, r *Record) error
+}
+
+// noopSink is the default ResultSink, results are only logged.
+type noopSink struct{}
+
+func (noopSink) Write(ctx context.Context, r *Record) error {
+	return nil
+}
+
+var sink ResultSink = noopSink{}
+
+var timeNow func() time.Time
+
+func init() {
+	timeNow = time.Now
+}
+
+// SetSink sets where results are recorded, such as a SQLSink. It is intended
+// to be called at startup. By default results are not recorded.
+func SetSink(s ResultSink) {
+	sink = s
+}
+
+// Write records the result of policy on owner/repo to the configured sink.
+func Write(ctx context.Context, owner, repo, policy string, r *policydef.Result) error {
+	return sink.Write(ctx, &Record{
+		Owner:  owner,
+		Repo:   repo,
+		Policy: policy,
+		Time:   timeNow(),
+		Result: r,
+	})
+}
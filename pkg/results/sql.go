@@ -0,0 +1,89 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SQLSink is a ResultSink that inserts results into a table with database/sql.
+// The caller opens the database with the driver of their choice.
+type SQLSink struct {
+	db     *sql.DB
+	table  string
+	dollar bool
+}
+
+// NewSQLSink returns a SQLSink that writes to table in db. Set dollar to true
+// for drivers that use numbered placeholders ($1), such as PostgreSQL, or false
+// for drivers that use ?, such as MySQL and SQLite.
+func NewSQLSink(db *sql.DB, table string, dollar bool) *SQLSink {
+	return &SQLSink{
+		db:     db,
+		table:  table,
+		dollar: dollar,
+	}
+}
+
+// CreateTable creates the results table if it doesn't exist. Reasons are
+// stored separated by ";", and details as JSON.
+func (s *SQLSink) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %v (
+	owner TEXT NOT NULL,
+	repo TEXT NOT NULL,
+	policy TEXT NOT NULL,
+	checked_at TIMESTAMP NOT NULL,
+	enabled BOOLEAN NOT NULL,
+	pass BOOLEAN NOT NULL,
+	reasons TEXT NOT NULL,
+	details TEXT NOT NULL
+)`, s.table))
+	return err
+}
+
+// Write inserts the record, implementing ResultSink.
+func (s *SQLSink) Write(ctx context.Context, r *Record) error {
+	d, err := json.Marshal(r.Result.Details)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.insert(),
+		r.Owner,
+		r.Repo,
+		r.Policy,
+		r.Time.UTC(),
+		r.Result.Enabled,
+		r.Result.Pass,
+		strings.Join(r.Result.Reasons, ";"),
+		string(d))
+	return err
+}
+
+func (s *SQLSink) insert() string {
+	ps := make([]string, 8)
+	for i := range ps {
+		if s.dollar {
+			ps[i] = fmt.Sprintf("$%v", i+1)
+		} else {
+			ps[i] = "?"
+		}
+	}
+	return fmt.Sprintf("INSERT INTO %v (owner, repo, policy, checked_at, enabled, pass, reasons, details) VALUES (%v)",
+		s.table, strings.Join(ps, ", "))
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ossf/allstar/pkg/policydef"
+)
+
+// fakeDriver records the statements executed through database/sql.
+type fakeDriver struct {
+	queries []string
+	args    [][]driver.Value
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{d: c.d, query: query}, nil
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s fakeStmt) Close() error {
+	return nil
+}
+
+func (s fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.args = append(s.d.args, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var fake = &fakeDriver{}
+
+func init() {
+	sql.Register("resultsfake", fake)
+}
+
+func TestSQLSink(t *testing.T) {
+	db, err := sql.Open("resultsfake", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer db.Close()
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	r := &Record{
+		Owner:  "org",
+		Repo:   "repo",
+		Policy: "SECURITY.md",
+		Time:   now,
+		Result: &policydef.Result{
+			Enabled: true,
+			Pass:    false,
+			Reasons: []string{"a", "b"},
+			Details: map[string]bool{"Enabled": false},
+		},
+	}
+	tests := []struct {
+		Name   string
+		Dollar bool
+		Query  string
+	}{
+		{
+			Name:   "Question",
+			Dollar: false,
+			Query:  "INSERT INTO results (owner, repo, policy, checked_at, enabled, pass, reasons, details) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		},
+		{
+			Name:   "Dollar",
+			Dollar: true,
+			Query:  "INSERT INTO results (owner, repo, policy, checked_at, enabled, pass, reasons, details) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fake.queries = nil
+			fake.args = nil
+			s := NewSQLSink(db, "results", test.Dollar)
+			if err := s.Write(context.Background(), r); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(fake.queries) != 1 || fake.queries[0] != test.Query {
+				t.Errorf("Unexpected queries: %v", fake.queries)
+			}
+			want := []driver.Value{"org", "repo", "SECURITY.md", now, true, false, "a;b", `{"Enabled":false}`}
+			if diff := cmp.Diff(want, fake.args[0]); diff != "" {
+				t.Errorf("Unexpected args. (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type mockSink struct {
+	records []*Record
+}
+
+func (m *mockSink) Write(ctx context.Context, r *Record) error {
+	m.records = append(m.records, r)
+	return nil
+}
+
+func TestWrite(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	m := &mockSink{}
+	SetSink(m)
+	defer SetSink(noopSink{})
+	res := &policydef.Result{Enabled: true, Pass: true}
+	if err := Write(context.Background(), "org", "repo", "policy", res); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []*Record{{Owner: "org", Repo: "repo", Policy: "policy", Time: now, Result: res}}
+	if diff := cmp.Diff(want, m.records); diff != "" {
+		t.Errorf("Unexpected records. (-want +got):\n%s", diff)
+	}
+}
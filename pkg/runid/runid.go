@@ -0,0 +1,63 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package runid stores a correlation ID for an enforcement run in a context,
+// so that all log lines from a single run can be grouped together.
+package runid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// LogField is the structured log field name for the run ID.
+const LogField = "runId"
+
+type ctxKey struct{}
+
+// New returns a new random run ID.
+func New() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithRunID returns a copy of ctx containing the provided run ID.
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the run ID stored in ctx, or an empty string if there
+// is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Logger returns the global logger with the run ID field added, if ctx
+// contains a run ID.
+func Logger(ctx context.Context) *zerolog.Logger {
+	id := FromContext(ctx)
+	if id == "" {
+		return &log.Logger
+	}
+	l := log.With().Str(LogField, id).Logger()
+	return &l
+}
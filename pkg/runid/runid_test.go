@@ -0,0 +1,62 @@
+// Copyright 2021 Allstar Authors
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runid
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog/log"
+)
+
+func TestFromContext(t *testing.T) {
+	if id := FromContext(context.Background()); id != "" {
+		t.Errorf("Expected empty run ID, got: %v", id)
+	}
+	ctx := WithRunID(context.Background(), "abc123")
+	if id := FromContext(ctx); id != "abc123" {
+		t.Errorf("Unexpected run ID: %v", id)
+	}
+}
+
+func TestNew(t *testing.T) {
+	a := New()
+	b := New()
+	if len(a) != 16 {
+		t.Errorf("Unexpected run ID length: %v", a)
+	}
+	if a == b {
+		t.Errorf("Expected unique run IDs, got: %v %v", a, b)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	orig := log.Logger
+	log.Logger = log.Output(&buf)
+	defer func() { log.Logger = orig }()
+
+	Logger(WithRunID(context.Background(), "abc123")).Info().Msg("with")
+	if !strings.Contains(buf.String(), `"runId":"abc123"`) {
+		t.Errorf("Expected run ID field in log: %v", buf.String())
+	}
+	buf.Reset()
+	Logger(context.Background()).Info().Msg("without")
+	if strings.Contains(buf.String(), LogField) {
+		t.Errorf("Unexpected run ID field in log: %v", buf.String())
+	}
+}